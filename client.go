@@ -13,6 +13,7 @@ import (
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/bizflycloud/gobizfly/utils"
 )
@@ -50,6 +51,8 @@ var (
 	ErrPermissionDenied = errors.New("you are not allowed to do this action")
 	// ErrCommon for common error
 	ErrCommon = errors.New("error")
+	// ErrTransient for errors which may go away when the request is retried
+	ErrTransient = fmt.Errorf("temporary error: %w", ErrCommon)
 )
 
 // Client represents Bizfly API client.
@@ -66,9 +69,10 @@ type Client struct {
 	userAgent     string
 	username      string
 
-	apiURL     *url.URL
-	httpClient *http.Client
-	services   []*Service
+	apiURL       *url.URL
+	httpClient   *http.Client
+	pollInterval time.Duration
+	services     []*Service
 
 	Account            AccountService
 	AutoScaling        AutoScalingService
@@ -127,6 +131,17 @@ func WithRegionName(region string) Option {
 	}
 }
 
+// WithPollInterval sets the interval between status checks when waiting for a resource.
+func WithPollInterval(interval time.Duration) Option {
+	return func(c *Client) error {
+		if interval <= 0 {
+			return errors.New("poll interval must be positive")
+		}
+		c.pollInterval = interval
+		return nil
+	}
+}
+
 func WithProjectID(id string) Option {
 	return func(c *Client) error {
 		c.projectID = id
@@ -144,8 +159,9 @@ func WithBasicAuth(basicAuth string) Option {
 // NewClient creates new Bizfly client.
 func NewClient(options ...Option) (*Client, error) {
 	c := &Client{
-		httpClient: http.DefaultClient,
-		userAgent:  ua,
+		httpClient:   http.DefaultClient,
+		pollInterval: defaultPollInterval,
+		userAgent:    ua,
 	}
 
 	err := WithAPIURL(defaultAPIURL)(c)
@@ -323,6 +339,9 @@ func errorFromStatus(code int, msg string) error {
 		return fmt.Errorf("%s: %w", msg, ErrNotFound)
 	case http.StatusForbidden:
 		return fmt.Errorf("%s: %w", msg, ErrPermissionDenied)
+	case http.StatusConflict, http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return fmt.Errorf("%s: %w", msg, ErrTransient)
 	default:
 		return fmt.Errorf("%s: %w", msg, ErrCommon)
 	}
@@ -139,11 +139,21 @@ func TestErrFromStatus(t *testing.T) {
 		{http.StatusBadRequest, "Volume not found", ErrCommon},
 		{http.StatusNotFound, "Permission denied", ErrNotFound},
 		{http.StatusForbidden, "Generic error", ErrPermissionDenied},
+		{http.StatusConflict, "Resource is busy", ErrTransient},
+		{http.StatusTooManyRequests, "Too many requests", ErrTransient},
+		{http.StatusBadGateway, "Bad gateway", ErrTransient},
+		{http.StatusServiceUnavailable, "Service unavailable", ErrTransient},
+		{http.StatusGatewayTimeout, "Gateway timeout", ErrTransient},
+		{http.StatusInternalServerError, "Internal error", ErrCommon},
 	}
 
 	for _, tc := range tests {
-		if err := errorFromStatus(tc.statusCode, tc.msg); !errors.Is(err, tc.err) {
+		err := errorFromStatus(tc.statusCode, tc.msg)
+		if !errors.Is(err, tc.err) {
 			t.Errorf("unexpected error, want: %v, got: %v", tc.err, err)
 		}
+		if tc.err == ErrCommon && errors.Is(err, ErrTransient) {
+			t.Errorf("status %d should not be transient", tc.statusCode)
+		}
 	}
 }
@@ -36,6 +36,7 @@ type CloudServerService interface {
 	HardReboot(ctx context.Context, id string) (*ServerMessageResponse, error)
 	List(ctx context.Context, opts *ServerListOptions) ([]*Server, error)
 	ListServerTypes(ctx context.Context) ([]*ServerType, error)
+	RecommendFlavor(ctx context.Context, requirements FlavorRequirements) ([]*FlavorRecommendation, error)
 	Rebuild(ctx context.Context, id string, imageID string) (*ServerTask, error)
 	RemoveNetworkInterface(ctx context.Context, id string, vpcs []string) (*Server, error)
 	Rename(ctx context.Context, id string, newName string) error
@@ -45,6 +46,7 @@ type CloudServerService interface {
 	Stop(ctx context.Context, id string) (*Server, error)
 	SwitchBillingPlan(ctx context.Context, id string, newBillingPlan string) error
 	FlavorGenerations() *cloudFlavorGenerations
+	Console() *cloudServerConsoleResource
 	CustomImages() *cloudServerCustomOSImageResource
 	Firewalls() *cloudServerFirewallResource
 	Flavors() *cloudServerFlavorResource
@@ -0,0 +1,184 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/coder/websocket"
+)
+
+// consoleSubprotocol is the websocket subprotocol of the noVNC proxy, carrying raw RFB bytes in binary frames.
+const consoleSubprotocol = "binary"
+
+type cloudServerConsoleResource struct {
+	client *Client
+}
+
+// Console returns the helper to open VNC console sessions of servers.
+func (cs *cloudServerService) Console() *cloudServerConsoleResource {
+	return &cloudServerConsoleResource{client: cs.client}
+}
+
+// ConsoleProxy forwards local TCP connections to the VNC console of a server.
+type ConsoleProxy struct {
+	listener net.Listener
+	done     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+	// dialMu serializes opening sessions, the client is not safe for concurrent requests.
+	dialMu sync.Mutex
+}
+
+// Dial opens a websocket session to the console of a server. The returned connection carries the raw
+// RFB (VNC) protocol, the console URL is requested with GetVNC for every session.
+func (c *cloudServerConsoleResource) Dial(ctx context.Context, serverID string) (net.Conn, error) {
+	console, err := (&cloudServerService{client: c.client}).GetVNC(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if console == nil || console.URL == "" {
+		return nil, fmt.Errorf("server %s has no console url: %w", serverID, ErrCommon)
+	}
+	location, origin, err := consoleWebsocketURL(console.URL)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set("Origin", origin)
+	header.Set("User-Agent", c.client.userAgent)
+	conn, _, err := websocket.Dial(ctx, location, &websocket.DialOptions{
+		HTTPHeader:   header,
+		Subprotocols: []string{consoleSubprotocol},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open console of server %s: %w", serverID, err)
+	}
+	// Framebuffer updates easily exceed the default message limit.
+	conn.SetReadLimit(-1)
+	// The session outlives the dial, it ends when the returned connection is closed.
+	return websocket.NetConn(context.WithoutCancel(ctx), conn, websocket.MessageBinary), nil
+}
+
+// Proxy listens on localAddr, e.g. "127.0.0.1:5900", and forwards every accepted connection to a new console
+// session of the server, so a native VNC client can connect to the local address. The proxy runs until ctx is
+// done or Close is called.
+func (c *cloudServerConsoleResource) Proxy(ctx context.Context, serverID string, localAddr string) (*ConsoleProxy, error) {
+	listener, err := net.Listen("tcp", localAddr)
+	if err != nil {
+		return nil, err
+	}
+	p := &ConsoleProxy{listener: listener, done: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = p.Close()
+		case <-p.done:
+		}
+	}()
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for {
+			local, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			p.wg.Add(1)
+			go func() {
+				defer p.wg.Done()
+				defer func() {
+					_ = local.Close()
+				}()
+				p.dialMu.Lock()
+				remote, err := c.Dial(ctx, serverID)
+				p.dialMu.Unlock()
+				if err != nil {
+					return
+				}
+				pipeConsole(local, remote, p.done)
+			}()
+		}
+	}()
+	return p, nil
+}
+
+// Addr returns the local address the proxy listens on.
+func (p *ConsoleProxy) Addr() net.Addr {
+	return p.listener.Addr()
+}
+
+// Close stops accepting connections, closes the open sessions and waits for them to end.
+func (p *ConsoleProxy) Close() error {
+	var err error
+	p.once.Do(func() {
+		close(p.done)
+		err = p.listener.Close()
+		p.wg.Wait()
+	})
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// pipeConsole copies both directions until either side closes or the proxy is done.
+func pipeConsole(local net.Conn, remote net.Conn, done <-chan struct{}) {
+	finished := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(remote, local)
+		finished <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(local, remote)
+		finished <- struct{}{}
+	}()
+	select {
+	case <-finished:
+	case <-done:
+	}
+	_ = remote.Close()
+	_ = local.Close()
+	<-finished
+}
+
+// consoleWebsocketURL converts the noVNC page URL returned by GetVNC into the websocket URL of its proxy.
+// The page passes the websocket path either in the path query parameter, e.g. vnc_auto.html?path=%3Ftoken%3Dx,
+// or directly as a token parameter, in which case the websockify endpoint is used.
+func consoleWebsocketURL(consoleURL string) (string, string, error) {
+	u, err := url.Parse(consoleURL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid console url %q: %w", consoleURL, ErrCommon)
+	}
+	origin := url.URL{Scheme: u.Scheme, Host: u.Host}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	case "ws", "wss":
+		origin.Scheme = strings.Replace(u.Scheme, "ws", "http", 1)
+	default:
+		return "", "", fmt.Errorf("invalid console url %q: %w", consoleURL, ErrCommon)
+	}
+	query := u.Query()
+	if path := query.Get("path"); path != "" {
+		target, err := url.Parse("/" + strings.TrimPrefix(path, "/"))
+		if err != nil {
+			return "", "", fmt.Errorf("invalid console path %q: %w", path, ErrCommon)
+		}
+		u.Path, u.RawQuery = target.Path, target.RawQuery
+	} else if strings.HasSuffix(u.Path, ".html") {
+		u.Path = strings.TrimSuffix(u.Path, u.Path[strings.LastIndex(u.Path, "/")+1:]) + "websockify"
+	}
+	u.Fragment = ""
+	return u.String(), origin.String(), nil
+}
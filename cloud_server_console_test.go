@@ -0,0 +1,88 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/coder/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsoleWebsocketURL(t *testing.T) {
+	for consoleURL, expected := range map[string]string{
+		"https://hn-1.vccloud.vn:6080/vnc_auto.html?token=abc":            "wss://hn-1.vccloud.vn:6080/websockify?token=abc",
+		"https://hn-1.vccloud.vn:6080/vnc_lite.html?path=%3Ftoken%3Dabc":  "wss://hn-1.vccloud.vn:6080/?token=abc",
+		"http://console.local/novnc/vnc.html?path=websockify%3Ftoken%3Dx": "ws://console.local/websockify?token=x",
+		"wss://console.local/websockify?token=abc":                        "wss://console.local/websockify?token=abc",
+	} {
+		location, _, err := consoleWebsocketURL(consoleURL)
+		require.NoError(t, err, consoleURL)
+		assert.Equal(t, expected, location, consoleURL)
+	}
+	_, origin, err := consoleWebsocketURL("https://hn-1.vccloud.vn:6080/vnc_auto.html?token=abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://hn-1.vccloud.vn:6080", origin)
+	_, _, err = consoleWebsocketURL("ftp://console.local/")
+	assert.Error(t, err)
+}
+
+func TestConsoleProxy(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var svr cloudServerService
+	var tokens int32
+	mux.HandleFunc(testlib.CloudServerURL(svr.itemActionPath("srv-1")), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		token := atomic.AddInt32(&tokens, 1)
+		_, _ = fmt.Fprintf(w, `{"console": {"type": "novnc", "url": "%s/vnc_auto.html?token=t%d"}}`, serverTest.URL, token)
+	})
+	mux.HandleFunc("/websockify", func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Values("Sec-WebSocket-Protocol"), consoleSubprotocol)
+		ws, err := websocket.Accept(w, r, &websocket.AcceptOptions{Subprotocols: []string{consoleSubprotocol}})
+		if !assert.NoError(t, err) {
+			return
+		}
+		conn := websocket.NetConn(r.Context(), ws, websocket.MessageBinary)
+		_, _ = fmt.Fprintf(conn, "RFB 003.008\n%s\n", r.URL.Query().Get("token"))
+		_, _ = io.Copy(conn, conn)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	proxy, err := client.CloudServer.Console().Proxy(ctx, "srv-1", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	for i := 1; i <= 2; i++ {
+		conn, err := net.Dial("tcp", proxy.Addr().String())
+		require.NoError(t, err)
+		greeting := make([]byte, len("RFB 003.008\nt1\n"))
+		_, err = io.ReadFull(conn, greeting)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("RFB 003.008\nt%d\n", i), string(greeting))
+		_, err = conn.Write([]byte{0x03, 0x08})
+		require.NoError(t, err)
+		echo := make([]byte, 2)
+		_, err = io.ReadFull(conn, echo)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x03, 0x08}, echo)
+		if i == 1 {
+			require.NoError(t, conn.Close())
+		} else {
+			defer conn.Close()
+		}
+	}
+
+	require.NoError(t, proxy.Close())
+	_, err = net.Dial("tcp", proxy.Addr().String())
+	assert.Error(t, err)
+}
@@ -0,0 +1,169 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	interfaceAttachAction = "attach_server"
+	interfaceDetachAction = "detach_server"
+	interfaceActiveStatus = "ACTIVE"
+)
+
+// FailoverResult contains the outcome and timing of moving an interface to another server.
+// RolledBack is set when detaching or attaching to the target failed and the interface was attached back.
+type FailoverResult struct {
+	InterfaceID  string        `json:"interface_id"`
+	FromServerID string        `json:"from_server_id"`
+	ToServerID   string        `json:"to_server_id"`
+	Detach       time.Duration `json:"detach"`
+	Attach       time.Duration `json:"attach"`
+	Total        time.Duration `json:"total"`
+	RolledBack   bool          `json:"rolled_back"`
+}
+
+// failoverInterface abstracts the private and public network interfaces which can be moved between servers.
+type failoverInterface interface {
+	attachment(ctx context.Context, id string) (serverID string, status string, err error)
+	action(ctx context.Context, id string, action string, serverID string) error
+}
+
+func (n cloudServerNetworkInterfaceResource) attachment(ctx context.Context, id string) (string, string, error) {
+	networkInterface, err := n.Get(ctx, id)
+	if err != nil {
+		return "", "", err
+	}
+	serverID := networkInterface.AttachedServer.ID
+	if serverID == "" {
+		serverID = networkInterface.DeviceID
+	}
+	return serverID, networkInterface.Status, nil
+}
+
+func (n cloudServerNetworkInterfaceResource) action(ctx context.Context, id string, action string, serverID string) error {
+	_, err := n.Action(ctx, id, &ActionNetworkInterfacePayload{Action: action, ServerID: serverID})
+	return err
+}
+
+func (w cloudServerPublicNetworkInterfaceResource) attachment(ctx context.Context, id string) (string, string, error) {
+	wanIP, err := w.Get(ctx, id)
+	if err != nil {
+		return "", "", err
+	}
+	serverID := wanIP.AttachedServer.ID
+	if serverID == "" {
+		serverID = wanIP.DeviceID
+	}
+	return serverID, wanIP.Status, nil
+}
+
+func (w cloudServerPublicNetworkInterfaceResource) action(ctx context.Context, id string, action string, serverID string) error {
+	return w.Action(ctx, id, &ActionPublicNetworkInterfacePayload{Action: action, ServerID: serverID})
+}
+
+// Failover moves a network interface, e.g. a VIP, from the server it is attached to onto toServerID.
+// The interface is detached, then attached once the detach is complete, both steps are retried on
+// transient errors. When the detach does not complete or attaching fails, the interface is attached back to
+// the original server, also when ctx is cancelled or expires, with its own timeout.
+func (n cloudServerNetworkInterfaceResource) Failover(ctx context.Context, interfaceID string, toServerID string) (*FailoverResult, error) {
+	return n.client.failover(ctx, n, interfaceID, toServerID)
+}
+
+// Failover moves a WAN IP from the server it is attached to onto toServerID, see the network interface Failover.
+func (w cloudServerPublicNetworkInterfaceResource) Failover(ctx context.Context, wanIPID string, toServerID string) (*FailoverResult, error) {
+	return w.client.failover(ctx, w, wanIPID, toServerID)
+}
+
+func (c *Client) failover(ctx context.Context, iface failoverInterface, id string, toServerID string) (*FailoverResult, error) {
+	start := time.Now()
+	fromServerID, _, err := iface.attachment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	result := &FailoverResult{InterfaceID: id, FromServerID: fromServerID, ToServerID: toServerID}
+	if fromServerID == toServerID {
+		result.Total = time.Since(start)
+		return result, nil
+	}
+
+	if fromServerID != "" {
+		err := c.retryAction(ctx, func() error {
+			return iface.action(ctx, id, interfaceDetachAction, fromServerID)
+		}, func() (bool, error) {
+			serverID, _, err := iface.attachment(ctx, id)
+			return err == nil && serverID != fromServerID, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = c.waitFor(ctx, func() (bool, error) {
+			serverID, _, err := iface.attachment(ctx, id)
+			return err == nil && serverID == "", err
+		})
+		if err != nil {
+			result.Total = time.Since(start)
+			if rollbackErr := c.rollbackInterface(ctx, iface, id, fromServerID); rollbackErr != nil {
+				return result, fmt.Errorf("detach from server %s failed: %v, rollback to server %s failed: %w",
+					fromServerID, err, fromServerID, rollbackErr)
+			}
+			result.RolledBack = true
+			result.Total = time.Since(start)
+			return result, fmt.Errorf("detach from server %s failed, interface was attached back: %w", fromServerID, err)
+		}
+		result.Detach = time.Since(start)
+	}
+
+	attachStart := time.Now()
+	if err := c.attachInterface(ctx, iface, id, toServerID); err != nil {
+		result.Total = time.Since(start)
+		if fromServerID == "" {
+			return result, err
+		}
+		if rollbackErr := c.rollbackInterface(ctx, iface, id, fromServerID); rollbackErr != nil {
+			return result, fmt.Errorf("attach to server %s failed: %v, rollback to server %s failed: %w",
+				toServerID, err, fromServerID, rollbackErr)
+		}
+		result.RolledBack = true
+		result.Total = time.Since(start)
+		return result, fmt.Errorf("attach to server %s failed, interface was attached back to server %s: %w",
+			toServerID, fromServerID, err)
+	}
+	result.Attach = time.Since(attachStart)
+	result.Total = time.Since(start)
+	return result, nil
+}
+
+// rollbackInterface attaches the interface back to a server unless it is still active on it. It runs with its
+// own timeout, also when ctx is cancelled or expired.
+func (c *Client) rollbackInterface(ctx context.Context, iface failoverInterface, id string, serverID string) error {
+	rollbackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultWaitTimeout)
+	defer cancel()
+	attachedTo, status, err := iface.attachment(rollbackCtx, id)
+	if err != nil {
+		return err
+	}
+	if attachedTo == serverID && status == interfaceActiveStatus {
+		return nil
+	}
+	return c.attachInterface(rollbackCtx, iface, id, serverID)
+}
+
+func (c *Client) attachInterface(ctx context.Context, iface failoverInterface, id string, serverID string) error {
+	err := c.retryAction(ctx, func() error {
+		return iface.action(ctx, id, interfaceAttachAction, serverID)
+	}, func() (bool, error) {
+		attachedTo, _, err := iface.attachment(ctx, id)
+		return err == nil && attachedTo == serverID, err
+	})
+	if err != nil {
+		return err
+	}
+	return c.waitFor(ctx, func() (bool, error) {
+		attachedTo, status, err := iface.attachment(ctx, id)
+		return err == nil && attachedTo == serverID && status == interfaceActiveStatus, err
+	})
+}
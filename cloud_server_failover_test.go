@@ -0,0 +1,156 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFailoverInterface serves an interface whose attachment settles one read after each action.
+type fakeFailoverInterface struct {
+	mu         sync.Mutex
+	serverID   string
+	status     string
+	pending    string
+	failServer string
+	// stuckServer never finishes attaching.
+	stuckServer string
+	// stuckDetach never finishes detaching.
+	stuckDetach bool
+	actions     []string
+}
+
+func (f *fakeFailoverInterface) register(t *testing.T, itemPath string, actionPath string) {
+	mux.HandleFunc(testlib.CloudServerURL(itemPath), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		_, _ = fmt.Fprintf(w, `{"id": "iface", "status": "%s", "attached_server": {"id": "%s"}}`, f.status, f.serverID)
+		switch f.pending {
+		case "detach":
+			if f.stuckDetach {
+				f.status = "DOWN"
+				return
+			}
+			f.serverID, f.status = "", "DOWN"
+		case "attach":
+			if f.serverID == f.stuckServer {
+				return
+			}
+			f.status = "ACTIVE"
+		}
+		f.pending = ""
+	})
+	mux.HandleFunc(testlib.CloudServerURL(actionPath), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		var payload ActionNetworkInterfacePayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.actions = append(f.actions, payload.Action+":"+payload.ServerID)
+		switch payload.Action {
+		case "detach_server":
+			f.pending = "detach"
+		case "attach_server":
+			if payload.ServerID == f.failServer {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f.serverID, f.status, f.pending = payload.ServerID, "BUILD", "attach"
+		}
+		_, _ = fmt.Fprint(w, `{"id": "iface"}`)
+	})
+}
+
+func TestNetworkInterfaceFailover(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	var n cloudServerNetworkInterfaceResource
+	f := &fakeFailoverInterface{serverID: "srv-active", status: "ACTIVE"}
+	f.register(t, n.itemPath("iface"), n.actionPath("iface"))
+
+	result, err := client.CloudServer.NetworkInterfaces().Failover(ctx, "iface", "srv-standby")
+	require.NoError(t, err)
+	assert.Equal(t, "srv-active", result.FromServerID)
+	assert.Equal(t, "srv-standby", result.ToServerID)
+	assert.False(t, result.RolledBack)
+	assert.True(t, result.Total >= result.Attach)
+	assert.Equal(t, []string{"detach_server:srv-active", "attach_server:srv-standby"}, f.actions)
+	assert.Equal(t, "srv-standby", f.serverID)
+
+	result, err = client.CloudServer.NetworkInterfaces().Failover(ctx, "iface", "srv-standby")
+	require.NoError(t, err)
+	assert.Equal(t, "srv-standby", result.FromServerID)
+	assert.Len(t, f.actions, 2)
+}
+
+func TestPublicNetworkInterfaceFailoverRollback(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	var w cloudServerPublicNetworkInterfaceResource
+	f := &fakeFailoverInterface{serverID: "srv-active", status: "ACTIVE", failServer: "srv-standby"}
+	f.register(t, w.itemPath("iface"), w.actionPath("iface"))
+
+	result, err := client.CloudServer.PublicNetworkInterfaces().Failover(ctx, "iface", "srv-standby")
+	require.Error(t, err)
+	require.NotNil(t, result)
+	assert.True(t, result.RolledBack)
+	assert.Equal(t, []string{"detach_server:srv-active", "attach_server:srv-standby", "attach_server:srv-active"}, f.actions)
+	assert.Equal(t, "srv-active", f.serverID)
+	assert.Equal(t, "ACTIVE", f.status)
+}
+
+func TestNetworkInterfaceFailoverRollbackAfterTimeout(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	var n cloudServerNetworkInterfaceResource
+	f := &fakeFailoverInterface{serverID: "srv-active", status: "ACTIVE", stuckServer: "srv-standby"}
+	f.register(t, n.itemPath("iface"), n.actionPath("iface"))
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	result, err := client.CloudServer.NetworkInterfaces().Failover(timeoutCtx, "iface", "srv-standby")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.NotNil(t, result)
+	assert.True(t, result.RolledBack)
+	assert.Equal(t, []string{"detach_server:srv-active", "attach_server:srv-standby", "attach_server:srv-active"}, f.actions)
+	assert.Equal(t, "srv-active", f.serverID)
+	assert.Equal(t, "ACTIVE", f.status)
+}
+
+func TestNetworkInterfaceFailoverRollbackAfterDetachTimeout(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	var n cloudServerNetworkInterfaceResource
+	f := &fakeFailoverInterface{serverID: "srv-active", status: "ACTIVE", stuckDetach: true}
+	f.register(t, n.itemPath("iface"), n.actionPath("iface"))
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	result, err := client.CloudServer.NetworkInterfaces().Failover(timeoutCtx, "iface", "srv-standby")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.NotNil(t, result)
+	assert.True(t, result.RolledBack)
+	assert.Equal(t, []string{"detach_server:srv-active", "attach_server:srv-active"}, f.actions)
+	assert.Equal(t, "srv-active", f.serverID)
+	assert.Equal(t, "ACTIVE", f.status)
+}
@@ -31,6 +31,8 @@ type FirewallService interface {
 	RemoveServer(ctx context.Context, id string, rsfr *FirewallRemoveServerRequest) (*Firewall, error)
 	Update(ctx context.Context, id string, ufr *FirewallRequestPayload) (*FirewallDetail, error)
 	DeleteRule(ctx context.Context, id string) (*FirewallDeleteResponse, error)
+	Sync(ctx context.Context, name string, desired *FirewallRuleSet, opts *FirewallSyncOptions) (*FirewallDiff, error)
+	Audit(ctx context.Context) (*FirewallAuditReport, error)
 }
 
 // BaseFirewall - contains base information fields of a firewall
@@ -169,6 +171,9 @@ func (f *cloudServerFirewallResource) List(ctx context.Context, opts *ListOption
 
 // Create a firewall.
 func (f *cloudServerFirewallResource) Create(ctx context.Context, fcr *FirewallRequestPayload) (*FirewallDetail, error) {
+	if err := fcr.Validate(); err != nil {
+		return nil, err
+	}
 
 	req, err := f.client.NewRequest(ctx, http.MethodPost, serverServiceName, firewallBasePath, fcr)
 	if err != nil {
@@ -268,6 +273,9 @@ func (f *cloudServerFirewallResource) RemoveServer(ctx context.Context, id strin
 
 // Update Firewall
 func (f *cloudServerFirewallResource) Update(ctx context.Context, id string, ufr *FirewallRequestPayload) (*FirewallDetail, error) {
+	if err := ufr.Validate(); err != nil {
+		return nil, err
+	}
 
 	req, err := f.client.NewRequest(ctx, http.MethodPatch, serverServiceName, firewallBasePath+"/"+id, ufr)
 
@@ -0,0 +1,164 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// FirewallAuditSeverity represents how risky a firewall finding is.
+type FirewallAuditSeverity string
+
+const (
+	FirewallAuditSeverityCritical FirewallAuditSeverity = "critical"
+	FirewallAuditSeverityHigh     FirewallAuditSeverity = "high"
+	FirewallAuditSeverityMedium   FirewallAuditSeverity = "medium"
+	FirewallAuditSeverityLow      FirewallAuditSeverity = "low"
+)
+
+const (
+	FirewallAuditAllPortsOpen      = "all_ports_open"
+	FirewallAuditSensitivePortOpen = "sensitive_port_open"
+	FirewallAuditUnattached        = "unattached"
+)
+
+// firewallSensitivePorts are ports which should never be reachable from anywhere.
+var firewallSensitivePorts = map[int]string{
+	22:    "SSH",
+	1433:  "MSSQL",
+	3306:  "MySQL",
+	3389:  "RDP",
+	5432:  "PostgreSQL",
+	6379:  "Redis",
+	9200:  "Elasticsearch",
+	11211: "Memcached",
+	27017: "MongoDB",
+}
+
+var firewallAuditSeverityOrder = map[FirewallAuditSeverity]int{
+	FirewallAuditSeverityCritical: 0,
+	FirewallAuditSeverityHigh:     1,
+	FirewallAuditSeverityMedium:   2,
+	FirewallAuditSeverityLow:      3,
+}
+
+// FirewallFinding contains a risky rule or configuration of a firewall.
+type FirewallFinding struct {
+	Severity          FirewallAuditSeverity `json:"severity"`
+	Code              string                `json:"code"`
+	Message           string                `json:"message"`
+	FirewallID        string                `json:"firewall_id"`
+	FirewallName      string                `json:"firewall_name"`
+	RuleID            string                `json:"rule_id,omitempty"`
+	Servers           []string              `json:"servers"`
+	NetworkInterfaces []string              `json:"network_interfaces"`
+}
+
+// FirewallAuditReport contains the findings of a firewall audit ordered by severity.
+type FirewallAuditReport struct {
+	GeneratedAt time.Time                     `json:"generated_at"`
+	Firewalls   int                           `json:"firewalls"`
+	Summary     map[FirewallAuditSeverity]int `json:"summary"`
+	Findings    []FirewallFinding             `json:"findings"`
+}
+
+// Audit walks every firewall of the project and reports risky inbound rules, such as sensitive
+// ports or all ports open to the internet, and firewalls which are not attached to anything.
+func (f *cloudServerFirewallResource) Audit(ctx context.Context) (*FirewallAuditReport, error) {
+	firewalls, err := f.List(ctx, &ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	report := &FirewallAuditReport{
+		GeneratedAt: time.Now().UTC(),
+		Firewalls:   len(firewalls),
+		Summary:     make(map[FirewallAuditSeverity]int),
+		Findings:    []FirewallFinding{},
+	}
+	for _, firewall := range firewalls {
+		detail, err := f.Get(ctx, firewall.ID)
+		if err != nil {
+			return nil, err
+		}
+		findings, err := auditFirewall(detail)
+		if err != nil {
+			return nil, err
+		}
+		report.Findings = append(report.Findings, findings...)
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return firewallAuditSeverityOrder[report.Findings[i].Severity] < firewallAuditSeverityOrder[report.Findings[j].Severity]
+	})
+	for _, finding := range report.Findings {
+		report.Summary[finding.Severity]++
+	}
+	return report, nil
+}
+
+func auditFirewall(firewall *FirewallDetail) ([]FirewallFinding, error) {
+	servers := make([]string, 0, len(firewall.Servers))
+	for _, server := range firewall.Servers {
+		servers = append(servers, server.ID)
+	}
+	networkInterfaces := make([]string, 0, len(firewall.NetworkInterface))
+	for _, networkInterface := range firewall.NetworkInterface {
+		networkInterfaces = append(networkInterfaces, networkInterface.ID)
+	}
+	newFinding := func(severity FirewallAuditSeverity, code string, ruleID string, message string) FirewallFinding {
+		return FirewallFinding{
+			Severity:          severity,
+			Code:              code,
+			Message:           message,
+			FirewallID:        firewall.ID,
+			FirewallName:      firewall.Name,
+			RuleID:            ruleID,
+			Servers:           servers,
+			NetworkInterfaces: networkInterfaces,
+		}
+	}
+
+	attached := len(servers) > 0 || len(networkInterfaces) > 0
+	var findings []FirewallFinding
+	if !attached {
+		findings = append(findings, newFinding(FirewallAuditSeverityLow, FirewallAuditUnattached, "",
+			"firewall is not attached to any server or network interface"))
+	}
+	for _, rule := range firewall.InBound {
+		key, err := firewallRuleKeyFromRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		if key.protocol == "icmp" {
+			continue
+		}
+		public := key.cidr == "0.0.0.0/0" || key.cidr == "::/0"
+		if key.portMin == 0 {
+			severity := FirewallAuditSeverityMedium
+			if public {
+				severity = FirewallAuditSeverityCritical
+			}
+			findings = append(findings, newFinding(severity, FirewallAuditAllPortsOpen, rule.ID,
+				fmt.Sprintf("all ports are open to %s", key.cidr)))
+			continue
+		}
+		if !public {
+			continue
+		}
+		for port := key.portMin; port <= key.portMax; port++ {
+			name, ok := firewallSensitivePorts[port]
+			if !ok {
+				continue
+			}
+			severity := FirewallAuditSeverityHigh
+			if !attached {
+				severity = FirewallAuditSeverityMedium
+			}
+			findings = append(findings, newFinding(severity, FirewallAuditSensitivePortOpen, rule.ID,
+				fmt.Sprintf("%s port %d is open to %s", name, port, key.cidr)))
+		}
+	}
+	return findings, nil
+}
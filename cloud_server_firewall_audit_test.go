@@ -0,0 +1,94 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFirewallAudit(t *testing.T) {
+	setup()
+	defer teardown()
+	mux.HandleFunc(testlib.CloudServerURL(firewallBasePath), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, `[{"id": "fw-web", "name": "web"}, {"id": "fw-idle", "name": "idle"}]`)
+	})
+	mux.HandleFunc(testlib.CloudServerURL(firewallBasePath+"/fw-web"), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, `
+{
+    "id": "fw-web",
+    "name": "web",
+    "servers": [{"id": "srv-1"}],
+    "network_interface": [{"id": "port-1"}],
+    "inbound": [
+        {"id": "r-ssh", "direction": "ingress", "ethertype": "IPv4", "protocol": "tcp",
+         "port_range_min": 22, "port_range_max": 22, "remote_ip_prefix": "0.0.0.0/0"},
+        {"id": "r-db", "direction": "ingress", "ethertype": "IPv4", "protocol": "tcp",
+         "port_range_min": 3306, "port_range_max": 3306, "remote_ip_prefix": "10.0.0.0/8"},
+        {"id": "r-all", "direction": "ingress", "ethertype": "IPv6", "protocol": null,
+         "port_range_min": null, "port_range_max": null, "remote_ip_prefix": null},
+        {"id": "r-ping", "direction": "ingress", "ethertype": "IPv4", "protocol": "icmp",
+         "port_range_min": null, "port_range_max": null, "remote_ip_prefix": null},
+        {"id": "r-web", "direction": "ingress", "ethertype": "IPv4", "protocol": "tcp",
+         "port_range_min": 80, "port_range_max": 443, "remote_ip_prefix": "0.0.0.0/0"}
+    ],
+    "outbound": [
+        {"id": "r-out", "direction": "egress", "ethertype": "IPv4", "protocol": null, "remote_ip_prefix": null}
+    ]
+}`)
+	})
+	mux.HandleFunc(testlib.CloudServerURL(firewallBasePath+"/fw-idle"), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, `
+{
+    "id": "fw-idle",
+    "name": "idle",
+    "servers": [],
+    "network_interface": [],
+    "inbound": [
+        {"id": "r-rdp", "direction": "ingress", "ethertype": "IPv4", "protocol": "tcp",
+         "port_range_min": 3389, "port_range_max": 3389, "remote_ip_prefix": "0.0.0.0/0"}
+    ],
+    "outbound": []
+}`)
+	})
+
+	report, err := client.CloudServer.Firewalls().Audit(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Firewalls)
+	require.Len(t, report.Findings, 4)
+
+	critical := report.Findings[0]
+	assert.Equal(t, FirewallAuditSeverityCritical, critical.Severity)
+	assert.Equal(t, FirewallAuditAllPortsOpen, critical.Code)
+	assert.Equal(t, "r-all", critical.RuleID)
+	assert.Equal(t, []string{"srv-1"}, critical.Servers)
+	assert.Equal(t, []string{"port-1"}, critical.NetworkInterfaces)
+
+	assert.Equal(t, FirewallAuditSeverityHigh, report.Findings[1].Severity)
+	assert.Equal(t, "r-ssh", report.Findings[1].RuleID)
+	assert.Equal(t, FirewallAuditSeverityMedium, report.Findings[2].Severity)
+	assert.Equal(t, "r-rdp", report.Findings[2].RuleID)
+	assert.Equal(t, FirewallAuditSeverityLow, report.Findings[3].Severity)
+	assert.Equal(t, FirewallAuditUnattached, report.Findings[3].Code)
+	assert.Equal(t, "fw-idle", report.Findings[3].FirewallID)
+
+	assert.Equal(t, map[FirewallAuditSeverity]int{
+		FirewallAuditSeverityCritical: 1,
+		FirewallAuditSeverityHigh:     1,
+		FirewallAuditSeverityMedium:   1,
+		FirewallAuditSeverityLow:      1,
+	}, report.Summary)
+
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"severity":"critical"`)
+}
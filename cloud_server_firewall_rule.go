@@ -0,0 +1,205 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// FirewallRuleType is the type of a firewall rule, e.g. SSH or CUSTOM.
+type FirewallRuleType string
+
+// FirewallProtocol is the protocol of a firewall rule, an empty protocol matches all protocols.
+type FirewallProtocol string
+
+const (
+	FirewallRuleTypeCustom FirewallRuleType = "CUSTOM"
+	FirewallRuleTypeSSH    FirewallRuleType = "SSH"
+	FirewallRuleTypeHTTP   FirewallRuleType = "HTTP"
+	FirewallRuleTypeHTTPS  FirewallRuleType = "HTTPS"
+	FirewallRuleTypeRDP    FirewallRuleType = "RDP"
+	FirewallRuleTypePing   FirewallRuleType = "PING"
+
+	FirewallProtocolTCP  FirewallProtocol = "TCP"
+	FirewallProtocolUDP  FirewallProtocol = "UDP"
+	FirewallProtocolICMP FirewallProtocol = "ICMP"
+)
+
+// KubernetesNodePortRange is the default port range of Kubernetes NodePort services.
+var KubernetesNodePortRange = PortRange{From: 30000, To: 32767}
+
+// PortRange represents an inclusive range of ports.
+type PortRange struct {
+	From uint16
+	To   uint16
+}
+
+// Port returns a port range containing a single port.
+func Port(port uint16) PortRange {
+	return PortRange{From: port, To: port}
+}
+
+// Ports returns a port range from a port to another.
+func Ports(from uint16, to uint16) PortRange {
+	return PortRange{From: from, To: to}
+}
+
+// ParsePortRange parses a port range in the form of "22" or "1-255".
+func ParsePortRange(value string) (PortRange, error) {
+	low, high, found := strings.Cut(value, "-")
+	from, err := strconv.ParseUint(low, 10, 16)
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q: %w", value, ErrCommon)
+	}
+	r := Port(uint16(from))
+	if found {
+		to, err := strconv.ParseUint(high, 10, 16)
+		if err != nil {
+			return PortRange{}, fmt.Errorf("invalid port range %q: %w", value, ErrCommon)
+		}
+		r.To = uint16(to)
+	}
+	if err := r.Validate(); err != nil {
+		return PortRange{}, err
+	}
+	return r, nil
+}
+
+// Validate checks that the port range is not empty and starts from port 1 or above.
+func (p PortRange) Validate() error {
+	if p.From == 0 || p.To < p.From {
+		return fmt.Errorf("invalid port range %d-%d: %w", p.From, p.To, ErrCommon)
+	}
+	return nil
+}
+
+// String returns the port range in the format expected by the firewall API.
+func (p PortRange) String() string {
+	if p.From == p.To {
+		return strconv.Itoa(int(p.From))
+	}
+	return fmt.Sprintf("%d-%d", p.From, p.To)
+}
+
+// newFirewallRule builds a rule, an empty protocol and a nil port range are sent as null.
+// An invalid cidr is kept in its string form, "invalid Prefix", so that Validate rejects the rule
+// instead of allowing any address.
+func newFirewallRule(ruleType FirewallRuleType, protocol FirewallProtocol, ports *PortRange, cidr netip.Prefix) FirewallRuleCreateRequest {
+	rule := FirewallRuleCreateRequest{Type: string(ruleType), CIDR: cidr.String()}
+	if protocol != "" {
+		rule.Protocol = string(protocol)
+	}
+	if ports != nil {
+		rule.PortRange = ports.String()
+	}
+	if cidr.IsValid() {
+		rule.CIDR = cidr.Masked().String()
+	}
+	return rule
+}
+
+// firewallRuleField returns the string form of the Type, Protocol or PortRange of a rule, nil is empty.
+func firewallRuleField(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// AllowTCP returns a rule allowing TCP traffic on the given ports from or to cidr.
+func AllowTCP(ports PortRange, cidr netip.Prefix) FirewallRuleCreateRequest {
+	return newFirewallRule(FirewallRuleTypeCustom, FirewallProtocolTCP, &ports, cidr)
+}
+
+// AllowUDP returns a rule allowing UDP traffic on the given ports from or to cidr.
+func AllowUDP(ports PortRange, cidr netip.Prefix) FirewallRuleCreateRequest {
+	return newFirewallRule(FirewallRuleTypeCustom, FirewallProtocolUDP, &ports, cidr)
+}
+
+// AllowICMP returns a rule allowing ICMP traffic from or to cidr.
+func AllowICMP(cidr netip.Prefix) FirewallRuleCreateRequest {
+	return newFirewallRule(FirewallRuleTypePing, FirewallProtocolICMP, nil, cidr)
+}
+
+// AllowAll returns a rule allowing all traffic from or to cidr.
+func AllowAll(cidr netip.Prefix) FirewallRuleCreateRequest {
+	return newFirewallRule(FirewallRuleTypeCustom, "", nil, cidr)
+}
+
+// AllowSSH returns a rule allowing SSH from cidr.
+func AllowSSH(cidr netip.Prefix) FirewallRuleCreateRequest {
+	ports := Port(22)
+	return newFirewallRule(FirewallRuleTypeSSH, FirewallProtocolTCP, &ports, cidr)
+}
+
+// AllowRDP returns a rule allowing RDP from cidr.
+func AllowRDP(cidr netip.Prefix) FirewallRuleCreateRequest {
+	ports := Port(3389)
+	return newFirewallRule(FirewallRuleTypeRDP, FirewallProtocolTCP, &ports, cidr)
+}
+
+// AllowHTTP returns a rule allowing HTTP from cidr.
+func AllowHTTP(cidr netip.Prefix) FirewallRuleCreateRequest {
+	ports := Port(80)
+	return newFirewallRule(FirewallRuleTypeHTTP, FirewallProtocolTCP, &ports, cidr)
+}
+
+// AllowHTTPS returns a rule allowing HTTPS from cidr.
+func AllowHTTPS(cidr netip.Prefix) FirewallRuleCreateRequest {
+	ports := Port(443)
+	return newFirewallRule(FirewallRuleTypeHTTPS, FirewallProtocolTCP, &ports, cidr)
+}
+
+// AllowKubernetesNodePorts returns a rule allowing the Kubernetes NodePort range from cidr.
+func AllowKubernetesNodePorts(cidr netip.Prefix) FirewallRuleCreateRequest {
+	return AllowTCP(KubernetesNodePortRange, cidr)
+}
+
+// Validate checks the protocol, port range and cidr of a rule.
+// The cidr may also be a single address, which the API treats as a host route.
+func (r FirewallRuleCreateRequest) Validate() error {
+	// Fields are sent as is, so surrounding spaces are rejected rather than trimmed.
+	for _, field := range []interface{}{r.Protocol, r.PortRange} {
+		if value := firewallRuleField(field); value != strings.TrimSpace(value) {
+			return fmt.Errorf("invalid rule field %q: %w", value, ErrCommon)
+		}
+	}
+	switch protocol := firewallRuleField(r.Protocol); strings.ToUpper(protocol) {
+	case "", string(FirewallProtocolTCP), string(FirewallProtocolUDP), string(FirewallProtocolICMP):
+	default:
+		return fmt.Errorf("invalid protocol %q: %w", protocol, ErrCommon)
+	}
+	if portRange := firewallRuleField(r.PortRange); portRange != "" {
+		if _, err := ParsePortRange(portRange); err != nil {
+			return err
+		}
+	}
+	if strings.Contains(r.CIDR, "/") {
+		if _, err := netip.ParsePrefix(r.CIDR); err != nil {
+			return fmt.Errorf("invalid cidr %q: %w", r.CIDR, ErrCommon)
+		}
+	} else if r.CIDR != "" {
+		if _, err := netip.ParseAddr(r.CIDR); err != nil {
+			return fmt.Errorf("invalid cidr %q: %w", r.CIDR, ErrCommon)
+		}
+	}
+	return nil
+}
+
+// Validate checks every inbound and outbound rule of the payload.
+func (p *FirewallRequestPayload) Validate() error {
+	for _, rule := range p.InBound {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("inbound rule: %w", err)
+		}
+	}
+	for _, rule := range p.OutBound {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("outbound rule: %w", err)
+		}
+	}
+	return nil
+}
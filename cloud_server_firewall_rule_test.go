@@ -0,0 +1,77 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"encoding/json"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFirewallRuleBuildersJSON(t *testing.T) {
+	anywhere := netip.MustParsePrefix("0.0.0.0/0")
+	tests := []struct {
+		rule     FirewallRuleCreateRequest
+		expected string
+	}{
+		{AllowSSH(anywhere), `{"type":"SSH","protocol":"TCP","port_range":"22","cidr":"0.0.0.0/0"}`},
+		{AllowHTTP(anywhere), `{"type":"HTTP","protocol":"TCP","port_range":"80","cidr":"0.0.0.0/0"}`},
+		{AllowHTTPS(anywhere), `{"type":"HTTPS","protocol":"TCP","port_range":"443","cidr":"0.0.0.0/0"}`},
+		{AllowRDP(anywhere), `{"type":"RDP","protocol":"TCP","port_range":"3389","cidr":"0.0.0.0/0"}`},
+		{AllowICMP(netip.MustParsePrefix("::/0")), `{"type":"PING","protocol":"ICMP","port_range":null,"cidr":"::/0"}`},
+		{AllowTCP(Ports(1, 255), netip.MustParsePrefix("192.168.0.5/28")), `{"type":"CUSTOM","protocol":"TCP","port_range":"1-255","cidr":"192.168.0.0/28"}`},
+		{AllowUDP(Port(53), netip.MustParsePrefix("10.0.0.0/8")), `{"type":"CUSTOM","protocol":"UDP","port_range":"53","cidr":"10.0.0.0/8"}`},
+		{AllowKubernetesNodePorts(anywhere), `{"type":"CUSTOM","protocol":"TCP","port_range":"30000-32767","cidr":"0.0.0.0/0"}`},
+		{AllowAll(anywhere), `{"type":"CUSTOM","protocol":null,"port_range":null,"cidr":"0.0.0.0/0"}`},
+		{FirewallRuleCreateRequest{Type: "PING", Protocol: "ICMP", CIDR: "::/0"}, `{"type":"PING","protocol":"ICMP","port_range":null,"cidr":"::/0"}`},
+	}
+	for _, tc := range tests {
+		data, err := json.Marshal(tc.rule)
+		require.NoError(t, err)
+		assert.JSONEq(t, tc.expected, string(data))
+		assert.NoError(t, tc.rule.Validate())
+	}
+}
+
+func TestFirewallRuleValidate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	invalid := []FirewallRuleCreateRequest{
+		{Type: "CUSTOM", Protocol: "sctp", PortRange: "80", CIDR: "0.0.0.0/0"},
+		{Type: "CUSTOM", Protocol: "TCP", PortRange: "80-", CIDR: "0.0.0.0/0"},
+		{Type: "CUSTOM", Protocol: "TCP", PortRange: "90-80", CIDR: "0.0.0.0/0"},
+		{Type: "CUSTOM", Protocol: "TCP", PortRange: "0", CIDR: "0.0.0.0/0"},
+		{Type: "CUSTOM", Protocol: "TCP", PortRange: "70000", CIDR: "0.0.0.0/0"},
+		{Type: "CUSTOM", Protocol: "TCP", PortRange: "80", CIDR: "10.0.0.0/33"},
+		{Type: "CUSTOM", Protocol: "TCP", PortRange: "80", CIDR: "10.0.0"},
+		{Type: "CUSTOM", Protocol: "tcp ", PortRange: "80", CIDR: "0.0.0.0/0"},
+		{Type: "CUSTOM", Protocol: "TCP", PortRange: " 80", CIDR: "0.0.0.0/0"},
+		AllowSSH(netip.Prefix{}),
+		AllowAll(netip.Prefix{}),
+	}
+	for _, rule := range invalid {
+		assert.Error(t, rule.Validate(), "rule %+v", rule)
+	}
+
+	payload := &FirewallRequestPayload{
+		Name:     "web",
+		InBound:  []FirewallRuleCreateRequest{AllowSSH(netip.MustParsePrefix("10.0.0.0/8"))},
+		OutBound: []FirewallRuleCreateRequest{invalid[0]},
+	}
+	_, err := client.CloudServer.Firewalls().Create(ctx, payload)
+	assert.Error(t, err)
+}
+
+func TestParsePortRange(t *testing.T) {
+	r, err := ParsePortRange("8000-8100")
+	require.NoError(t, err)
+	assert.Equal(t, Ports(8000, 8100), r)
+	assert.Equal(t, "8000-8100", r.String())
+	r, err = ParsePortRange("22")
+	require.NoError(t, err)
+	assert.Equal(t, "22", r.String())
+}
@@ -0,0 +1,262 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+const (
+	firewallIngressDirection = "ingress"
+	firewallEgressDirection  = "egress"
+)
+
+// FirewallRuleSet represents the desired inbound and outbound rules of a firewall.
+type FirewallRuleSet struct {
+	InBound  []FirewallRuleCreateRequest `json:"inbound"`
+	OutBound []FirewallRuleCreateRequest `json:"outbound"`
+}
+
+// FirewallSyncOptions represents options when syncing a firewall.
+// DryRun computes the diff without changing anything.
+type FirewallSyncOptions struct {
+	DryRun bool `json:"dry_run"`
+}
+
+// FirewallDiff contains the changes needed to make a firewall match a rule set.
+// Create is true when no firewall with the given name exists yet.
+type FirewallDiff struct {
+	FirewallID  string                      `json:"firewall_id"`
+	Create      bool                        `json:"create"`
+	AddInBound  []FirewallRuleCreateRequest `json:"add_inbound"`
+	AddOutBound []FirewallRuleCreateRequest `json:"add_outbound"`
+	Remove      []FirewallRule              `json:"remove"`
+}
+
+// Empty reports whether the firewall already matches the rule set.
+func (d *FirewallDiff) Empty() bool {
+	return !d.Create && len(d.AddInBound) == 0 && len(d.AddOutBound) == 0 && len(d.Remove) == 0
+}
+
+// firewallRuleKey is the comparable form shared by FirewallRule and FirewallRuleCreateRequest.
+type firewallRuleKey struct {
+	direction string
+	protocol  string
+	portMin   int
+	portMax   int
+	cidr      string
+}
+
+func normalizeFirewallProtocol(protocol string) string {
+	protocol = strings.ToLower(strings.TrimSpace(protocol))
+	if protocol == "any" || protocol == "all" {
+		return ""
+	}
+	return protocol
+}
+
+func normalizeFirewallPorts(protocol string, portMin int, portMax int) (int, int) {
+	if protocol == "" || protocol == "icmp" || (portMin <= 1 && portMax >= 65535) {
+		return 0, 0
+	}
+	if portMax == 0 {
+		portMax = portMin
+	}
+	return portMin, portMax
+}
+
+func normalizeFirewallCIDR(cidr string, etherType string) (string, error) {
+	cidr = strings.TrimSpace(cidr)
+	if cidr == "" {
+		if etherType == "IPv6" {
+			return "::/0", nil
+		}
+		return "0.0.0.0/0", nil
+	}
+	if !strings.Contains(cidr, "/") {
+		addr, err := netip.ParseAddr(cidr)
+		if err != nil {
+			return "", err
+		}
+		return netip.PrefixFrom(addr, addr.BitLen()).String(), nil
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return "", err
+	}
+	return prefix.Masked().String(), nil
+}
+
+// firewallRuleCIDR returns the cidr a rule applies to. List and Get fill an empty CIDR from the ether type,
+// so the remote prefix is more accurate.
+func firewallRuleCIDR(rule FirewallRule) string {
+	if rule.RemoteIPPrefix != "" {
+		return rule.RemoteIPPrefix
+	}
+	return rule.CIDR
+}
+
+func firewallRuleKeyFromRule(rule FirewallRule) (firewallRuleKey, error) {
+	cidr := firewallRuleCIDR(rule)
+	normalizedCIDR, err := normalizeFirewallCIDR(cidr, rule.EtherType)
+	if err != nil {
+		return firewallRuleKey{}, fmt.Errorf("rule %s: invalid cidr %q: %w", rule.ID, cidr, ErrCommon)
+	}
+	protocol := normalizeFirewallProtocol(rule.Protocol)
+	portMin, portMax := normalizeFirewallPorts(protocol, rule.PortRangeMin, rule.PortRangeMax)
+	return firewallRuleKey{
+		direction: rule.Direction,
+		protocol:  protocol,
+		portMin:   portMin,
+		portMax:   portMax,
+		cidr:      normalizedCIDR,
+	}, nil
+}
+
+func firewallRuleKeyFromRequest(direction string, rule FirewallRuleCreateRequest) (firewallRuleKey, error) {
+	if err := rule.Validate(); err != nil {
+		return firewallRuleKey{}, err
+	}
+	protocol := normalizeFirewallProtocol(firewallRuleField(rule.Protocol))
+	var portMin, portMax int
+	if portRange := firewallRuleField(rule.PortRange); portRange != "" {
+		ports, _ := ParsePortRange(portRange)
+		portMin, portMax = int(ports.From), int(ports.To)
+	}
+	portMin, portMax = normalizeFirewallPorts(protocol, portMin, portMax)
+	cidr, err := normalizeFirewallCIDR(rule.CIDR, "")
+	if err != nil {
+		return firewallRuleKey{}, fmt.Errorf("invalid cidr %q: %w", rule.CIDR, ErrCommon)
+	}
+	return firewallRuleKey{
+		direction: direction,
+		protocol:  protocol,
+		portMin:   portMin,
+		portMax:   portMax,
+		cidr:      cidr,
+	}, nil
+}
+
+// diffFirewall compares the rules of an existing firewall with the desired rule set.
+func diffFirewall(current []FirewallRule, desired *FirewallRuleSet) (*FirewallDiff, error) {
+	diff := &FirewallDiff{}
+	existing := make(map[firewallRuleKey]bool, len(current))
+	for _, rule := range current {
+		key, err := firewallRuleKeyFromRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		existing[key] = true
+	}
+
+	wanted := make(map[firewallRuleKey]bool)
+	add := func(direction string, rules []FirewallRuleCreateRequest) ([]FirewallRuleCreateRequest, error) {
+		var added []FirewallRuleCreateRequest
+		for _, rule := range rules {
+			key, err := firewallRuleKeyFromRequest(direction, rule)
+			if err != nil {
+				return nil, err
+			}
+			if wanted[key] {
+				continue
+			}
+			wanted[key] = true
+			if !existing[key] {
+				added = append(added, rule)
+			}
+		}
+		return added, nil
+	}
+	var err error
+	if diff.AddInBound, err = add(firewallIngressDirection, desired.InBound); err != nil {
+		return nil, err
+	}
+	if diff.AddOutBound, err = add(firewallEgressDirection, desired.OutBound); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range current {
+		key, _ := firewallRuleKeyFromRule(rule)
+		if !wanted[key] {
+			diff.Remove = append(diff.Remove, rule)
+		}
+	}
+	return diff, nil
+}
+
+// Sync makes the rules of the firewall with the given name match the desired rule set.
+// The firewall is created when it does not exist, missing rules are added with Update and
+// extra rules are removed with DeleteRule. The returned diff lists the changes made, or the
+// changes that would be made when DryRun is set.
+func (f *cloudServerFirewallResource) Sync(ctx context.Context, name string, desired *FirewallRuleSet, opts *FirewallSyncOptions) (*FirewallDiff, error) {
+	if opts == nil {
+		opts = &FirewallSyncOptions{}
+	}
+	if desired == nil {
+		desired = &FirewallRuleSet{}
+	}
+	firewalls, err := f.List(ctx, &ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var current *Firewall
+	for _, firewall := range firewalls {
+		if firewall.Name != name {
+			continue
+		}
+		if current != nil {
+			return nil, fmt.Errorf("more than one firewall named %s: %w", name, ErrCommon)
+		}
+		current = firewall
+	}
+
+	if current == nil {
+		diff, err := diffFirewall(nil, desired)
+		if err != nil {
+			return nil, err
+		}
+		diff.Create = true
+		if opts.DryRun {
+			return diff, nil
+		}
+		created, err := f.Create(ctx, &FirewallRequestPayload{
+			Name:     name,
+			InBound:  diff.AddInBound,
+			OutBound: diff.AddOutBound,
+		})
+		if err != nil {
+			return nil, err
+		}
+		diff.FirewallID = created.ID
+		return diff, nil
+	}
+
+	rules := append(append([]FirewallRule{}, current.InBound...), current.OutBound...)
+	diff, err := diffFirewall(rules, desired)
+	if err != nil {
+		return nil, err
+	}
+	diff.FirewallID = current.ID
+	if opts.DryRun {
+		return diff, nil
+	}
+	if len(diff.AddInBound) > 0 || len(diff.AddOutBound) > 0 {
+		_, err := f.Update(ctx, current.ID, &FirewallRequestPayload{
+			Name:     name,
+			InBound:  diff.AddInBound,
+			OutBound: diff.AddOutBound,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, rule := range diff.Remove {
+		if _, err := f.DeleteRule(ctx, rule.ID); err != nil {
+			return nil, err
+		}
+	}
+	return diff, nil
+}
@@ -0,0 +1,149 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const firewallSyncListResponse = `
+[
+    {
+        "id": "fw-web",
+        "name": "web",
+        "servers": [],
+        "inbound": [
+            {"id": "r-ssh", "direction": "ingress", "ethertype": "IPv4", "protocol": "tcp",
+             "port_range_min": 22, "port_range_max": 22, "remote_ip_prefix": "0.0.0.0/0", "type": "SSH", "cidr": "0.0.0.0/0", "port_range": "22"},
+            {"id": "r-ping", "direction": "ingress", "ethertype": "IPv4", "protocol": "icmp",
+             "port_range_min": null, "port_range_max": null, "remote_ip_prefix": null, "type": "PING", "cidr": null, "port_range": null},
+            {"id": "r-rdp", "direction": "ingress", "ethertype": "IPv4", "protocol": "tcp",
+             "port_range_min": 3389, "port_range_max": 3389, "remote_ip_prefix": "0.0.0.0/0", "type": "RDP", "cidr": "0.0.0.0/0", "port_range": "3389"}
+        ],
+        "outbound": [
+            {"id": "r-out", "direction": "egress", "ethertype": "IPv4", "protocol": null,
+             "port_range_min": null, "port_range_max": null, "remote_ip_prefix": null, "type": "ALL OUT", "cidr": null, "port_range": null}
+        ]
+    }
+]
+`
+
+func firewallSyncDesired() *FirewallRuleSet {
+	return &FirewallRuleSet{
+		InBound: []FirewallRuleCreateRequest{
+			{Type: "SSH", Protocol: "TCP", PortRange: "22", CIDR: "0.0.0.0/0"},
+			{Type: "PING", Protocol: "ICMP", CIDR: "0.0.0.0/0"},
+			{Type: "HTTP", Protocol: "TCP", PortRange: "80", CIDR: "10.0.0.5"},
+		},
+		OutBound: []FirewallRuleCreateRequest{
+			{Type: "ALL OUT"},
+		},
+	}
+}
+
+func TestDiffFirewallNormalizesRules(t *testing.T) {
+	current := []FirewallRule{
+		{ID: "a", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 1, PortRangeMax: 65535, RemoteIPPrefix: "10.0.0.0/8",
+			CIDR: "0.0.0.0/0"},
+		{ID: "b", Direction: "ingress", EtherType: "IPv6", Protocol: "udp", PortRangeMin: 53, PortRangeMax: 53},
+		{ID: "c", Direction: "egress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 8000, PortRangeMax: 8100, CIDR: "192.168.1.0/24"},
+	}
+	desired := &FirewallRuleSet{
+		InBound: []FirewallRuleCreateRequest{
+			{Type: "CUSTOM", Protocol: "tcp", CIDR: "10.1.2.3/8"},
+			{Type: "DNS", Protocol: "UDP", PortRange: "53", CIDR: "::/0"},
+			{Type: "DNS", Protocol: "UDP", PortRange: "53", CIDR: "::/0"},
+		},
+		OutBound: []FirewallRuleCreateRequest{
+			{Type: "CUSTOM", Protocol: "TCP", PortRange: "8000-8100", CIDR: "192.168.1.0/24"},
+		},
+	}
+	diff, err := diffFirewall(current, desired)
+	require.NoError(t, err)
+	assert.True(t, diff.Empty())
+
+	_, err = diffFirewall(nil, &FirewallRuleSet{InBound: []FirewallRuleCreateRequest{{Protocol: "tcp ", CIDR: "10.1.2.3/8"}}})
+	assert.True(t, errors.Is(err, ErrCommon))
+	_, err = diffFirewall(nil, &FirewallRuleSet{InBound: []FirewallRuleCreateRequest{{Protocol: "tcp", PortRange: "80-", CIDR: "0.0.0.0/0"}}})
+	assert.Error(t, err)
+	_, err = diffFirewall(nil, &FirewallRuleSet{InBound: []FirewallRuleCreateRequest{{Protocol: "tcp", CIDR: "10.0.0.0/33"}}})
+	assert.Error(t, err)
+}
+
+func TestFirewallSyncDryRun(t *testing.T) {
+	setup()
+	defer teardown()
+	mux.HandleFunc(testlib.CloudServerURL(firewallBasePath), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, firewallSyncListResponse)
+	})
+
+	diff, err := client.CloudServer.Firewalls().Sync(ctx, "web", firewallSyncDesired(), &FirewallSyncOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, "fw-web", diff.FirewallID)
+	assert.False(t, diff.Create)
+	require.Len(t, diff.AddInBound, 1)
+	assert.Equal(t, "HTTP", diff.AddInBound[0].Type)
+	assert.Empty(t, diff.AddOutBound)
+	require.Len(t, diff.Remove, 1)
+	assert.Equal(t, "r-rdp", diff.Remove[0].ID)
+}
+
+func TestFirewallSyncApply(t *testing.T) {
+	setup()
+	defer teardown()
+	var updated FirewallRequestPayload
+	var deleted []string
+	mux.HandleFunc(testlib.CloudServerURL(firewallBasePath), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, firewallSyncListResponse)
+	})
+	mux.HandleFunc(testlib.CloudServerURL(firewallBasePath+"/fw-web"), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPatch, r.Method)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&updated))
+		_, _ = fmt.Fprint(w, `{"id": "fw-web", "name": "web"}`)
+	})
+	mux.HandleFunc(testlib.CloudServerURL(firewallBasePath+"/r-rdp"), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodDelete, r.Method)
+		deleted = append(deleted, "r-rdp")
+		_, _ = fmt.Fprint(w, `{"message": "Deleted Firewall Rule"}`)
+	})
+
+	diff, err := client.CloudServer.Firewalls().Sync(ctx, "web", firewallSyncDesired(), nil)
+	require.NoError(t, err)
+	assert.False(t, diff.Empty())
+	require.Len(t, updated.InBound, 1)
+	assert.Equal(t, "80", updated.InBound[0].PortRange)
+	assert.Equal(t, []string{"r-rdp"}, deleted)
+}
+
+func TestFirewallSyncCreate(t *testing.T) {
+	setup()
+	defer teardown()
+	var created FirewallRequestPayload
+	mux.HandleFunc(testlib.CloudServerURL(firewallBasePath), func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = fmt.Fprint(w, `[]`)
+		case http.MethodPost:
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+			_, _ = fmt.Fprint(w, `{"id": "fw-new", "name": "web"}`)
+		}
+	})
+
+	diff, err := client.CloudServer.Firewalls().Sync(ctx, "web", firewallSyncDesired(), nil)
+	require.NoError(t, err)
+	assert.True(t, diff.Create)
+	assert.Equal(t, "fw-new", diff.FirewallID)
+	assert.Equal(t, "web", created.Name)
+	assert.Len(t, created.InBound, 3)
+	assert.Len(t, created.OutBound, 1)
+}
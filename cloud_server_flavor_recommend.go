@@ -0,0 +1,162 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FlavorRequirements describes the resources a workload needs. RAMGiB is in GiB, GPU is the number of GPUs,
+// GPUName optionally restricts the GPU model. Category and AZ restrict the flavor category and the availability
+// zone of its generation. Limit caps the number of recommendations, zero returns every fitting flavor.
+type FlavorRequirements struct {
+	VCPU     int
+	RAMGiB   int
+	GPU      int
+	GPUName  string
+	Category string
+	AZ       string
+	Limit    int
+}
+
+// FlavorRecommendation is a flavor fitting the requirements. Score is the relative amount of resources
+// exceeding the requirements, 0 is an exact fit.
+type FlavorRecommendation struct {
+	Flavor     *ServerFlavorResponse `json:"flavor"`
+	Score      float64               `json:"score"`
+	ExtraVCPU  int                   `json:"extra_vcpu"`
+	ExtraRAMMB int                   `json:"extra_ram_mb"`
+	GPU        *FlavorGPU            `json:"gpu,omitempty"`
+}
+
+// RecommendFlavor returns the flavors fitting the requirements, the closest fit first. Flavors are ranked
+// by the generation catalog of the requested availability zone and category, which lists the newest
+// generation first, so an equally close flavor of a newer generation comes first. When an availability zone
+// is requested, flavors whose generation is not offered there are left out.
+func (cs *cloudServerService) RecommendFlavor(ctx context.Context, requirements FlavorRequirements) ([]*FlavorRecommendation, error) {
+	if requirements.VCPU <= 0 || requirements.RAMGiB <= 0 || requirements.GPU < 0 {
+		return nil, fmt.Errorf("vcpu and ram are required: %w", ErrCommon)
+	}
+	flavors, err := cs.Flavors().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var filters []ListOption
+	if requirements.AZ != "" {
+		filters = append(filters, WithAZ(requirements.AZ))
+	}
+	if requirements.Category != "" {
+		filters = append(filters, WithCategory(requirements.Category))
+	}
+	generations, err := cs.FlavorGenerations().List(ctx, filters...)
+	if err != nil {
+		return nil, err
+	}
+	return recommendFlavors(flavors, generations, requirements), nil
+}
+
+func recommendFlavors(flavors []*ServerFlavorResponse, generations []FlavorGeneration, requirements FlavorRequirements) []*FlavorRecommendation {
+	ramMB := requirements.RAMGiB * 1024
+	recommendations := []*FlavorRecommendation{}
+	// Flavors of a generation missing from the catalog rank after every generation of the catalog.
+	ranks := make(map[*ServerFlavorResponse]int, len(flavors))
+	for _, flavor := range flavors {
+		if flavor.VCPUs < requirements.VCPU || flavor.RAM < ramMB || !flavorMatches(flavor, requirements) {
+			continue
+		}
+		rank, ok := generationRank(flavor, generations, requirements.AZ)
+		if !ok {
+			if requirements.AZ != "" {
+				continue
+			}
+			rank = len(generations)
+		}
+		ranks[flavor] = rank
+		recommendation := &FlavorRecommendation{
+			Flavor:     flavor,
+			ExtraVCPU:  flavor.VCPUs - requirements.VCPU,
+			ExtraRAMMB: flavor.RAM - ramMB,
+			GPU:        flavor.GPU,
+		}
+		recommendation.Score = float64(recommendation.ExtraVCPU)/float64(requirements.VCPU) +
+			float64(recommendation.ExtraRAMMB)/float64(ramMB)
+		if requirements.GPU > 0 {
+			recommendation.Score += float64(flavor.GPU.Count-requirements.GPU) / float64(requirements.GPU)
+		}
+		recommendations = append(recommendations, recommendation)
+	}
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		a, b := recommendations[i], recommendations[j]
+		if a.Score != b.Score {
+			return a.Score < b.Score
+		}
+		if ranks[a.Flavor] != ranks[b.Flavor] {
+			return ranks[a.Flavor] < ranks[b.Flavor]
+		}
+		return a.Flavor.Name < b.Flavor.Name
+	})
+	if requirements.Limit > 0 && len(recommendations) > requirements.Limit {
+		recommendations = recommendations[:requirements.Limit]
+	}
+	return recommendations
+}
+
+func flavorMatches(flavor *ServerFlavorResponse, requirements FlavorRequirements) bool {
+	if requirements.GPU == 0 {
+		// Do not spend GPUs on workloads which do not need them.
+		if flavor.GPU != nil && flavor.GPU.Count > 0 {
+			return false
+		}
+	} else {
+		if flavor.GPU == nil || flavor.GPU.Count < requirements.GPU {
+			return false
+		}
+		if requirements.GPUName != "" && !strings.EqualFold(flavor.GPU.Name, requirements.GPUName) {
+			return false
+		}
+	}
+	if requirements.Category != "" {
+		category := flavor.Category
+		if category == "" {
+			category = flavor.Generation.Category
+		}
+		if !strings.EqualFold(category, requirements.Category) {
+			return false
+		}
+	}
+	return true
+}
+
+// generationRank returns the position of the flavor's generation in the catalog. A generation listing its
+// availability zones must list az, the catalog is already filtered by az otherwise.
+func generationRank(flavor *ServerFlavorResponse, generations []FlavorGeneration, az string) (int, bool) {
+	for i, generation := range generations {
+		if !flavorOfGeneration(flavor, generation) {
+			continue
+		}
+		if az == "" || len(generation.AvailabilityZones) == 0 {
+			return i, true
+		}
+		for _, zone := range generation.AvailabilityZones {
+			if strings.EqualFold(zone, az) {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	return 0, false
+}
+
+func flavorOfGeneration(flavor *ServerFlavorResponse, generation FlavorGeneration) bool {
+	switch {
+	case flavor.GenerationID != "":
+		return flavor.GenerationID == generation.ID
+	case flavor.Generation.ID != "":
+		return flavor.Generation.ID == generation.ID
+	default:
+		return flavor.Generation.Code != "" && strings.EqualFold(flavor.Generation.Code, generation.Code)
+	}
+}
@@ -0,0 +1,110 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const flavorCatalogFixture = `[
+{"id": "f1", "name": "2c_4g", "vcpus": 2, "ram": 4096, "category": "premium", "generation_id": "g-gen1"},
+{"id": "f2", "name": "nix.2c_4g", "vcpus": 2, "ram": 4096, "category": "premium", "generation_id": "g-gen3"},
+{"id": "f3", "name": "4c_8g", "vcpus": 4, "ram": 8192, "category": "premium", "generation_id": "g-gen2"},
+{"id": "f4", "name": "2c_4g_basic", "vcpus": 2, "ram": 4096, "category": "basic", "generation": {"code": "gen2"}},
+{"id": "f5", "name": "8c_32g_1a30", "vcpus": 8, "ram": 32768, "category": "gpu", "generation_id": "g-gpu1",
+ "gpu": {"name": "A30", "count": 1}},
+{"id": "f6", "name": "16c_64g_2a30", "vcpus": 16, "ram": 65536, "category": "gpu", "generation_id": "g-gpu1",
+ "gpu": {"name": "A30", "count": 2}},
+{"id": "f7", "name": "8c_32g_1t4", "vcpus": 8, "ram": 32768, "category": "gpu", "generation_id": "g-gpu0",
+ "gpu": {"name": "T4", "count": 1}},
+{"id": "f8", "name": "legacy_2c_4g", "vcpus": 2, "ram": 4096, "category": "premium"}
+]`
+
+// flavorGenerationsFixture lists the newest generation first.
+const flavorGenerationsFixture = `{"data": [
+{"id": "g-gen3", "code": "gen3", "category": "premium", "availability_zones": ["HN1"]},
+{"id": "g-gen2", "code": "gen2", "category": "premium", "availability_zones": ["HN1", "HN2"]},
+{"id": "g-gpu1", "code": "gpu1", "category": "gpu", "availability_zones": ["HN1"]},
+{"id": "g-gen1", "code": "gen1", "category": "premium", "availability_zones": ["HN1", "HN2"]},
+{"id": "g-gpu0", "code": "gpu0", "category": "gpu", "availability_zones": ["HN2"]}
+]}`
+
+func flavorNames(recommendations []*FlavorRecommendation) []string {
+	names := []string{}
+	for _, recommendation := range recommendations {
+		names = append(names, recommendation.Flavor.Name)
+	}
+	return names
+}
+
+func TestRecommendFlavors(t *testing.T) {
+	var flavors []*ServerFlavorResponse
+	require.NoError(t, json.Unmarshal([]byte(flavorCatalogFixture), &flavors))
+	var generations flavorGenerationsResponse
+	require.NoError(t, json.Unmarshal([]byte(flavorGenerationsFixture), &generations))
+
+	recommendations := recommendFlavors(flavors, generations.Data, FlavorRequirements{VCPU: 2, RAMGiB: 4})
+	assert.Equal(t, []string{"nix.2c_4g", "2c_4g_basic", "2c_4g", "legacy_2c_4g", "4c_8g"}, flavorNames(recommendations))
+	assert.Zero(t, recommendations[0].Score)
+	assert.Equal(t, 2, recommendations[4].ExtraVCPU)
+	assert.Equal(t, 4096, recommendations[4].ExtraRAMMB)
+
+	recommendations = recommendFlavors(flavors, generations.Data, FlavorRequirements{VCPU: 2, RAMGiB: 4, Category: "premium", AZ: "HN2"})
+	assert.Equal(t, []string{"2c_4g", "4c_8g"}, flavorNames(recommendations))
+	recommendations = recommendFlavors(flavors, generations.Data, FlavorRequirements{VCPU: 2, RAMGiB: 4, Category: "premium", AZ: "HN2", Limit: 1})
+	assert.Equal(t, []string{"2c_4g"}, flavorNames(recommendations))
+
+	recommendations = recommendFlavors(flavors, generations.Data, FlavorRequirements{VCPU: 4, RAMGiB: 16, GPU: 1})
+	assert.Equal(t, []string{"8c_32g_1a30", "8c_32g_1t4", "16c_64g_2a30"}, flavorNames(recommendations))
+	assert.Equal(t, &FlavorGPU{Name: "A30", Count: 1}, recommendations[0].GPU)
+
+	recommendations = recommendFlavors(flavors, generations.Data, FlavorRequirements{VCPU: 4, RAMGiB: 16, GPU: 1, GPUName: "t4", AZ: "HN1"})
+	assert.Empty(t, recommendations)
+	recommendations = recommendFlavors(flavors, generations.Data, FlavorRequirements{VCPU: 32, RAMGiB: 4})
+	assert.Empty(t, recommendations)
+}
+
+// redirectTransport sends every request to the test server, flavor generations are not served by a catalog service.
+type redirectTransport struct{}
+
+func (redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	target, err := url.Parse(serverTest.URL)
+	if err != nil {
+		return nil, err
+	}
+	r.URL.Scheme, r.URL.Host = target.Scheme, target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestRecommendFlavor(t *testing.T) {
+	setup()
+	defer teardown()
+	client.httpClient = &http.Client{Transport: redirectTransport{}}
+	mux.HandleFunc(testlib.CloudServerURL(flavorPath), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, flavorCatalogFixture)
+	})
+	var query url.Values
+	mux.HandleFunc("/api"+flavorGenerationsResourcePath, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		query = r.URL.Query()
+		_, _ = fmt.Fprint(w, flavorGenerationsFixture)
+	})
+
+	recommendations, err := client.CloudServer.RecommendFlavor(ctx, FlavorRequirements{VCPU: 3, RAMGiB: 6, Category: "premium", AZ: "HN2"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4c_8g"}, flavorNames(recommendations))
+	assert.Equal(t, url.Values{"az": {"HN2"}, "category": {"premium"}}, query)
+
+	_, err = client.CloudServer.RecommendFlavor(ctx, FlavorRequirements{RAMGiB: 6})
+	assert.True(t, errors.Is(err, ErrCommon))
+}
@@ -55,16 +55,16 @@ func (s *cloudServerCustomOSImageResource) itemCustomImagePath(id string) string
 	return strings.Join([]string{customImagePath, id}, "/")
 }
 
-// osDistributionVersion represents the os distribution version
-type osDistributionVersion struct {
+// OSDistributionVersion represents a version of an OS distribution, ID is the image ID used in ServerOS.
+type OSDistributionVersion struct {
 	Name string `json:"name"`
 	ID   string `json:"id"`
 }
 
-// osImageResponse represents the response body when getting os image
-type osImageResponse struct {
+// OSImage represents an OS distribution and its available versions.
+type OSImage struct {
 	OSDistribution string                  `json:"os"`
-	Version        []osDistributionVersion `json:"versions"`
+	Version        []OSDistributionVersion `json:"versions"`
 }
 
 type cloudServerOSImageResource struct {
@@ -84,7 +84,7 @@ func (cs *cloudServerService) CustomImages() *cloudServerCustomOSImageResource {
 }
 
 // Get list server os images
-func (s *cloudServerOSImageResource) List(ctx context.Context) ([]osImageResponse, error) {
+func (s *cloudServerOSImageResource) List(ctx context.Context) ([]OSImage, error) {
 	req, err := s.client.NewRequest(ctx, http.MethodGet, serverServiceName, osImagePath, nil)
 
 	if err != nil {
@@ -99,7 +99,7 @@ func (s *cloudServerOSImageResource) List(ctx context.Context) ([]osImageRespons
 		return nil, err
 	}
 	var respPayload struct {
-		OSImages []osImageResponse `json:"os_images"`
+		OSImages []OSImage `json:"os_images"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&respPayload); err != nil {
 		return nil, err
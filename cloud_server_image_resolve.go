@@ -0,0 +1,151 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var versionNumberRegexp = regexp.MustCompile(`\d+(\.\d+)*`)
+
+// ResolvedOSImage is the image an OS distribution and version resolved to.
+// Custom is set when the image is a custom image matched by name.
+type ResolvedOSImage struct {
+	ID           string `json:"id"`
+	Distribution string `json:"distribution"`
+	Version      string `json:"version"`
+	Custom       bool   `json:"custom"`
+}
+
+// ServerOS returns the OS of a server created from the image.
+func (r *ResolvedOSImage) ServerOS() *ServerOS {
+	return &ServerOS{ID: r.ID, Type: "image"}
+}
+
+// Resolve finds the image of an OS distribution version, e.g. Resolve(ctx, "ubuntu", "22.04").
+// The version matches on its leading numbers, so "22" or "22.x" match any 22 release, and the highest
+// matching version wins. An empty version or "latest" selects the highest version, "latest 22.x" the highest
+// 22 release. When no OS image matches, a custom image named distribution, "distribution-version" or
+// "distribution version" is looked up instead.
+func (s *cloudServerOSImageResource) Resolve(ctx context.Context, distribution string, version string) (*ResolvedOSImage, error) {
+	osImages, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, ok := resolveOSImage(osImages, distribution, version); ok {
+		return resolved, nil
+	}
+
+	customImages, err := (&cloudServerCustomOSImageResource{client: s.client}).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{distribution}
+	if version != "" {
+		names = append(names, distribution+"-"+version, distribution+" "+version)
+	}
+	var found *CustomImage
+	for _, image := range customImages {
+		if image.Status != imageActiveStatus {
+			continue
+		}
+		for _, name := range names {
+			// Several images may share a name, prefer the most recent one.
+			if strings.EqualFold(image.Name, name) && (found == nil || image.CreatedAt > found.CreatedAt) {
+				found = image
+			}
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no image found for %s %s: %w", distribution, version, ErrNotFound)
+	}
+	return &ResolvedOSImage{ID: found.ID, Distribution: distribution, Version: found.Name, Custom: true}, nil
+}
+
+func resolveOSImage(osImages []OSImage, distribution string, version string) (*ResolvedOSImage, bool) {
+	wanted, ok := parseVersionSpec(version)
+	if !ok {
+		return nil, false
+	}
+	var best *ResolvedOSImage
+	var bestNumbers []int
+	for _, osImage := range osImages {
+		if !strings.EqualFold(osImage.OSDistribution, distribution) {
+			continue
+		}
+		for _, v := range osImage.Version {
+			numbers := parseVersionNumbers(v.Name)
+			if !versionHasPrefix(numbers, wanted) {
+				continue
+			}
+			if best == nil || compareVersions(numbers, bestNumbers) > 0 {
+				best = &ResolvedOSImage{ID: v.ID, Distribution: osImage.OSDistribution, Version: v.Name}
+				bestNumbers = numbers
+			}
+		}
+	}
+	return best, best != nil
+}
+
+// parseVersionSpec returns the leading version numbers a version must have, an empty prefix matches any version.
+func parseVersionSpec(spec string) ([]int, bool) {
+	spec = strings.TrimSpace(strings.ToLower(spec))
+	spec = strings.TrimSpace(strings.TrimPrefix(spec, "latest"))
+	var numbers []int
+	for _, part := range strings.Split(spec, ".") {
+		if part == "" && len(numbers) == 0 {
+			continue
+		}
+		if part == "x" || part == "*" {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, true
+}
+
+// parseVersionNumbers extracts the first version number of an image name, e.g. "22.04 x64" gives [22 4].
+func parseVersionNumbers(name string) []int {
+	match := versionNumberRegexp.FindString(name)
+	if match == "" {
+		return nil
+	}
+	var numbers []int
+	for _, part := range strings.Split(match, ".") {
+		n, _ := strconv.Atoi(part)
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+func versionHasPrefix(numbers []int, prefix []int) bool {
+	if len(prefix) > len(numbers) {
+		return false
+	}
+	for i, n := range prefix {
+		if numbers[i] != n {
+			return false
+		}
+	}
+	return true
+}
+
+func compareVersions(a []int, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
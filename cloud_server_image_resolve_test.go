@@ -0,0 +1,72 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOSImageResolve(t *testing.T) {
+	setup()
+	defer teardown()
+	mux.HandleFunc(testlib.CloudServerURL(osImagePath), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, `{"os_images": [
+{"os": "Ubuntu", "versions": [
+	{"name": "20.04 x64", "id": "ubuntu-2004"},
+	{"name": "22.04 x64", "id": "ubuntu-2204"},
+	{"name": "22.10 x64", "id": "ubuntu-2210"},
+	{"name": "24.04 x64", "id": "ubuntu-2404"}
+]},
+{"os": "CentOS", "versions": [{"name": "7.9 x64", "id": "centos-79"}, {"name": "7.10 x64", "id": "centos-710"}]}
+]}`)
+	})
+	mux.HandleFunc(testlib.CloudServerURL(customImagesPath), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, `{"images": [
+{"id": "web-old", "name": "web-base-1.2", "status": "active", "created_at": "2024-01-01T00:00:00Z"},
+{"id": "web-new", "name": "web-base-1.2", "status": "active", "created_at": "2024-02-01T00:00:00Z"},
+{"id": "web-queued", "name": "web-base-1.2", "status": "queued", "created_at": "2024-03-01T00:00:00Z"}
+]}`)
+	})
+
+	for _, c := range []struct {
+		distribution string
+		version      string
+		id           string
+	}{
+		{"ubuntu", "22.04", "ubuntu-2204"},
+		{"Ubuntu", "latest 22.x", "ubuntu-2210"},
+		{"ubuntu", "22", "ubuntu-2210"},
+		{"ubuntu", "", "ubuntu-2404"},
+		{"ubuntu", "latest", "ubuntu-2404"},
+		{"centos", "7.x", "centos-710"},
+		{"web-base", "1.2", "web-new"},
+	} {
+		resolved, err := client.CloudServer.OSImages().Resolve(ctx, c.distribution, c.version)
+		require.NoError(t, err, c.distribution+" "+c.version)
+		assert.Equal(t, c.id, resolved.ID, c.distribution+" "+c.version)
+	}
+
+	resolved, err := client.CloudServer.OSImages().Resolve(ctx, "ubuntu", "22.04")
+	require.NoError(t, err)
+	assert.Equal(t, "22.04 x64", resolved.Version)
+	assert.False(t, resolved.Custom)
+	assert.Equal(t, &ServerOS{ID: "ubuntu-2204", Type: "image"}, resolved.ServerOS())
+
+	resolved, err = client.CloudServer.OSImages().Resolve(ctx, "web-base-1.2", "")
+	require.NoError(t, err)
+	assert.True(t, resolved.Custom)
+
+	_, err = client.CloudServer.OSImages().Resolve(ctx, "ubuntu", "18.04")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	_, err = client.CloudServer.OSImages().Resolve(ctx, "debian", "")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
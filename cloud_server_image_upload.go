@@ -0,0 +1,230 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	imageQueuedStatus = "queued"
+	imageActiveStatus = "active"
+)
+
+// CustomImageUploadOptions represents options when uploading a local disk image.
+// Name defaults to the file name without extension and DiskFormat is detected from the file when empty.
+// Progress is called as the file is sent with the number of bytes sent and the file size.
+// ImageID resumes a failed upload into an existing queued image instead of creating a new one, the image
+// service does not accept partial uploads so the whole file is sent again.
+type CustomImageUploadOptions struct {
+	Name        string
+	Description string
+	DiskFormat  string
+	ImageID     string
+	Progress    func(sent int64, total int64)
+}
+
+// Upload creates a custom image from a local qcow2, raw, vmdk, vhd or iso file. The file is streamed to the
+// image service, then Upload waits until the image is active and verifies its checksum against the file.
+func (s *cloudServerCustomOSImageResource) Upload(ctx context.Context, path string, opts *CustomImageUploadOptions) (*CustomImage, error) {
+	if opts == nil {
+		opts = &CustomImageUploadOptions{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory: %w", path, ErrCommon)
+	}
+	diskFormat := opts.DiskFormat
+	if diskFormat == "" {
+		if diskFormat, err = detectDiskFormat(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var imageID, uploadURI, token string
+	if opts.ImageID != "" {
+		resp, err := s.Get(ctx, opts.ImageID)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Image.Status != imageQueuedStatus {
+			return nil, fmt.Errorf("image %s is %s, only queued images can be uploaded again: %w",
+				opts.ImageID, resp.Image.Status, ErrCommon)
+		}
+		imageID, uploadURI, token = resp.Image.ID, resp.Image.File, resp.Token
+	} else {
+		name := opts.Name
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		resp, err := s.Create(ctx, &CreateCustomImagePayload{Name: name, DiskFormat: diskFormat, Description: opts.Description})
+		if err != nil {
+			return nil, err
+		}
+		imageID, uploadURI, token = resp.Image.ID, resp.UploadURI, resp.Token
+	}
+	if uploadURI == "" {
+		return nil, fmt.Errorf("image %s has no upload uri: %w", imageID, ErrCommon)
+	}
+	if uploadURI, err = s.imageFileURL(uploadURI); err != nil {
+		return nil, err
+	}
+
+	var md5Sum, sha512Sum hash.Hash
+	// The image only accepts data while it is queued, an upload that failed after the service took the
+	// data must not be sent again.
+	err = s.client.retryAction(ctx, func() error {
+		md5Sum, sha512Sum = md5.New(), sha512.New()
+		return s.uploadFile(ctx, path, info.Size(), uploadURI, token, io.MultiWriter(md5Sum, sha512Sum), opts.Progress)
+	}, func() (bool, error) {
+		resp, err := s.Get(ctx, imageID)
+		return err == nil && resp.Image.Status != imageQueuedStatus, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("upload image %s: %w", imageID, err)
+	}
+
+	var image *CustomImage
+	err = s.client.waitFor(ctx, func() (bool, error) {
+		resp, err := s.Get(ctx, imageID)
+		if err != nil {
+			return false, err
+		}
+		image = &resp.Image
+		switch image.Status {
+		case imageActiveStatus:
+			return true, nil
+		case "killed", "deleted", "deactivated":
+			return false, fmt.Errorf("image %s is %s: %w", imageID, image.Status, ErrCommon)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if image.Checksum != "" && image.Checksum != hex.EncodeToString(md5Sum.Sum(nil)) {
+		return image, fmt.Errorf("image %s checksum %s does not match the local file: %w", imageID, image.Checksum, ErrCommon)
+	}
+	if image.OSHashAlgo == "sha512" && image.OSHashValue != hex.EncodeToString(sha512Sum.Sum(nil)) {
+		return image, fmt.Errorf("image %s sha512 does not match the local file: %w", imageID, ErrCommon)
+	}
+	return image, nil
+}
+
+// imageFileURL resolves the file of an image, which the image service may return as a path such as
+// /v2/images/<id>/file, against the cloud server endpoint.
+func (s *cloudServerCustomOSImageResource) imageFileURL(file string) (string, error) {
+	ref, err := url.Parse(file)
+	if err != nil {
+		return "", fmt.Errorf("invalid image file %q: %w", file, ErrCommon)
+	}
+	if ref.IsAbs() {
+		return file, nil
+	}
+	base, err := url.Parse(s.client.GetServiceURL(serverServiceName))
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// uploadFile streams the file to the image service, which authenticates uploads with the token
+// returned alongside the upload uri rather than with the client credentials.
+func (s *cloudServerCustomOSImageResource) uploadFile(ctx context.Context, path string, size int64, uploadURI string,
+	token string, digest io.Writer, progress func(int64, int64)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	body := &progressReader{reader: io.TeeReader(f, digest), total: size, progress: progress}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURI, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("User-Agent", s.client.userAgent)
+	req.Header.Set("X-Auth-Token", token)
+	resp, err := s.client.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode >= http.StatusBadRequest {
+		buf, _ := io.ReadAll(resp.Body)
+		return errorFromStatus(resp.StatusCode, string(buf))
+	}
+	return nil
+}
+
+type progressReader struct {
+	reader   io.Reader
+	sent     int64
+	total    int64
+	progress func(int64, int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.sent += int64(n)
+		if r.progress != nil {
+			r.progress(r.sent, r.total)
+		}
+	}
+	return n, err
+}
+
+// detectDiskFormat recognizes qcow2 and vmdk images by their magic number, other formats by file extension.
+func detectDiskFormat(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err == nil {
+		switch {
+		case bytes.Equal(magic, []byte{'Q', 'F', 'I', 0xfb}):
+			return "qcow2", nil
+		case bytes.Equal(magic, []byte("KDMV")):
+			return "vmdk", nil
+		}
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".qcow2":
+		return "qcow2", nil
+	case ".vmdk":
+		return "vmdk", nil
+	case ".vhd":
+		return "vhd", nil
+	case ".iso":
+		return "iso", nil
+	case ".raw", ".img":
+		return "raw", nil
+	}
+	return "", fmt.Errorf("unknown disk format of %s: %w", path, ErrCommon)
+}
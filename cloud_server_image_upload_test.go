@@ -0,0 +1,155 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"crypto/md5"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeImageService accepts uploads into queued images, the first upload attempt fails with 503.
+// With committed set, that attempt takes the data before failing with 502.
+type fakeImageService struct {
+	mu        sync.Mutex
+	images    map[string]*CustomImage
+	uploads   int
+	corrupt   bool
+	committed bool
+	created   *CreateCustomImagePayload
+	lastToken string
+}
+
+func (f *fakeImageService) register(t *testing.T) {
+	mux.HandleFunc(testlib.CloudServerURL(customImagePath+"/upload"), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&f.created))
+		image := &CustomImage{ID: "img-new", Name: f.created.Name, DiskFormat: f.created.DiskFormat, Status: imageQueuedStatus,
+			File: "/v2/images/img-new/file"}
+		f.images[image.ID] = image
+		_ = json.NewEncoder(w).Encode(CreateCustomImageResp{Image: *image, Success: true, Token: "upload-token",
+			UploadURI: serverTest.URL + image.File})
+	})
+	mux.HandleFunc(testlib.CloudServerURL(customImagePath+"/"), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		image, ok := f.images[strings.TrimPrefix(r.URL.Path, testlib.CloudServerURL(customImagePath+"/"))]
+		require.True(t, ok)
+		_ = json.NewEncoder(w).Encode(CustomImageGetResp{Image: *image, Token: "resume-token"})
+	})
+	mux.HandleFunc("/v2/images/", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		require.Equal(t, "application/octet-stream", r.Header.Get("Content-Type"))
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.uploads++
+		f.lastToken = r.Header.Get("X-Auth-Token")
+		if f.uploads == 1 && !f.committed {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		image := f.images[strings.Split(strings.TrimPrefix(r.URL.Path, "/v2/images/"), "/")[0]]
+		if image.Status != imageQueuedStatus {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		md5Sum := md5.Sum(data)
+		sha512Sum := sha512.Sum512(data)
+		if f.corrupt {
+			md5Sum[0]++
+		}
+		image.Status, image.Size = imageActiveStatus, len(data)
+		image.Checksum, image.OSHashAlgo, image.OSHashValue = hex.EncodeToString(md5Sum[:]), "sha512", hex.EncodeToString(sha512Sum[:])
+		if f.uploads == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func TestCustomImageUpload(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+	f := &fakeImageService{images: map[string]*CustomImage{}}
+	f.register(t)
+
+	path := filepath.Join(t.TempDir(), "packer-ubuntu.img")
+	data := append([]byte{'Q', 'F', 'I', 0xfb}, []byte(strings.Repeat("disk", 4096))...)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	var sent, total int64
+	image, err := client.CloudServer.CustomImages().Upload(ctx, path, &CustomImageUploadOptions{
+		Progress: func(s int64, t int64) { sent, total = s, t },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "img-new", image.ID)
+	assert.Equal(t, imageActiveStatus, image.Status)
+	assert.Equal(t, len(data), image.Size)
+	assert.Equal(t, "packer-ubuntu", f.created.Name)
+	assert.Equal(t, "qcow2", f.created.DiskFormat)
+	assert.Equal(t, "upload-token", f.lastToken)
+	assert.Equal(t, 2, f.uploads)
+	assert.Equal(t, int64(len(data)), sent)
+	assert.Equal(t, int64(len(data)), total)
+
+	_, err = client.CloudServer.CustomImages().Upload(ctx, path, &CustomImageUploadOptions{ImageID: "img-new"})
+	assert.True(t, errors.Is(err, ErrCommon))
+
+	f.images["img-queued"] = &CustomImage{ID: "img-queued", Status: imageQueuedStatus, File: "/v2/images/img-queued/file"}
+	f.corrupt = true
+	_, err = client.CloudServer.CustomImages().Upload(ctx, path, &CustomImageUploadOptions{ImageID: "img-queued"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum")
+	assert.Equal(t, "resume-token", f.lastToken)
+}
+
+func TestCustomImageUploadNotResentOnceTaken(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+	f := &fakeImageService{images: map[string]*CustomImage{}, committed: true}
+	f.register(t)
+
+	path := filepath.Join(t.TempDir(), "disk.raw")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("disk", 4096)), 0o600))
+
+	image, err := client.CloudServer.CustomImages().Upload(ctx, path, &CustomImageUploadOptions{DiskFormat: "raw"})
+	require.NoError(t, err)
+	assert.Equal(t, imageActiveStatus, image.Status)
+	assert.Equal(t, 1, f.uploads)
+}
+
+func TestDetectDiskFormat(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.bin": "KDMV....", "b.vhd": "conectix", "c.img": "", "d.txt": "text"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	for name, expected := range map[string]string{"a.bin": "vmdk", "b.vhd": "vhd", "c.img": "raw"} {
+		format, err := detectDiskFormat(filepath.Join(dir, name))
+		require.NoError(t, err, name)
+		assert.Equal(t, expected, format, name)
+	}
+	_, err := detectDiskFormat(filepath.Join(dir, "d.txt"))
+	assert.True(t, errors.Is(err, ErrCommon))
+}
@@ -28,6 +28,7 @@ type NetworkInterfaceService interface {
 	Get(ctx context.Context, networkInterfaceID string) (*NetworkInterface, error)
 	Action(ctx context.Context, networkInterfaceID string, payload *ActionNetworkInterfacePayload) (*NetworkInterface, error)
 	List(ctx context.Context, opts *ListNetworkInterfaceOptions) ([]*NetworkInterface, error)
+	Failover(ctx context.Context, interfaceID string, toServerID string) (*FailoverResult, error)
 }
 
 // ListNetworkInterfaceOptions represents the options for listing network interfaces.
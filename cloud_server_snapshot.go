@@ -36,6 +36,8 @@ type SnapshotService interface {
 	Create(ctx context.Context, scr *SnapshotCreateRequest) (*Snapshot, error)
 	Get(ctx context.Context, id string) (*Snapshot, error)
 	Delete(ctx context.Context, id string) error
+	PlanRetention(ctx context.Context, policy SnapshotRetentionPolicy, opts *SnapshotRetentionOptions) (*SnapshotRetentionPlan, error)
+	ApplyRetention(ctx context.Context, plan *SnapshotRetentionPlan) (*SnapshotRetentionResult, error)
 }
 
 // SnapshotCreateRequest represents create new volume request payload.
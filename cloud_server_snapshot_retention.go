@@ -0,0 +1,261 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	snapshotAvailableStatus = "available"
+)
+
+// snapshotTimeLayouts are the timestamp formats returned by the Cloud Server API.
+var snapshotTimeLayouts = []string{
+	"2006-01-02T15:04:05.000000",
+	"2006-01-02T15:04:05",
+	time.RFC3339Nano,
+	time.RFC3339,
+}
+
+// SnapshotRetentionPolicy represents how many snapshots are kept per volume.
+// Daily keeps the newest snapshot of each of the last Daily days that have one,
+// Weekly and Monthly do the same per ISO week and per calendar month.
+// A snapshot kept by any rule is not pruned.
+type SnapshotRetentionPolicy struct {
+	Daily   int `json:"daily"`
+	Weekly  int `json:"weekly"`
+	Monthly int `json:"monthly"`
+}
+
+// SnapshotRetentionOptions represents options when planning snapshot retention.
+// VolumeIDs restricts the plan to the given volumes, volumes without any snapshot are planned for a fresh one.
+// Without VolumeIDs, fresh snapshots are only planned for volumes which still exist.
+// Now returns the reference time of the plan, it defaults to time.Now.
+type SnapshotRetentionOptions struct {
+	VolumeIDs []string
+	Now       func() time.Time
+}
+
+// SnapshotRetentionPlan contains the result of evaluating a retention policy.
+type SnapshotRetentionPlan struct {
+	GeneratedAt time.Time   `json:"generated_at"`
+	Keep        []*Snapshot `json:"keep"`
+	Prune       []*Snapshot `json:"prune"`
+	Create      []string    `json:"create"`
+}
+
+// SnapshotRetentionResult contains the result of applying a retention plan.
+type SnapshotRetentionResult struct {
+	Created []*Snapshot `json:"created"`
+	Pruned  []string    `json:"pruned"`
+}
+
+func (p SnapshotRetentionPolicy) validate() error {
+	if p.Daily < 0 || p.Weekly < 0 || p.Monthly < 0 {
+		return fmt.Errorf("retention counts must not be negative: %w", ErrCommon)
+	}
+	if p.Daily == 0 && p.Weekly == 0 && p.Monthly == 0 {
+		return fmt.Errorf("retention policy keeps no snapshot: %w", ErrCommon)
+	}
+	return nil
+}
+
+// interval returns the age after which a volume needs a fresh snapshot.
+func (p SnapshotRetentionPolicy) interval() time.Duration {
+	switch {
+	case p.Daily > 0:
+		return 24 * time.Hour
+	case p.Weekly > 0:
+		return 7 * 24 * time.Hour
+	default:
+		return 30 * 24 * time.Hour
+	}
+}
+
+func parseSnapshotTime(value string) (time.Time, bool) {
+	for _, layout := range snapshotTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// PlanRetention computes which snapshots to prune and which volumes need a fresh snapshot without changing anything.
+func (s *cloudServerSnapshotResource) PlanRetention(ctx context.Context, policy SnapshotRetentionPolicy, opts *SnapshotRetentionOptions) (*SnapshotRetentionPlan, error) {
+	if opts == nil {
+		opts = &SnapshotRetentionOptions{}
+	}
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	snapshots, err := s.List(ctx, &ListSnasphotsOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var existing map[string]bool
+	if len(opts.VolumeIDs) == 0 {
+		volumes, err := (&cloudServerVolumeResource{client: s.client}).List(ctx, &VolumeListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		existing = make(map[string]bool, len(volumes))
+		for _, volume := range volumes {
+			existing[volume.ID] = true
+		}
+	}
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+	return planSnapshotRetention(snapshots, policy, opts.VolumeIDs, existing, now()), nil
+}
+
+// ApplyRetention executes a plan computed by PlanRetention, fresh snapshots are created before pruning.
+// A failed create or delete does not stop the others, the failures are returned together.
+func (s *cloudServerSnapshotResource) ApplyRetention(ctx context.Context, plan *SnapshotRetentionPlan) (*SnapshotRetentionResult, error) {
+	if plan == nil {
+		return nil, fmt.Errorf("retention plan is required: %w", ErrCommon)
+	}
+	result := &SnapshotRetentionResult{}
+	var errs []error
+	for _, volumeID := range plan.Create {
+		snapshot, err := s.Create(ctx, &SnapshotCreateRequest{
+			Name:     fmt.Sprintf("retention-%s-%s", volumeID, plan.GeneratedAt.UTC().Format("20060102150405")),
+			VolumeID: volumeID,
+			Force:    true,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("create snapshot of volume %s: %w", volumeID, err))
+			continue
+		}
+		result.Created = append(result.Created, snapshot)
+	}
+	for _, snapshot := range plan.Prune {
+		if err := s.Delete(ctx, snapshot.ID); err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("delete snapshot %s: %w", snapshot.ID, err))
+			continue
+		}
+		result.Pruned = append(result.Pruned, snapshot.ID)
+	}
+	return result, errors.Join(errs...)
+}
+
+// planSnapshotRetention plans fresh snapshots for the given volumes or, when there are none, for the
+// existing volumes. Snapshots without a volume ID are only subject to pruning.
+func planSnapshotRetention(snapshots []*Snapshot, policy SnapshotRetentionPolicy, volumeIDs []string, existing map[string]bool,
+	now time.Time) *SnapshotRetentionPlan {
+	plan := &SnapshotRetentionPlan{GeneratedAt: now}
+
+	wanted := make(map[string]bool, len(volumeIDs))
+	for _, id := range volumeIDs {
+		wanted[id] = true
+	}
+	byVolume := make(map[string][]*Snapshot)
+	for _, snapshot := range snapshots {
+		if len(wanted) > 0 && !wanted[snapshot.VolumeID] {
+			continue
+		}
+		byVolume[snapshot.VolumeID] = append(byVolume[snapshot.VolumeID], snapshot)
+	}
+	for _, id := range volumeIDs {
+		if _, ok := byVolume[id]; !ok {
+			byVolume[id] = nil
+		}
+	}
+
+	volumes := make([]string, 0, len(byVolume))
+	for id := range byVolume {
+		volumes = append(volumes, id)
+	}
+	sort.Strings(volumes)
+
+	for _, volumeID := range volumes {
+		keep, prune, newest := retainVolumeSnapshots(byVolume[volumeID], policy)
+		plan.Keep = append(plan.Keep, keep...)
+		plan.Prune = append(plan.Prune, prune...)
+		if volumeID == "" || (len(wanted) == 0 && !existing[volumeID]) {
+			continue
+		}
+		if newest.IsZero() || now.Sub(newest) >= policy.interval() {
+			plan.Create = append(plan.Create, volumeID)
+		}
+	}
+	return plan
+}
+
+// retainVolumeSnapshots applies the policy to the snapshots of a single volume.
+// Snapshots which are not available or have no parsable creation time are always kept.
+func retainVolumeSnapshots(snapshots []*Snapshot, policy SnapshotRetentionPolicy) ([]*Snapshot, []*Snapshot, time.Time) {
+	type dated struct {
+		snapshot  *Snapshot
+		createdAt time.Time
+	}
+	var keep, prune []*Snapshot
+	var candidates []dated
+	var newest time.Time
+	for _, snapshot := range snapshots {
+		createdAt, ok := parseSnapshotTime(snapshot.CreateAt)
+		available := snapshot.Status == snapshotAvailableStatus
+		// Only usable snapshots count as fresh, a failed snapshot must not delay the next one.
+		if ok && available && createdAt.After(newest) {
+			newest = createdAt
+		}
+		if !ok || !available {
+			keep = append(keep, snapshot)
+			continue
+		}
+		candidates = append(candidates, dated{snapshot: snapshot, createdAt: createdAt})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].createdAt.After(candidates[j].createdAt)
+	})
+
+	rules := []struct {
+		count  int
+		bucket func(time.Time) string
+	}{
+		{policy.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{policy.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{policy.Monthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	kept := make([]bool, len(candidates))
+	for _, rule := range rules {
+		last := ""
+		remaining := rule.count
+		for i, c := range candidates {
+			if remaining == 0 {
+				break
+			}
+			bucket := rule.bucket(c.createdAt)
+			if bucket == last {
+				continue
+			}
+			last = bucket
+			kept[i] = true
+			remaining--
+		}
+	}
+	for i, c := range candidates {
+		if kept[i] {
+			keep = append(keep, c.snapshot)
+		} else {
+			prune = append(prune, c.snapshot)
+		}
+	}
+	return keep, prune, newest
+}
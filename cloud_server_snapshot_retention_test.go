@@ -0,0 +1,182 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func retentionSnapshotIDs(snapshots []*Snapshot) []string {
+	ids := make([]string, 0, len(snapshots))
+	for _, s := range snapshots {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func TestPlanSnapshotRetention(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	var snapshots []*Snapshot
+	// One snapshot per day for 60 days on vol-1.
+	for i := 0; i < 60; i++ {
+		snapshots = append(snapshots, &Snapshot{
+			ID:       fmt.Sprintf("s-%02d", i),
+			VolumeID: "vol-1",
+			Status:   "available",
+			CreateAt: now.Add(-time.Duration(i)*24*time.Hour - time.Hour).Format("2006-01-02T15:04:05.000000"),
+		})
+	}
+	// vol-2 has only an old snapshot and one still being created.
+	snapshots = append(snapshots,
+		&Snapshot{ID: "old", VolumeID: "vol-2", Status: "available", CreateAt: "2024-03-01T00:00:00.000000"},
+		&Snapshot{ID: "creating", VolumeID: "vol-2", Status: "creating", CreateAt: "2024-02-01T00:00:00.000000"},
+	)
+	// Old snapshots of a deleted volume and of an unknown one get no fresh snapshot.
+	snapshots = append(snapshots,
+		&Snapshot{ID: "gone", VolumeID: "vol-gone", Status: "available", CreateAt: "2024-03-01T00:00:00.000000"},
+		&Snapshot{ID: "unknown", Status: "available", CreateAt: "2024-03-01T00:00:00.000000"},
+	)
+
+	plan := planSnapshotRetention(snapshots, SnapshotRetentionPolicy{Daily: 7, Weekly: 4, Monthly: 3}, nil,
+		map[string]bool{"vol-1": true, "vol-2": true}, now)
+
+	keep := retentionSnapshotIDs(plan.Keep)
+	// 7 daily snapshots.
+	for i := 0; i < 7; i++ {
+		assert.Contains(t, keep, fmt.Sprintf("s-%02d", i))
+	}
+	// Newest snapshot of the previous weeks, Sunday 2024-03-03 and 2024-02-25.
+	assert.Contains(t, keep, "s-12")
+	assert.Contains(t, keep, "s-19")
+	// Newest snapshot of February and January for the monthly rule.
+	assert.Contains(t, keep, "s-15") // 2024-02-29
+	assert.Contains(t, keep, "s-44") // 2024-01-31
+	assert.Len(t, keep, 15)
+	assert.Contains(t, keep, "old")
+	assert.Contains(t, keep, "creating")
+	assert.NotContains(t, retentionSnapshotIDs(plan.Prune), "creating")
+	assert.Equal(t, len(snapshots), len(plan.Keep)+len(plan.Prune))
+	assert.Equal(t, []string{"vol-2"}, plan.Create)
+	assert.Equal(t, now, plan.GeneratedAt)
+}
+
+func TestPlanSnapshotRetentionVolumeFilter(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	snapshots := []*Snapshot{
+		{ID: "a", VolumeID: "vol-1", Status: "available", CreateAt: "2024-03-15T10:00:00.000000"},
+		{ID: "b", VolumeID: "vol-2", Status: "available", CreateAt: "2024-03-15T10:00:00.000000"},
+	}
+	plan := planSnapshotRetention(snapshots, SnapshotRetentionPolicy{Daily: 1}, []string{"vol-1", "vol-3"}, nil, now)
+	assert.Equal(t, []string{"a"}, retentionSnapshotIDs(plan.Keep))
+	assert.Empty(t, plan.Prune)
+	assert.Equal(t, []string{"vol-3"}, plan.Create)
+}
+
+func TestPlanSnapshotRetentionIgnoresFailedSnapshots(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	snapshots := []*Snapshot{
+		{ID: "old", VolumeID: "vol-1", Status: "available", CreateAt: "2024-03-10T10:00:00.000000"},
+		{ID: "failed", VolumeID: "vol-1", Status: "error", CreateAt: "2024-03-15T10:00:00.000000"},
+	}
+	plan := planSnapshotRetention(snapshots, SnapshotRetentionPolicy{Daily: 1}, nil, map[string]bool{"vol-1": true}, now)
+	assert.Equal(t, []string{"vol-1"}, plan.Create)
+	assert.ElementsMatch(t, []string{"old", "failed"}, retentionSnapshotIDs(plan.Keep))
+}
+
+func TestSnapshotApplyRetentionNilPlan(t *testing.T) {
+	setup()
+	defer teardown()
+	_, err := client.CloudServer.Snapshots().ApplyRetention(ctx, nil)
+	assert.True(t, errors.Is(err, ErrCommon))
+}
+
+func TestSnapshotPlanAndApplyRetention(t *testing.T) {
+	setup()
+	defer teardown()
+
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	var deleted []string
+	var created []string
+	mux.HandleFunc(testlib.CloudServerURL(snapshotPath), func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = fmt.Fprint(w, `[
+{"id": "new", "volume_id": "vol-1", "status": "available", "created_at": "2024-03-14T10:00:00.000000"},
+{"id": "older", "volume_id": "vol-1", "status": "available", "created_at": "2024-03-14T08:00:00.000000"}
+]`)
+		case http.MethodPost:
+			var payload SnapshotCreateRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			created = append(created, payload.VolumeID)
+			_, _ = fmt.Fprintf(w, `{"id": "fresh", "volume_id": "%s", "status": "creating"}`, payload.VolumeID)
+		default:
+			t.Fatalf("unexpected method %s", r.Method)
+		}
+	})
+	mux.HandleFunc(testlib.CloudServerURL(snapshotPath+"/older"), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodDelete, r.Method)
+		deleted = append(deleted, "older")
+	})
+	mux.HandleFunc(testlib.CloudServerURL(volumeBasePath), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, `[{"id": "vol-1", "status": "in-use"}]`)
+	})
+
+	plan, err := client.CloudServer.Snapshots().PlanRetention(ctx, SnapshotRetentionPolicy{Daily: 3}, &SnapshotRetentionOptions{
+		Now: func() time.Time { return now },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new"}, retentionSnapshotIDs(plan.Keep))
+	assert.Equal(t, []string{"older"}, retentionSnapshotIDs(plan.Prune))
+	assert.Equal(t, []string{"vol-1"}, plan.Create)
+	assert.Empty(t, deleted)
+
+	result, err := client.CloudServer.Snapshots().ApplyRetention(ctx, plan)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"older"}, result.Pruned)
+	assert.Equal(t, "fresh", result.Created[0].ID)
+	assert.Equal(t, []string{"vol-1"}, created)
+	assert.Equal(t, []string{"older"}, deleted)
+}
+
+func TestSnapshotApplyRetentionPrunesAfterFailedCreate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var deleted []string
+	mux.HandleFunc(testlib.CloudServerURL(snapshotPath), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc(testlib.CloudServerURL(snapshotPath+"/older"), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodDelete, r.Method)
+		deleted = append(deleted, "older")
+	})
+
+	result, err := client.CloudServer.Snapshots().ApplyRetention(ctx, &SnapshotRetentionPlan{
+		Prune:  []*Snapshot{{ID: "older", VolumeID: "vol-gone"}},
+		Create: []string{"vol-gone"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "vol-gone")
+	assert.Empty(t, result.Created)
+	assert.Equal(t, []string{"older"}, result.Pruned)
+	assert.Equal(t, []string{"older"}, deleted)
+}
+
+func TestSnapshotPlanRetentionInvalidPolicy(t *testing.T) {
+	setup()
+	defer teardown()
+
+	_, err := client.CloudServer.Snapshots().PlanRetention(ctx, SnapshotRetentionPolicy{}, nil)
+	assert.True(t, errors.Is(err, ErrCommon))
+}
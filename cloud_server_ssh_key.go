@@ -28,6 +28,7 @@ type SSHKeyService interface {
 	Create(ctx context.Context, scr *SSHKeyCreateRequest) (*SSHKeyCreateResponse, error)
 	Delete(ctx context.Context, keyname string) (*SSHKeyDeleteResponse, error)
 	Get(ctx context.Context, keyname string) (*SSHKey, error)
+	Sync(ctx context.Context, keys []*SSHKeyCreateRequest, opts *SSHKeySyncOptions) (*SSHKeySyncResult, error)
 }
 
 type SSHKey struct {
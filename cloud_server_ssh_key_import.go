@@ -0,0 +1,312 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// LocalSSHKey is an OpenSSH public key read from a file or an ssh-agent.
+// Name defaults to the key comment, or to the file name for keys without comment.
+type LocalSSHKey struct {
+	Name              string
+	Comment           string
+	Type              string
+	PublicKey         string
+	FingerprintMD5    string
+	FingerprintSHA256 string
+	Source            string
+}
+
+// SSHKeySyncOptions represents options when syncing SSH keys.
+// DryRun only reports the changes. Prune deletes the keys which are not in the desired set.
+type SSHKeySyncOptions struct {
+	DryRun bool
+	Prune  bool
+}
+
+// SSHKeySyncResult contains the key names changed by a sync.
+// Replaced keys had the same name but a different public key, they were deleted and created again.
+type SSHKeySyncResult struct {
+	Created   []string `json:"created"`
+	Replaced  []string `json:"replaced"`
+	Deleted   []string `json:"deleted"`
+	Unchanged []string `json:"unchanged"`
+}
+
+// ParseSSHPublicKey parses a public key in the OpenSSH authorized_keys format.
+func ParseSSHPublicKey(data []byte) (*LocalSSHKey, error) {
+	publicKey, comment, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh public key: %v: %w", err, ErrCommon)
+	}
+	return newLocalSSHKey(publicKey, comment), nil
+}
+
+func newLocalSSHKey(publicKey ssh.PublicKey, comment string) *LocalSSHKey {
+	return &LocalSSHKey{
+		Name:              comment,
+		Comment:           comment,
+		Type:              publicKey.Type(),
+		PublicKey:         strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey))),
+		FingerprintMD5:    ssh.FingerprintLegacyMD5(publicKey),
+		FingerprintSHA256: ssh.FingerprintSHA256(publicKey),
+	}
+}
+
+// ReadSSHPublicKeyFile reads a single public key file, e.g. ~/.ssh/id_ed25519.pub.
+func ReadSSHPublicKeyFile(path string) (*LocalSSHKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseSSHPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key.Source = path
+	if key.Name == "" {
+		key.Name = strings.TrimSuffix(filepath.Base(path), ".pub")
+	}
+	return key, nil
+}
+
+// ReadAuthorizedKeys reads every key of an authorized_keys file, skipping blank lines and comments.
+func ReadAuthorizedKeys(path string) ([]*LocalSSHKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys []*LocalSSHKey
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, err := ParseSSHPublicKey([]byte(text))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		key.Source = fmt.Sprintf("%s:%d", path, line)
+		if key.Name == "" {
+			key.Name = fmt.Sprintf("%s-%d", filepath.Base(path), line)
+		}
+		keys = append(keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// LoadSSHPublicKeys reads every *.pub file of dir, dir defaults to ~/.ssh.
+func LoadSSHPublicKeys(dir string) ([]*LocalSSHKey, error) {
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(home, ".ssh")
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pub"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	keys := make([]*LocalSSHKey, 0, len(paths))
+	for _, path := range paths {
+		key, err := ReadSSHPublicKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// SSHAgentKeys lists the keys of the ssh-agent listening on socket, socket defaults to $SSH_AUTH_SOCK.
+func SSHAgentKeys(socket string) ([]*LocalSSHKey, error) {
+	if socket == "" {
+		socket = os.Getenv("SSH_AUTH_SOCK")
+	}
+	if socket == "" {
+		return nil, fmt.Errorf("SSH_AUTH_SOCK is not set: %w", ErrCommon)
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	agentKeys, err := agent.NewClient(conn).List()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*LocalSSHKey, 0, len(agentKeys))
+	for i, agentKey := range agentKeys {
+		publicKey, err := ssh.ParsePublicKey(agentKey.Blob)
+		if err != nil {
+			return nil, err
+		}
+		key := newLocalSSHKey(publicKey, agentKey.Comment)
+		key.Source = "ssh-agent"
+		if key.Name == "" {
+			key.Name = fmt.Sprintf("ssh-agent-%d", i+1)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// CreateRequest returns the request to upload the key.
+func (k *LocalSSHKey) CreateRequest() *SSHKeyCreateRequest {
+	return &SSHKeyCreateRequest{Name: k.Name, PublicKey: k.PublicKey}
+}
+
+// Matches reports whether a key of the project is the same public key, comparing fingerprints.
+func (k *LocalSSHKey) Matches(key *SSHKey) bool {
+	if key.FingerPrint != "" {
+		return fingerprintEqual(key.FingerPrint, k.FingerprintMD5, k.FingerprintSHA256)
+	}
+	remote, err := ParseSSHPublicKey([]byte(key.PublicKey))
+	return err == nil && remote.FingerprintSHA256 == k.FingerprintSHA256
+}
+
+// VerifyFingerprint checks that FingerPrint matches PublicKey, an error means the key drifted.
+func (k *SSHKey) VerifyFingerprint() error {
+	key, err := ParseSSHPublicKey([]byte(k.PublicKey))
+	if err != nil {
+		return err
+	}
+	if !fingerprintEqual(k.FingerPrint, key.FingerprintMD5, key.FingerprintSHA256) {
+		return fmt.Errorf("ssh key %s fingerprint %s does not match its public key %s: %w",
+			k.Name, k.FingerPrint, key.FingerprintSHA256, ErrCommon)
+	}
+	return nil
+}
+
+// fingerprintEqual compares a fingerprint in either the SHA256:base64 or the legacy MD5 hex format.
+func fingerprintEqual(fingerprint string, md5 string, sha256 string) bool {
+	if strings.HasPrefix(fingerprint, "SHA256:") {
+		return fingerprint == sha256
+	}
+	return strings.EqualFold(strings.TrimPrefix(fingerprint, "MD5:"), md5)
+}
+
+// Sync makes the project's SSH keys match keys: missing keys are created and keys whose public key changed
+// are replaced. Keys not in the desired set are only deleted when opts.Prune is set. Every key is validated
+// before any change is made, new keys are created before anything is deleted, and a replaced key is
+// created again from its old public key when creating its replacement fails.
+func (s *cloudServerSSHKeyResource) Sync(ctx context.Context, keys []*SSHKeyCreateRequest, opts *SSHKeySyncOptions) (*SSHKeySyncResult, error) {
+	if opts == nil {
+		opts = &SSHKeySyncOptions{}
+	}
+	if opts.Prune && len(keys) == 0 {
+		return nil, fmt.Errorf("refusing to prune every ssh key of the project: %w", ErrCommon)
+	}
+	desired := make(map[string]*LocalSSHKey, len(keys))
+	var names []string
+	for _, request := range keys {
+		if request == nil {
+			return nil, fmt.Errorf("ssh key is nil: %w", ErrCommon)
+		}
+		if request.Name == "" {
+			return nil, fmt.Errorf("ssh key name is required: %w", ErrCommon)
+		}
+		if _, ok := desired[request.Name]; ok {
+			return nil, fmt.Errorf("duplicate ssh key name %s: %w", request.Name, ErrCommon)
+		}
+		key, err := ParseSSHPublicKey([]byte(request.PublicKey))
+		if err != nil {
+			return nil, fmt.Errorf("ssh key %s: %w", request.Name, err)
+		}
+		key.Name = request.Name
+		desired[request.Name] = key
+		names = append(names, request.Name)
+	}
+
+	existing, err := s.List(ctx, &ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	result := &SSHKeySyncResult{}
+	current := make(map[string]*SSHKey, len(existing))
+	var stale []string
+	for _, keyPair := range existing {
+		key := keyPair.SSHKeyPair
+		current[key.Name] = &key
+		if _, ok := desired[key.Name]; !ok {
+			stale = append(stale, key.Name)
+		}
+	}
+
+	var replaced []string
+	for _, name := range names {
+		remote, ok := current[name]
+		switch {
+		case !ok:
+			if !opts.DryRun {
+				if _, err := s.Create(ctx, desired[name].CreateRequest()); err != nil {
+					return result, err
+				}
+			}
+			result.Created = append(result.Created, name)
+		case desired[name].Matches(remote):
+			result.Unchanged = append(result.Unchanged, name)
+		default:
+			replaced = append(replaced, name)
+		}
+	}
+	for _, name := range replaced {
+		if !opts.DryRun {
+			if err := s.replace(ctx, current[name], desired[name]); err != nil {
+				return result, err
+			}
+		}
+		result.Replaced = append(result.Replaced, name)
+	}
+	if !opts.Prune {
+		return result, nil
+	}
+	for _, name := range stale {
+		if !opts.DryRun {
+			if _, err := s.Delete(ctx, name); err != nil {
+				return result, err
+			}
+		}
+		result.Deleted = append(result.Deleted, name)
+	}
+	return result, nil
+}
+
+// replace deletes the remote key and creates the local key under the same name, the API does not allow
+// two keys with one name. When the create fails the remote key is created again.
+func (s *cloudServerSSHKeyResource) replace(ctx context.Context, remote *SSHKey, key *LocalSSHKey) error {
+	if _, err := s.Delete(ctx, remote.Name); err != nil {
+		return err
+	}
+	_, err := s.Create(ctx, key.CreateRequest())
+	if err == nil {
+		return nil
+	}
+	restoreCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultWaitTimeout)
+	defer cancel()
+	if _, restoreErr := s.Create(restoreCtx, &SSHKeyCreateRequest{Name: remote.Name, PublicKey: remote.PublicKey}); restoreErr != nil {
+		return fmt.Errorf("replace ssh key %s failed: %v, restoring the old key failed: %w", remote.Name, err, restoreErr)
+	}
+	return fmt.Errorf("replace ssh key %s failed, the old key was restored: %w", remote.Name, err)
+}
@@ -0,0 +1,207 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func generateTestSSHKey(t *testing.T, comment string) (ed25519.PrivateKey, string) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	require.NoError(t, err)
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublicKey)))
+	if comment != "" {
+		line += " " + comment
+	}
+	return privateKey, line
+}
+
+func TestReadSSHPublicKeys(t *testing.T) {
+	dir := t.TempDir()
+	_, laptop := generateTestSSHKey(t, "me@laptop")
+	_, deploy := generateTestSSHKey(t, "")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "id_ed25519.pub"), []byte(laptop+"\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "deploy.pub"), []byte(deploy), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "authorized_keys"),
+		[]byte("# team keys\n\n"+laptop+"\n"+deploy+"\n"), 0o600))
+
+	keys, err := LoadSSHPublicKeys(dir)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "deploy", keys[0].Name)
+	assert.Equal(t, "me@laptop", keys[1].Name)
+	assert.Equal(t, ssh.KeyAlgoED25519, keys[1].Type)
+	assert.True(t, strings.HasPrefix(keys[1].FingerprintSHA256, "SHA256:"))
+	assert.Len(t, strings.Split(keys[1].FingerprintMD5, ":"), 16)
+
+	authorized, err := ReadAuthorizedKeys(filepath.Join(dir, "authorized_keys"))
+	require.NoError(t, err)
+	require.Len(t, authorized, 2)
+	assert.Equal(t, keys[1].FingerprintSHA256, authorized[0].FingerprintSHA256)
+	assert.Equal(t, "authorized_keys-4", authorized[1].Name)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.pub"), []byte("ssh-ed25519 AAAA"), 0o600))
+	_, err = LoadSSHPublicKeys(dir)
+	assert.True(t, errors.Is(err, ErrCommon))
+}
+
+func TestSSHAgentKeys(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer listener.Close()
+	keyring := agent.NewKeyring()
+	privateKey, line := generateTestSSHKey(t, "")
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: privateKey, Comment: "agent@host"}))
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = agent.ServeAgent(keyring, conn)
+		}
+	}()
+
+	keys, err := SSHAgentKeys(socket)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "agent@host", keys[0].Name)
+	assert.Equal(t, line, keys[0].PublicKey)
+	assert.Equal(t, "ssh-agent", keys[0].Source)
+}
+
+func TestSSHKeyVerifyFingerprint(t *testing.T) {
+	_, line := generateTestSSHKey(t, "me@laptop")
+	local, err := ParseSSHPublicKey([]byte(line))
+	require.NoError(t, err)
+
+	key := &SSHKey{Name: "me", PublicKey: line, FingerPrint: local.FingerprintMD5}
+	assert.NoError(t, key.VerifyFingerprint())
+	assert.True(t, local.Matches(key))
+	key.FingerPrint = local.FingerprintSHA256
+	assert.NoError(t, key.VerifyFingerprint())
+	key.FingerPrint = "28:56:9e:4b:bb:a0:91:71:42:37:40:a2:d0:66:24:17"
+	assert.True(t, errors.Is(key.VerifyFingerprint(), ErrCommon))
+	assert.False(t, local.Matches(key))
+	key.FingerPrint = ""
+	assert.True(t, local.Matches(key))
+}
+
+func TestSSHKeySync(t *testing.T) {
+	setup()
+	defer teardown()
+
+	_, kept := generateTestSSHKey(t, "")
+	_, rotatedOld := generateTestSSHKey(t, "")
+	_, rotatedNew := generateTestSSHKey(t, "")
+	_, added := generateTestSSHKey(t, "")
+	_, rejected := generateTestSSHKey(t, "")
+	var mu sync.Mutex
+	remote := map[string]string{"kept": kept, "rotated": rotatedOld, "stale": added}
+	var calls []string
+
+	mux.HandleFunc(testlib.CloudServerURL(sshKeyBasePath), func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			var keyPairs []*KeyPair
+			for name, publicKey := range remote {
+				parsed, err := ParseSSHPublicKey([]byte(publicKey))
+				require.NoError(t, err)
+				keyPairs = append(keyPairs, &KeyPair{SSHKeyPair: SSHKey{Name: name, PublicKey: publicKey, FingerPrint: parsed.FingerprintMD5}})
+			}
+			sort.Slice(keyPairs, func(i, j int) bool { return keyPairs[i].SSHKeyPair.Name < keyPairs[j].SSHKeyPair.Name })
+			_ = json.NewEncoder(w).Encode(keyPairs)
+		case http.MethodPost:
+			var payload SSHKeyCreateRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			_, ok := remote[payload.Name]
+			require.False(t, ok)
+			calls = append(calls, "create:"+payload.Name)
+			if payload.PublicKey == rejected {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			remote[payload.Name] = payload.PublicKey
+			_ = json.NewEncoder(w).Encode(SSHKeyCreateResponse{SSHKey: SSHKey{Name: payload.Name}})
+		}
+	})
+	mux.HandleFunc(testlib.CloudServerURL(sshKeyBasePath+"/"), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodDelete, r.Method)
+		mu.Lock()
+		defer mu.Unlock()
+		name := strings.TrimPrefix(r.URL.Path, testlib.CloudServerURL(sshKeyBasePath+"/"))
+		delete(remote, name)
+		calls = append(calls, "delete:"+name)
+		_, _ = fmt.Fprint(w, `{"message": "Delete successful"}`)
+	})
+
+	desired := []*SSHKeyCreateRequest{
+		{Name: "kept", PublicKey: kept},
+		{Name: "rotated", PublicKey: rotatedNew},
+		{Name: "added", PublicKey: added},
+	}
+	result, err := client.CloudServer.SSHKeys().Sync(ctx, desired, &SSHKeySyncOptions{Prune: true, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"added"}, result.Created)
+	assert.Equal(t, []string{"rotated"}, result.Replaced)
+	assert.Equal(t, []string{"stale"}, result.Deleted)
+	assert.Empty(t, calls)
+
+	result, err = client.CloudServer.SSHKeys().Sync(ctx, desired, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"added"}, result.Created)
+	assert.Equal(t, []string{"rotated"}, result.Replaced)
+	assert.Empty(t, result.Deleted)
+	assert.Equal(t, []string{"kept"}, result.Unchanged)
+	assert.Equal(t, []string{"create:added", "delete:rotated", "create:rotated"}, calls)
+	assert.Contains(t, remote, "stale")
+
+	calls = nil
+	result, err = client.CloudServer.SSHKeys().Sync(ctx, desired, &SSHKeySyncOptions{Prune: true})
+	require.NoError(t, err)
+	assert.Len(t, result.Unchanged, 3)
+	assert.Equal(t, []string{"stale"}, result.Deleted)
+	assert.Equal(t, []string{"delete:stale"}, calls)
+
+	calls = nil
+	_, err = client.CloudServer.SSHKeys().Sync(ctx, []*SSHKeyCreateRequest{{Name: "kept", PublicKey: rejected}}, nil)
+	require.Error(t, err)
+	assert.Equal(t, []string{"delete:kept", "create:kept", "create:kept"}, calls)
+	assert.Equal(t, kept, remote["kept"])
+
+	calls = nil
+	for _, keys := range [][]*SSHKeyCreateRequest{
+		{{Name: "bad", PublicKey: "ssh-rsa AAAA"}},
+		{{Name: "kept", PublicKey: kept}, nil},
+	} {
+		_, err = client.CloudServer.SSHKeys().Sync(ctx, keys, &SSHKeySyncOptions{Prune: true})
+		assert.True(t, errors.Is(err, ErrCommon))
+	}
+	_, err = client.CloudServer.SSHKeys().Sync(ctx, nil, &SSHKeySyncOptions{Prune: true})
+	assert.True(t, errors.Is(err, ErrCommon))
+	assert.Empty(t, calls)
+	assert.Len(t, remote, 3)
+}
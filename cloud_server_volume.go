@@ -30,6 +30,7 @@ type VolumeService interface {
 	Restore(ctx context.Context, id string, snapshotID string) (*Task, error)
 	Patch(ctx context.Context, id string, req *VolumePatchRequest) (*Volume, error)
 	ListVolumeTypes(ctx context.Context, opts *ListVolumeTypesOptions) ([]*VolumeType, error)
+	Move(ctx context.Context, volumeID string, fromServerID string, toServerID string) (*Volume, error)
 }
 
 // VolumeListOptions represents options to list volumes.
@@ -0,0 +1,142 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	volumeAvailableStatus = "available"
+	volumeInUseStatus     = "in-use"
+	volumeErrorStatus     = "error"
+	rootDiskAttachedType  = "rootdisk"
+)
+
+// Move detaches a data volume from a server and attaches it to another server in the same availability zone.
+// Both steps are retried on transient errors, and the volume is attached back to the original server when
+// the detach does not complete or attaching to the new server fails. The rollback also runs when ctx is
+// cancelled or expires, with its own timeout.
+func (v *cloudServerVolumeResource) Move(ctx context.Context, volumeID string, fromServerID string, toServerID string) (*Volume, error) {
+	if fromServerID == toServerID {
+		return nil, fmt.Errorf("volume %s is already attached to server %s: %w", volumeID, toServerID, ErrCommon)
+	}
+	volume, err := v.Get(ctx, volumeID)
+	if err != nil {
+		return nil, err
+	}
+	if !volumeAttachedTo(volume, fromServerID) {
+		return nil, fmt.Errorf("volume %s is not attached to server %s: %w", volumeID, fromServerID, ErrCommon)
+	}
+	if volume.AttachedType == rootDiskAttachedType {
+		return nil, fmt.Errorf("volume %s is the root disk of server %s: %w", volumeID, fromServerID, ErrCommon)
+	}
+
+	servers := &cloudServerService{client: v.client}
+	from, err := servers.Get(ctx, fromServerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, attached := range from.AttachedVolumes {
+		if attached.ID == volumeID && attached.AttachedType == rootDiskAttachedType {
+			return nil, fmt.Errorf("volume %s is the root disk of server %s: %w", volumeID, fromServerID, ErrCommon)
+		}
+	}
+	to, err := servers.Get(ctx, toServerID)
+	if err != nil {
+		return nil, err
+	}
+	if from.AvailabilityZone != to.AvailabilityZone {
+		return nil, fmt.Errorf("server %s is in %s but server %s is in %s: %w",
+			fromServerID, from.AvailabilityZone, toServerID, to.AvailabilityZone, ErrCommon)
+	}
+
+	err = v.client.retryAction(ctx, func() error {
+		_, err := v.Detach(ctx, volumeID, fromServerID)
+		return err
+	}, func() (bool, error) {
+		volume, err := v.Get(ctx, volumeID)
+		return err == nil && !volumeAttachedTo(volume, fromServerID), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := v.waitForStatus(ctx, volumeID, volumeAvailableStatus); err != nil {
+		if rollbackErr := v.rollbackAttach(ctx, volumeID, fromServerID); rollbackErr != nil {
+			return nil, fmt.Errorf("detach from server %s failed: %v, rollback to server %s failed: %w",
+				fromServerID, err, fromServerID, rollbackErr)
+		}
+		return nil, fmt.Errorf("detach from server %s failed, volume was attached back: %w", fromServerID, err)
+	}
+
+	moved, err := v.attachAndWait(ctx, volumeID, toServerID)
+	if err != nil {
+		if rollbackErr := v.rollbackAttach(ctx, volumeID, fromServerID); rollbackErr != nil {
+			return nil, fmt.Errorf("attach to server %s failed: %v, rollback to server %s failed: %w",
+				toServerID, err, fromServerID, rollbackErr)
+		}
+		return nil, fmt.Errorf("attach to server %s failed, volume was attached back to server %s: %w",
+			toServerID, fromServerID, err)
+	}
+	return moved, nil
+}
+
+// rollbackAttach attaches the volume back to a server unless it is still in use by it. It runs with its own
+// timeout, also when ctx is cancelled or expired.
+func (v *cloudServerVolumeResource) rollbackAttach(ctx context.Context, volumeID string, serverID string) error {
+	rollbackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultWaitTimeout)
+	defer cancel()
+	volume, err := v.Get(rollbackCtx, volumeID)
+	if err != nil {
+		return err
+	}
+	if volume.Status == volumeInUseStatus && volumeAttachedTo(volume, serverID) {
+		return nil
+	}
+	_, err = v.attachAndWait(rollbackCtx, volumeID, serverID)
+	return err
+}
+
+func (v *cloudServerVolumeResource) attachAndWait(ctx context.Context, volumeID string, serverID string) (*Volume, error) {
+	err := v.client.retryAction(ctx, func() error {
+		_, err := v.Attach(ctx, volumeID, serverID)
+		return err
+	}, func() (bool, error) {
+		volume, err := v.Get(ctx, volumeID)
+		return err == nil && volumeAttachedTo(volume, serverID), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.waitForStatus(ctx, volumeID, volumeInUseStatus)
+}
+
+// waitForStatus waits until the volume reaches the given status, it fails early when the volume goes into error.
+func (v *cloudServerVolumeResource) waitForStatus(ctx context.Context, volumeID string, status string) (*Volume, error) {
+	var volume *Volume
+	err := v.client.waitFor(ctx, func() (bool, error) {
+		var err error
+		volume, err = v.Get(ctx, volumeID)
+		if err != nil {
+			return false, err
+		}
+		if volume.Status == volumeErrorStatus {
+			return false, fmt.Errorf("volume %s is in error status: %w", volumeID, ErrCommon)
+		}
+		return volume.Status == status, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return volume, nil
+}
+
+func volumeAttachedTo(volume *Volume, serverID string) bool {
+	for _, attachment := range volume.Attachments {
+		if attachment.ServerID == serverID {
+			return true
+		}
+	}
+	return false
+}
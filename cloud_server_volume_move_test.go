@@ -0,0 +1,171 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVolumeMove serves a volume and two servers, attach requests to failServer are rejected.
+type fakeVolumeMove struct {
+	mu         sync.Mutex
+	serverID   string
+	status     string
+	failServer string
+	// stuckServer never finishes attaching.
+	stuckServer string
+	// stuckDetach never finishes detaching.
+	stuckDetach bool
+	conflicts   int
+	actions     []string
+}
+
+func (f *fakeVolumeMove) register(t *testing.T, volumeID string, zones map[string]string) {
+	var v cloudServerVolumeResource
+	mux.HandleFunc(testlib.CloudServerURL(v.itemPath(volumeID)), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		attachments := "[]"
+		if f.serverID != "" {
+			attachments = fmt.Sprintf(`[{"server_id": "%s", "volume_id": "%s"}]`, f.serverID, volumeID)
+		}
+		_, _ = fmt.Fprintf(w, `{"id": "%s", "status": "%s", "attached_type": "datadisk", "attachments": %s}`,
+			volumeID, f.status, attachments)
+		// Every read moves a pending transition forward.
+		switch f.status {
+		case "detaching":
+			if !f.stuckDetach {
+				f.status, f.serverID = "available", ""
+			}
+		case "attaching":
+			if f.serverID != f.stuckServer {
+				f.status = "in-use"
+			}
+		}
+	})
+	mux.HandleFunc(testlib.CloudServerURL(v.itemActionPath(volumeID)), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		var action VolumeAction
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&action))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.actions = append(f.actions, action.Type+":"+action.ServerID)
+		if f.conflicts > 0 {
+			f.conflicts--
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		switch action.Type {
+		case "detach":
+			f.status = "detaching"
+		case "attach":
+			if action.ServerID == f.failServer {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f.status, f.serverID = "attaching", action.ServerID
+		}
+		_, _ = fmt.Fprint(w, `{"message": "ok"}`)
+	})
+	for id, zone := range zones {
+		id, zone := id, zone
+		mux.HandleFunc(testlib.CloudServerURL(serverBasePath+"/"+id), func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, `{"id": "%s", "OS-EXT-AZ:availability_zone": "%s",
+"os-extended-volumes:volumes_attached": [{"id": "root-%s", "attached_type": "rootdisk"}]}`, id, zone, id)
+		})
+	}
+}
+
+func TestVolumeMove(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	f := &fakeVolumeMove{serverID: "srv-a", status: "in-use", conflicts: 1}
+	f.register(t, "vol-1", map[string]string{"srv-a": "HN1", "srv-b": "HN1"})
+
+	volume, err := client.CloudServer.Volumes().Move(ctx, "vol-1", "srv-a", "srv-b")
+	require.NoError(t, err)
+	assert.Equal(t, "in-use", volume.Status)
+	assert.Equal(t, "srv-b", volume.Attachments[0].ServerID)
+	assert.Equal(t, []string{"detach:srv-a", "detach:srv-a", "attach:srv-b"}, f.actions)
+}
+
+func TestVolumeMoveRollback(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	f := &fakeVolumeMove{serverID: "srv-a", status: "in-use", failServer: "srv-b"}
+	f.register(t, "vol-1", map[string]string{"srv-a": "HN1", "srv-b": "HN1"})
+
+	_, err := client.CloudServer.Volumes().Move(ctx, "vol-1", "srv-a", "srv-b")
+	require.Error(t, err)
+	assert.Equal(t, []string{"detach:srv-a", "attach:srv-b", "attach:srv-a"}, f.actions)
+	assert.Equal(t, "srv-a", f.serverID)
+	assert.Equal(t, "in-use", f.status)
+}
+
+func TestVolumeMoveRollbackAfterTimeout(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	f := &fakeVolumeMove{serverID: "srv-a", status: "in-use", stuckServer: "srv-b"}
+	f.register(t, "vol-1", map[string]string{"srv-a": "HN1", "srv-b": "HN1"})
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err := client.CloudServer.Volumes().Move(timeoutCtx, "vol-1", "srv-a", "srv-b")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, []string{"detach:srv-a", "attach:srv-b", "attach:srv-a"}, f.actions)
+	assert.Equal(t, "srv-a", f.serverID)
+	assert.Equal(t, "in-use", f.status)
+}
+
+func TestVolumeMoveRollbackAfterDetachTimeout(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	f := &fakeVolumeMove{serverID: "srv-a", status: "in-use", stuckDetach: true}
+	f.register(t, "vol-1", map[string]string{"srv-a": "HN1", "srv-b": "HN1"})
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err := client.CloudServer.Volumes().Move(timeoutCtx, "vol-1", "srv-a", "srv-b")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, []string{"detach:srv-a", "attach:srv-a"}, f.actions)
+	assert.Equal(t, "srv-a", f.serverID)
+	assert.Equal(t, "in-use", f.status)
+}
+
+func TestVolumeMoveValidation(t *testing.T) {
+	setup()
+	defer teardown()
+
+	f := &fakeVolumeMove{serverID: "srv-a", status: "in-use"}
+	f.register(t, "vol-1", map[string]string{"srv-a": "HN1", "srv-b": "HN2"})
+	f.register(t, "root-srv-a", nil)
+
+	_, err := client.CloudServer.Volumes().Move(ctx, "vol-1", "srv-a", "srv-b")
+	assert.True(t, errors.Is(err, ErrCommon))
+	_, err = client.CloudServer.Volumes().Move(ctx, "vol-1", "srv-c", "srv-b")
+	assert.True(t, errors.Is(err, ErrCommon))
+	_, err = client.CloudServer.Volumes().Move(ctx, "root-srv-a", "srv-a", "srv-b")
+	assert.True(t, errors.Is(err, ErrCommon))
+	assert.Contains(t, err.Error(), "root disk")
+	assert.Empty(t, f.actions)
+}
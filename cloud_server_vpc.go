@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/netip"
 	"strings"
 
 	"github.com/bizflycloud/gobizfly/utils"
@@ -31,6 +32,10 @@ type VPCNetworkService interface {
 	Update(ctx context.Context, vpcID string, uvpl *UpdateVPCPayload) (*VPCNetwork, error)
 	Create(ctx context.Context, cvpl *CreateVPCPayload) (*VPCNetwork, error)
 	Delete(ctx context.Context, vpcID string) error
+	CheckOverlap(ctx context.Context, cidr string, opts *VPCCIDROptions) ([]*VPCCIDRConflict, error)
+	AllocateCIDR(ctx context.Context, prefixLen int, opts *VPCCIDROptions) (netip.Prefix, error)
+	EnableInternetAccess(ctx context.Context, vpcID string) (*ExtendedInternetGateway, error)
+	DisableInternetAccess(ctx context.Context, vpcID string) error
 }
 
 type VPCNetwork struct {
@@ -0,0 +1,181 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+
+	"github.com/bizflycloud/gobizfly/utils"
+)
+
+// defaultVPCCIDRPools are the private IPv4 ranges of RFC 1918, searched in order when allocating.
+var defaultVPCCIDRPools = []netip.Prefix{
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+}
+
+// VPCCIDROptions represents options when checking or allocating VPC CIDRs.
+// Pools are the ranges to allocate from, they default to the RFC 1918 private ranges.
+// Regions lists other regions whose VPCs are also considered, the client's region is always considered.
+type VPCCIDROptions struct {
+	Pools   []netip.Prefix
+	Regions []string
+}
+
+// VPCCIDRConflict describes an existing subnet overlapping a CIDR.
+type VPCCIDRConflict struct {
+	Region   string `json:"region"`
+	VPCID    string `json:"vpc_id"`
+	VPCName  string `json:"vpc_name"`
+	SubnetID string `json:"subnet_id"`
+	CIDR     string `json:"cidr"`
+}
+
+type vpcUsedCIDR struct {
+	prefix   netip.Prefix
+	conflict VPCCIDRConflict
+}
+
+// CheckOverlap returns the existing VPC subnets overlapping cidr, an empty result means cidr is free.
+func (v cloudServerVPCNetworkResource) CheckOverlap(ctx context.Context, cidr string, opts *VPCCIDROptions) ([]*VPCCIDRConflict, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cidr %q: %w", cidr, ErrCommon)
+	}
+	used, err := v.usedCIDRs(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []*VPCCIDRConflict
+	for _, u := range used {
+		if u.prefix.Overlaps(prefix.Masked()) {
+			conflict := u.conflict
+			conflicts = append(conflicts, &conflict)
+		}
+	}
+	return conflicts, nil
+}
+
+// AllocateCIDR returns the first IPv4 range of the given prefix length which does not overlap any VPC subnet.
+func (v cloudServerVPCNetworkResource) AllocateCIDR(ctx context.Context, prefixLen int, opts *VPCCIDROptions) (netip.Prefix, error) {
+	if opts == nil {
+		opts = &VPCCIDROptions{}
+	}
+	pools := opts.Pools
+	if len(pools) == 0 {
+		pools = defaultVPCCIDRPools
+	}
+	used, err := v.usedCIDRs(ctx, opts)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	prefixes := make([]netip.Prefix, 0, len(used))
+	for _, u := range used {
+		prefixes = append(prefixes, u.prefix)
+	}
+	for _, pool := range pools {
+		if prefix, ok := allocateCIDR(pool, prefixLen, prefixes); ok {
+			return prefix, nil
+		}
+	}
+	return netip.Prefix{}, fmt.Errorf("no free /%d range left in %v: %w", prefixLen, pools, ErrCommon)
+}
+
+// usedCIDRs lists the subnets of every VPC in the client's region and in the requested regions.
+func (v cloudServerVPCNetworkResource) usedCIDRs(ctx context.Context, opts *VPCCIDROptions) ([]vpcUsedCIDR, error) {
+	regions := []string{v.client.regionName}
+	if opts == nil {
+		opts = &VPCCIDROptions{}
+	}
+	for _, region := range opts.Regions {
+		regionName, err := utils.ParseRegionName(region)
+		if err != nil {
+			return nil, err
+		}
+		if regionName != v.client.regionName {
+			regions = append(regions, regionName)
+		}
+	}
+
+	var used []vpcUsedCIDR
+	for _, region := range regions {
+		resource := v
+		if region != v.client.regionName {
+			regionClient := *v.client
+			regionClient.regionName = region
+			resource = cloudServerVPCNetworkResource{client: &regionClient}
+		}
+		vpcs, err := resource.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, vpc := range vpcs {
+			for _, subnet := range vpc.Subnets {
+				prefix, err := netip.ParsePrefix(subnet.CIDR)
+				if err != nil {
+					continue
+				}
+				used = append(used, vpcUsedCIDR{
+					prefix: prefix.Masked(),
+					conflict: VPCCIDRConflict{
+						Region:   region,
+						VPCID:    vpc.ID,
+						VPCName:  vpc.Name,
+						SubnetID: subnet.ID,
+						CIDR:     subnet.CIDR,
+					},
+				})
+			}
+		}
+	}
+	return used, nil
+}
+
+// allocateCIDR finds the first IPv4 prefix of length bits in pool which overlaps none of used.
+func allocateCIDR(pool netip.Prefix, bits int, used []netip.Prefix) (netip.Prefix, bool) {
+	pool = pool.Masked()
+	if !pool.Addr().Is4() || bits < pool.Bits() || bits > 32 {
+		return netip.Prefix{}, false
+	}
+	size := uint64(1) << (32 - bits)
+	start := uint64(ipv4ToUint32(pool.Addr()))
+	end := start + (uint64(1) << (32 - pool.Bits()))
+	for candidate := start; candidate+size <= end; {
+		prefix := netip.PrefixFrom(uint32ToIPv4(uint32(candidate)), bits)
+		next := candidate + size
+		free := true
+		for _, u := range used {
+			if !u.Overlaps(prefix) {
+				continue
+			}
+			free = false
+			// Skip past the used range, aligned to the candidate size.
+			if u.Addr().Is4() {
+				usedEnd := uint64(ipv4ToUint32(u.Masked().Addr())) + (uint64(1) << (32 - u.Bits()))
+				if aligned := (usedEnd + size - 1) / size * size; aligned > next {
+					next = aligned
+				}
+			}
+		}
+		if free {
+			return prefix, true
+		}
+		candidate = next
+	}
+	return netip.Prefix{}, false
+}
+
+func ipv4ToUint32(addr netip.Addr) uint32 {
+	b := addr.As4()
+	return binary.BigEndian.Uint32(b[:])
+}
+
+func uint32ToIPv4(value uint32) netip.Addr {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], value)
+	return netip.AddrFrom4(b)
+}
@@ -0,0 +1,92 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllocateCIDR(t *testing.T) {
+	pool := netip.MustParsePrefix("10.0.0.0/16")
+	used := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/24"),
+		netip.MustParsePrefix("10.0.1.0/25"),
+		netip.MustParsePrefix("10.0.4.0/22"),
+	}
+
+	prefix, ok := allocateCIDR(pool, 24, used)
+	require.True(t, ok)
+	assert.Equal(t, "10.0.2.0/24", prefix.String())
+
+	prefix, ok = allocateCIDR(pool, 25, used)
+	require.True(t, ok)
+	assert.Equal(t, "10.0.1.128/25", prefix.String())
+
+	prefix, ok = allocateCIDR(pool, 22, used)
+	require.True(t, ok)
+	assert.Equal(t, "10.0.8.0/22", prefix.String())
+
+	_, ok = allocateCIDR(pool, 16, used)
+	assert.False(t, ok)
+	_, ok = allocateCIDR(pool, 8, nil)
+	assert.False(t, ok)
+}
+
+func TestVPCCheckOverlapAndAllocateCIDR(t *testing.T) {
+	setup()
+	defer teardown()
+	client.services = append(client.services, &Service{
+		Name:          "Cloud Server",
+		CanonicalName: serverServiceName,
+		ServiceURL:    serverTest.URL + "/hcm/iaas-cloud/api",
+		Region:        "HoChiMinh",
+	})
+	mux.HandleFunc(testlib.CloudServerURL(vpcPath), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, `[
+{"id": "vpc-1", "name": "default", "subnets": [{"id": "subnet-1", "cidr": "10.0.0.0/16"}, {"id": "subnet-v6", "cidr": "fd00::/64"}]},
+{"id": "vpc-2", "name": "apps", "subnets": [{"id": "subnet-2", "cidr": "10.1.0.0/16"}]}
+]`)
+	})
+	mux.HandleFunc("/hcm/iaas-cloud/api"+vpcPath, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, `[{"id": "vpc-hcm", "name": "hcm", "subnets": [{"id": "subnet-hcm", "cidr": "10.2.0.0/16"}]}]`)
+	})
+
+	conflicts, err := client.CloudServer.VPCNetworks().CheckOverlap(ctx, "10.0.128.0/17", nil)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "vpc-1", conflicts[0].VPCID)
+	assert.Equal(t, "subnet-1", conflicts[0].SubnetID)
+	assert.Equal(t, "HaNoi", conflicts[0].Region)
+
+	conflicts, err = client.CloudServer.VPCNetworks().CheckOverlap(ctx, "10.2.3.0/24", nil)
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	conflicts, err = client.CloudServer.VPCNetworks().CheckOverlap(ctx, "10.2.3.0/24", &VPCCIDROptions{Regions: []string{"hcm"}})
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "HoChiMinh", conflicts[0].Region)
+
+	_, err = client.CloudServer.VPCNetworks().CheckOverlap(ctx, "10.2.3.0", nil)
+	assert.Error(t, err)
+
+	prefix, err := client.CloudServer.VPCNetworks().AllocateCIDR(ctx, 16, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "10.2.0.0/16", prefix.String())
+	prefix, err = client.CloudServer.VPCNetworks().AllocateCIDR(ctx, 16, &VPCCIDROptions{Regions: []string{"HoChiMinh"}})
+	require.NoError(t, err)
+	assert.Equal(t, "10.3.0.0/16", prefix.String())
+	prefix, err = client.CloudServer.VPCNetworks().AllocateCIDR(ctx, 24, &VPCCIDROptions{
+		Pools: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/15"), netip.MustParsePrefix("192.168.0.0/16")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.0.0/24", prefix.String())
+}
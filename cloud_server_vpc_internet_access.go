@@ -0,0 +1,139 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+)
+
+// EnableInternetAccess gives a VPC outbound internet access. The internet gateway already routing the VPC
+// is reused, otherwise the gateway referenced by the VPC is attached or a new gateway is created.
+// It waits until the gateway has external IPs assigned, calling it again on an enabled VPC is a no-op.
+func (v cloudServerVPCNetworkResource) EnableInternetAccess(ctx context.Context, vpcID string) (*ExtendedInternetGateway, error) {
+	vpc, err := v.Get(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	igws := &cloudServerInternetGatewayResource{client: v.client}
+	gateways, err := listAllInternetGateways(ctx, igws)
+	if err != nil {
+		return nil, err
+	}
+
+	var gateway *ExtendedInternetGateway
+	for _, g := range gateways {
+		if internetGatewayRoutes(g, vpcID) {
+			gateway = g
+			break
+		}
+	}
+	if gateway == nil && vpc.InternetGateway != nil && *vpc.InternetGateway != "" {
+		existing, err := igws.Get(ctx, *vpc.InternetGateway)
+		if err != nil {
+			return nil, err
+		}
+		networkIDs := []string{vpcID}
+		for _, info := range existing.InterfacesInfo {
+			networkIDs = append(networkIDs, info.NetworkID)
+		}
+		gateway, err = igws.Update(ctx, existing.ID, UpdateInternetGatewayPayload{
+			Name:        existing.Name,
+			Description: existing.Description,
+			NetworkIDs:  uniqueStrings(networkIDs),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if gateway == nil {
+		gateway, err = igws.Create(ctx, CreateInternetGatewayPayload{
+			Name:       "igw-" + vpc.Name,
+			NetworkIDs: &[]string{vpcID},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if internetGatewayRoutes(gateway, vpcID) && internetGatewayHasExternalIPs(gateway) {
+		return gateway, nil
+	}
+
+	gatewayID := gateway.ID
+	err = v.client.waitFor(ctx, func() (bool, error) {
+		gateway, err = igws.Get(ctx, gatewayID)
+		if err != nil {
+			return false, err
+		}
+		return internetGatewayRoutes(gateway, vpcID) && internetGatewayHasExternalIPs(gateway), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gateway, nil
+}
+
+// DisableInternetAccess detaches the VPC from every internet gateway routing it and waits until it is detached.
+// The gateways are kept, calling it on a VPC without internet access is a no-op.
+func (v cloudServerVPCNetworkResource) DisableInternetAccess(ctx context.Context, vpcID string) error {
+	igws := &cloudServerInternetGatewayResource{client: v.client}
+	gateways, err := listAllInternetGateways(ctx, igws)
+	if err != nil {
+		return err
+	}
+	for _, gateway := range gateways {
+		if !internetGatewayRoutes(gateway, vpcID) {
+			continue
+		}
+		networkIDs := []string{}
+		for _, info := range gateway.InterfacesInfo {
+			if info.NetworkID != vpcID {
+				networkIDs = append(networkIDs, info.NetworkID)
+			}
+		}
+		_, err := igws.Update(ctx, gateway.ID, UpdateInternetGatewayPayload{
+			Name:        gateway.Name,
+			Description: gateway.Description,
+			NetworkIDs:  uniqueStrings(networkIDs),
+		})
+		if err != nil {
+			return err
+		}
+		gatewayID := gateway.ID
+		err = v.client.waitFor(ctx, func() (bool, error) {
+			gateway, err := igws.Get(ctx, gatewayID)
+			if err != nil {
+				return false, err
+			}
+			return !internetGatewayRoutes(gateway, vpcID), nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func internetGatewayRoutes(gateway *ExtendedInternetGateway, vpcID string) bool {
+	for _, info := range gateway.InterfacesInfo {
+		if info.NetworkID == vpcID {
+			return true
+		}
+	}
+	return false
+}
+
+func internetGatewayHasExternalIPs(gateway *ExtendedInternetGateway) bool {
+	return gateway.ExTernalGatewayInfo != nil && len(gateway.ExTernalGatewayInfo.ExternalFixedIPs) > 0
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
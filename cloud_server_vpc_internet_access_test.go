@@ -0,0 +1,129 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeInternetGateways serves gateways whose external IP is assigned one read after being attached.
+type fakeInternetGateways struct {
+	mu       sync.Mutex
+	gateways map[string]*ExtendedInternetGateway
+	pending  map[string]bool
+	creates  int
+	updates  int
+}
+
+func (f *fakeInternetGateways) setNetworks(gateway *ExtendedInternetGateway, networkIDs []string) {
+	gateway.InterfacesInfo = nil
+	for _, networkID := range networkIDs {
+		gateway.InterfacesInfo = append(gateway.InterfacesInfo, InterfaceInfo{NetworkID: networkID})
+	}
+	if gateway.ExTernalGatewayInfo == nil {
+		f.pending[gateway.ID] = true
+	}
+}
+
+func (f *fakeInternetGateways) register(t *testing.T) {
+	mux.HandleFunc(testlib.CloudServerURL(igwPath), func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			result := ListInternetGatewaysResult{InternetGateways: []*ExtendedInternetGateway{}}
+			for _, gateway := range f.gateways {
+				result.InternetGateways = append(result.InternetGateways, gateway)
+			}
+			_ = json.NewEncoder(w).Encode(result)
+		case http.MethodPost:
+			var payload CreateInternetGatewayPayload
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			f.creates++
+			gateway := &ExtendedInternetGateway{InternetGateway: InternetGateway{ID: fmt.Sprintf("igw-%d", f.creates), Name: payload.Name}}
+			f.gateways[gateway.ID] = gateway
+			f.setNetworks(gateway, *payload.NetworkIDs)
+			_ = json.NewEncoder(w).Encode(gateway)
+		default:
+			t.Fatalf("unexpected method %s", r.Method)
+		}
+	})
+	mux.HandleFunc(testlib.CloudServerURL(igwPath+"/"), func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		gateway, ok := f.gateways[strings.TrimPrefix(r.URL.Path, testlib.CloudServerURL(igwPath+"/"))]
+		require.True(t, ok)
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(gateway)
+			if f.pending[gateway.ID] {
+				gateway.ExTernalGatewayInfo = &ExternalGatewayInfo{ExternalFixedIPs: []ExternalFixedIP{{IpAddress: "103.107.180.104"}}}
+				delete(f.pending, gateway.ID)
+			}
+		case http.MethodPut:
+			var payload UpdateInternetGatewayPayload
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			f.updates++
+			f.setNetworks(gateway, payload.NetworkIDs)
+			_ = json.NewEncoder(w).Encode(gateway)
+		default:
+			t.Fatalf("unexpected method %s", r.Method)
+		}
+	})
+}
+
+func TestVPCEnableDisableInternetAccess(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	var v cloudServerVPCNetworkResource
+	mux.HandleFunc(testlib.CloudServerURL(v.itemPath("vpc-1")), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, `{"id": "vpc-1", "name": "apps", "internet_gateway": null}`)
+	})
+	mux.HandleFunc(testlib.CloudServerURL(v.itemPath("vpc-2")), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, `{"id": "vpc-2", "name": "db", "internet_gateway": "igw-1"}`)
+	})
+	f := &fakeInternetGateways{gateways: map[string]*ExtendedInternetGateway{}, pending: map[string]bool{}}
+	f.register(t)
+
+	gateway, err := client.CloudServer.VPCNetworks().EnableInternetAccess(ctx, "vpc-1")
+	require.NoError(t, err)
+	assert.Equal(t, "igw-1", gateway.ID)
+	assert.Equal(t, "igw-apps", gateway.Name)
+	assert.Equal(t, "103.107.180.104", gateway.ExTernalGatewayInfo.ExternalFixedIPs[0].IpAddress)
+	assert.Equal(t, 1, f.creates)
+
+	gateway, err = client.CloudServer.VPCNetworks().EnableInternetAccess(ctx, "vpc-1")
+	require.NoError(t, err)
+	assert.Equal(t, "igw-1", gateway.ID)
+	assert.Equal(t, 1, f.creates)
+	assert.Equal(t, 0, f.updates)
+
+	gateway, err = client.CloudServer.VPCNetworks().EnableInternetAccess(ctx, "vpc-2")
+	require.NoError(t, err)
+	assert.Equal(t, "igw-1", gateway.ID)
+	assert.Equal(t, 1, f.creates)
+	assert.Equal(t, 1, f.updates)
+	assert.Len(t, gateway.InterfacesInfo, 2)
+
+	require.NoError(t, client.CloudServer.VPCNetworks().DisableInternetAccess(ctx, "vpc-1"))
+	assert.Equal(t, 2, f.updates)
+	require.Len(t, f.gateways["igw-1"].InterfacesInfo, 1)
+	assert.Equal(t, "vpc-2", f.gateways["igw-1"].InterfacesInfo[0].NetworkID)
+
+	require.NoError(t, client.CloudServer.VPCNetworks().DisableInternetAccess(ctx, "vpc-1"))
+	assert.Equal(t, 2, f.updates)
+}
@@ -27,6 +27,7 @@ type CloudServerPublicNetworkInterfaceService interface {
 	Get(ctx context.Context, wanIPID string) (*CloudServerPublicNetworkInterface, error)
 	Delete(ctx context.Context, publicNetworkInterfaceID string) error
 	Action(ctx context.Context, publicNetworkInterfaceID string, payload *ActionPublicNetworkInterfacePayload) error
+	Failover(ctx context.Context, wanIPID string, toServerID string) (*FailoverResult, error)
 }
 
 type CloudServerPublicNetworkInterface struct {
@@ -2,6 +2,13 @@
 
 package gobizfly
 
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
 // SliceContains - Check data in slice
 func SliceContains(slice interface{}, val interface{}) (int, bool) {
 	switch v := slice.(type) {
@@ -26,3 +33,77 @@ func SliceContains(slice interface{}, val interface{}) (int, bool) {
 		return -1, false
 	}
 }
+
+const (
+	defaultPollInterval = 5 * time.Second
+	defaultWaitTimeout  = 10 * time.Minute
+	defaultRetries      = 3
+)
+
+// isTransientError reports whether a request failed in a way that is worth retrying.
+func isTransientError(err error) bool {
+	if errors.Is(err, ErrTransient) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryTransient calls fn until it succeeds, fails with a non transient error or the attempts are exhausted.
+// fn is sent again as is, so it must be idempotent, use retryAction for requests which are not.
+func (c *Client) retryTransient(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < defaultRetries; attempt++ {
+		if err = fn(); err == nil || !isTransientError(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.pollInterval):
+		}
+	}
+	return err
+}
+
+// retryAction sends a non idempotent request with fn and retries it on transient errors like retryTransient.
+// A transient error does not tell whether the backend committed the request, so before sending it again
+// applied re-reads the resource and the request is only resent when it did not take effect.
+func (c *Client) retryAction(ctx context.Context, fn func() error, applied func() (bool, error)) error {
+	sent := false
+	return c.retryTransient(ctx, func() error {
+		if sent {
+			if ok, err := applied(); err != nil || ok {
+				return err
+			}
+		}
+		sent = true
+		return fn()
+	})
+}
+
+// waitFor polls check every poll interval until it reports done, returns an error or the context expires.
+// When the context has no deadline, waiting is bounded by defaultWaitTimeout.
+func (c *Client) waitFor(ctx context.Context, check func() (bool, error)) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultWaitTimeout)
+		defer cancel()
+	}
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+	for {
+		done, err := check()
+		if err != nil && !isTransientError(err) {
+			return err
+		}
+		if done {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
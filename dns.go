@@ -4,6 +4,7 @@ package gobizfly
 
 import (
 	"context"
+	"io"
 	"strings"
 )
 
@@ -24,10 +25,13 @@ type DNSService interface {
 	CreateZone(ctx context.Context, czpl *CreateZonePayload) (*ExtendedZone, error)
 	GetZone(ctx context.Context, zoneID string) (*ExtendedZone, error)
 	DeleteZone(ctx context.Context, zoneID string) error
-	CreateRecord(ctx context.Context, zoneID string, crpl interface{}) (*Record, error)
+	CreateRecord(ctx context.Context, zoneID string, crpl RecordPayload) (*Record, error)
 	GetRecord(ctx context.Context, recordID string) (*Record, error)
-	UpdateRecord(ctx context.Context, recordID string, urpl interface{}) (*Record, error)
+	UpdateRecord(ctx context.Context, recordID string, urpl RecordPayload) (*Record, error)
 	DeleteRecord(ctx context.Context, recordID string) error
+	ExportZone(ctx context.Context, zoneID string) (io.Reader, error)
+	ImportZone(ctx context.Context, zoneID string, zoneFile io.Reader, opts ImportOptions) (*ZoneImportResult, error)
+	SyncRecords(ctx context.Context, zoneID string, desired []RecordSpec, opts SyncOptions) (*RecordSyncResult, error)
 }
 
 func (d dnsService) resourcePath() string {
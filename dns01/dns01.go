@@ -0,0 +1,328 @@
+// This file is part of gobizfly
+
+// Package dns01 solves ACME DNS-01 challenges with Bizfly Cloud DNS. Provider implements the
+// Present and CleanUp methods of the challenge provider interface used by ACME clients such as lego.
+package dns01
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bizflycloud/gobizfly"
+)
+
+const (
+	challengeLabel            = "_acme-challenge"
+	defaultTTL                = 60
+	defaultPropagationTimeout = 2 * time.Minute
+	defaultPollingInterval    = 5 * time.Second
+)
+
+// DNSAPI is the part of the DNS service used by the provider.
+type DNSAPI interface {
+	ListZones(ctx context.Context, opts *gobizfly.ListOptions) (*gobizfly.ListZoneResp, error)
+	GetZone(ctx context.Context, zoneID string) (*gobizfly.ExtendedZone, error)
+	CreateRecord(ctx context.Context, zoneID string, crpl gobizfly.RecordPayload) (*gobizfly.Record, error)
+	UpdateRecord(ctx context.Context, recordID string, urpl gobizfly.RecordPayload) (*gobizfly.Record, error)
+	DeleteRecord(ctx context.Context, recordID string) error
+}
+
+// Resolver looks up the TXT records of name on a given name server.
+type Resolver interface {
+	LookupTXT(ctx context.Context, nameserver, name string) ([]string, error)
+}
+
+// Provider presents DNS-01 challenges as TXT records in the Bizfly DNS zone of the domain. The challenges
+// of a domain and of its wildcard share a TXT record set, so Present and CleanUp add and remove a single
+// value of the set, one call at a time.
+type Provider struct {
+	DNS DNSAPI
+	// Resolver checks that the name servers of the zone serve the challenge, it defaults to
+	// querying them directly on port 53.
+	Resolver Resolver
+	// TTL of the challenge records, it defaults to 60 seconds.
+	TTL int
+	// WaitForPropagation makes Present return once every name server of the zone serves the record.
+	WaitForPropagation bool
+	// PropagationTimeout and PollingInterval bound the propagation check, they default to 2 minutes and 5 seconds.
+	PropagationTimeout time.Duration
+	PollingInterval    time.Duration
+
+	mu sync.Mutex
+	// zones caches the zone of each challenge record name.
+	zones map[string]*gobizfly.Zone
+}
+
+// New creates a provider using the DNS service of client.
+func New(client *gobizfly.Client) *Provider {
+	return &Provider{DNS: client.DNS}
+}
+
+// ChallengeRecord returns the fully qualified name and the value of the TXT record solving the
+// challenge of domain. Wildcard domains share the record of their base domain.
+func ChallengeRecord(domain, keyAuth string) (fqdn, value string) {
+	domain = strings.TrimSuffix(strings.TrimPrefix(domain, "*."), ".")
+	sum := sha256.Sum256([]byte(keyAuth))
+	return challengeLabel + "." + strings.ToLower(domain) + ".", base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Timeout returns the propagation timeout and polling interval of the provider.
+func (p *Provider) Timeout() (timeout, interval time.Duration) {
+	return p.propagationTimeout(), p.pollingInterval()
+}
+
+func (p *Provider) propagationTimeout() time.Duration {
+	if p.PropagationTimeout > 0 {
+		return p.PropagationTimeout
+	}
+	return defaultPropagationTimeout
+}
+
+func (p *Provider) pollingInterval() time.Duration {
+	if p.PollingInterval > 0 {
+		return p.PollingInterval
+	}
+	return defaultPollingInterval
+}
+
+// Present adds the value of the challenge to its TXT record set.
+func (p *Provider) Present(domain, token, keyAuth string) error {
+	return p.PresentContext(context.Background(), domain, token, keyAuth)
+}
+
+// CleanUp removes the value added by Present.
+func (p *Provider) CleanUp(domain, token, keyAuth string) error {
+	return p.CleanUpContext(context.Background(), domain, token, keyAuth)
+}
+
+// PresentContext adds the value of the challenge to the TXT record set at the challenge name, in the zone
+// with the longest name matching the domain, creating the set when there is none. It waits for the name
+// servers of the zone to serve the value when WaitForPropagation is set.
+func (p *Provider) PresentContext(ctx context.Context, domain, token, keyAuth string) error {
+	fqdn, value := ChallengeRecord(domain, keyAuth)
+	zone, err := p.present(ctx, fqdn, value)
+	if err != nil {
+		return err
+	}
+	if !p.WaitForPropagation {
+		return nil
+	}
+	return p.waitForPropagation(ctx, zone.NameServer, fqdn, value)
+}
+
+func (p *Provider) present(ctx context.Context, fqdn, value string) (*gobizfly.Zone, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	zone, err := p.zone(ctx, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	records, err := p.txtRecords(ctx, zone, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		if hasValue(records[i].AsTXT(), value) {
+			return zone, nil
+		}
+	}
+	if len(records) > 0 {
+		record := &records[0]
+		if err := p.setValues(ctx, record, append(record.AsTXT(), value)); err != nil {
+			return nil, fmt.Errorf("update %s TXT record: %w", fqdn, err)
+		}
+		return zone, nil
+	}
+	ttl := p.TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	if _, err := p.DNS.CreateRecord(ctx, zone.ID, gobizfly.NewTXTRecord(recordName(fqdn, zone.Name), ttl, value)); err != nil {
+		return nil, fmt.Errorf("create %s TXT record: %w", fqdn, err)
+	}
+	return zone, nil
+}
+
+// CleanUpContext removes the value of the challenge from the TXT record sets at the challenge name, and
+// deletes a set once it holds no other value. Values of other challenges are kept.
+func (p *Provider) CleanUpContext(ctx context.Context, domain, token, keyAuth string) error {
+	fqdn, value := ChallengeRecord(domain, keyAuth)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	zone, err := p.zone(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	records, err := p.txtRecords(ctx, zone, fqdn)
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		record := &records[i]
+		values := record.AsTXT()
+		if !hasValue(values, value) {
+			continue
+		}
+		var others []string
+		for _, v := range values {
+			if v != value {
+				others = append(others, v)
+			}
+		}
+		if len(others) == 0 {
+			err = p.DNS.DeleteRecord(ctx, record.ID)
+		} else {
+			err = p.setValues(ctx, record, others)
+		}
+		if err != nil {
+			return fmt.Errorf("remove challenge from %s TXT record: %w", fqdn, err)
+		}
+	}
+	return nil
+}
+
+// zone returns the zone of a challenge record name, looking it up once.
+func (p *Provider) zone(ctx context.Context, fqdn string) (*gobizfly.Zone, error) {
+	if zone, ok := p.zones[fqdn]; ok {
+		return zone, nil
+	}
+	zone, err := p.findZone(ctx, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	if p.zones == nil {
+		p.zones = make(map[string]*gobizfly.Zone)
+	}
+	p.zones[fqdn] = zone
+	return zone, nil
+}
+
+// findZone returns the zone with the longest name which fqdn belongs to, reading every page of zones.
+func (p *Provider) findZone(ctx context.Context, fqdn string) (*gobizfly.Zone, error) {
+	name := strings.TrimSuffix(fqdn, ".")
+	var best *gobizfly.Zone
+	seen := make(map[string]bool)
+	for page := 1; ; page++ {
+		resp, err := p.DNS.ListZones(ctx, &gobizfly.ListOptions{Page: page})
+		if err != nil {
+			return nil, err
+		}
+		added := 0
+		for i := range resp.Zones {
+			zone := &resp.Zones[i]
+			if seen[zone.ID] {
+				continue
+			}
+			seen[zone.ID] = true
+			added++
+			zoneName := strings.ToLower(strings.TrimSuffix(zone.Name, "."))
+			if name != zoneName && !strings.HasSuffix(name, "."+zoneName) {
+				continue
+			}
+			if best == nil || len(zoneName) > len(strings.TrimSuffix(best.Name, ".")) {
+				best = zone
+			}
+		}
+		// A page without new zones also ends the listing, in case the API ignores the page.
+		if added == 0 || len(seen) >= resp.Meta.Total {
+			break
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no zone found for %s: %w", fqdn, gobizfly.ErrNotFound)
+	}
+	return best, nil
+}
+
+// txtRecords returns the TXT record sets at fqdn.
+func (p *Provider) txtRecords(ctx context.Context, zone *gobizfly.Zone, fqdn string) ([]gobizfly.Record, error) {
+	extended, err := p.DNS.GetZone(ctx, zone.ID)
+	if err != nil {
+		return nil, err
+	}
+	name := recordName(fqdn, zone.Name)
+	var records []gobizfly.Record
+	for _, record := range extended.RecordsSet {
+		if record.Type == "TXT" && recordName(strings.ToLower(record.Name), zone.Name) == name {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// setValues replaces the values of a TXT record set.
+func (p *Provider) setValues(ctx context.Context, record *gobizfly.Record, values []string) error {
+	_, err := p.DNS.UpdateRecord(ctx, record.ID, &gobizfly.UpdateNormalRecordPayload{
+		BaseUpdateRecordPayload: gobizfly.BaseUpdateRecordPayload{Type: "TXT"},
+		Data:                    gobizfly.NewTXTRecord(record.Name, record.TTL, values...).Data,
+	})
+	return err
+}
+
+func hasValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// recordName returns fqdn relative to the zone.
+func recordName(fqdn, zoneName string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(fqdn, "."), "."+strings.ToLower(strings.TrimSuffix(zoneName, ".")))
+}
+
+// waitForPropagation polls every name server until it serves the value or the propagation timeout expires.
+func (p *Provider) waitForPropagation(ctx context.Context, nameServers []string, fqdn, value string) error {
+	ctx, cancel := context.WithTimeout(ctx, p.propagationTimeout())
+	defer cancel()
+	resolver := p.Resolver
+	if resolver == nil {
+		resolver = nameServerResolver{}
+	}
+	pending := append([]string(nil), nameServers...)
+	ticker := time.NewTicker(p.pollingInterval())
+	defer ticker.Stop()
+	for {
+		var left []string
+		for _, ns := range pending {
+			if !servesValue(ctx, resolver, ns, fqdn, value) {
+				left = append(left, ns)
+			}
+		}
+		if pending = left; len(pending) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s TXT record not served by %s: %w", fqdn, strings.Join(pending, ", "), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func servesValue(ctx context.Context, resolver Resolver, nameserver, fqdn, value string) bool {
+	values, err := resolver.LookupTXT(ctx, nameserver, fqdn)
+	return err == nil && hasValue(values, value)
+}
+
+// nameServerResolver queries a name server directly.
+type nameServerResolver struct{}
+
+func (nameServerResolver) LookupTXT(ctx context.Context, nameserver, name string) ([]string, error) {
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, net.JoinHostPort(strings.TrimSuffix(nameserver, "."), "53"))
+		},
+	}
+	return r.LookupTXT(ctx, name)
+}
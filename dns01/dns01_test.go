@@ -0,0 +1,217 @@
+// This file is part of gobizfly
+
+package dns01
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bizflycloud/gobizfly"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDNS keeps one TXT record set per name and lists zones two per page.
+type fakeDNS struct {
+	zones   []gobizfly.Zone
+	records map[string][]gobizfly.Record
+	created []*gobizfly.CreateNormalRecordPayload
+	updated []string
+	deleted []string
+}
+
+func (f *fakeDNS) ListZones(ctx context.Context, opts *gobizfly.ListOptions) (*gobizfly.ListZoneResp, error) {
+	start := (opts.Page - 1) * 2
+	if start > len(f.zones) {
+		start = len(f.zones)
+	}
+	end := start + 2
+	if end > len(f.zones) {
+		end = len(f.zones)
+	}
+	return &gobizfly.ListZoneResp{Zones: f.zones[start:end], Meta: gobizfly.Meta{MaxResults: 2, Total: len(f.zones), Page: opts.Page}}, nil
+}
+
+func (f *fakeDNS) GetZone(ctx context.Context, zoneID string) (*gobizfly.ExtendedZone, error) {
+	for _, zone := range f.zones {
+		if zone.ID == zoneID {
+			return &gobizfly.ExtendedZone{Zone: zone, RecordsSet: append([]gobizfly.Record(nil), f.records[zoneID]...)}, nil
+		}
+	}
+	return nil, gobizfly.ErrNotFound
+}
+
+func (f *fakeDNS) CreateRecord(ctx context.Context, zoneID string, crpl gobizfly.RecordPayload) (*gobizfly.Record, error) {
+	if err := crpl.Validate(); err != nil {
+		return nil, err
+	}
+	payload := crpl.(*gobizfly.CreateNormalRecordPayload)
+	for _, record := range f.records[zoneID] {
+		if record.Name == payload.Name && record.Type == payload.Type {
+			return nil, fmt.Errorf("record set exists: %w", gobizfly.ErrCommon)
+		}
+	}
+	f.created = append(f.created, payload)
+	record := gobizfly.Record{ID: "rec-" + payload.Name, ZoneID: zoneID, Name: payload.Name, Type: payload.Type, TTL: payload.TTL}
+	for _, value := range payload.Data {
+		record.Data = append(record.Data, value)
+	}
+	f.records[zoneID] = append(f.records[zoneID], record)
+	return &record, nil
+}
+
+func (f *fakeDNS) UpdateRecord(ctx context.Context, recordID string, urpl gobizfly.RecordPayload) (*gobizfly.Record, error) {
+	if err := urpl.Validate(); err != nil {
+		return nil, err
+	}
+	payload := urpl.(*gobizfly.UpdateNormalRecordPayload)
+	for zoneID, records := range f.records {
+		for i := range records {
+			if records[i].ID == recordID {
+				records[i].Data = nil
+				for _, value := range payload.Data {
+					records[i].Data = append(records[i].Data, value)
+				}
+				f.updated = append(f.updated, fmt.Sprintf("%s %v", recordID, payload.Data))
+				f.records[zoneID] = records
+				return &records[i], nil
+			}
+		}
+	}
+	return nil, gobizfly.ErrNotFound
+}
+
+func (f *fakeDNS) DeleteRecord(ctx context.Context, recordID string) error {
+	f.deleted = append(f.deleted, recordID)
+	for zoneID, records := range f.records {
+		for i := range records {
+			if records[i].ID == recordID {
+				f.records[zoneID] = append(records[:i:i], records[i+1:]...)
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// fakeResolver serves the TXT value on a name server after a number of lookups.
+type fakeResolver struct {
+	value   string
+	after   map[string]int
+	lookups map[string]int
+}
+
+func (f *fakeResolver) LookupTXT(ctx context.Context, nameserver, name string) ([]string, error) {
+	f.lookups[nameserver]++
+	if f.lookups[nameserver] <= f.after[nameserver] {
+		return nil, errors.New("no such host")
+	}
+	return []string{"other", f.value}, nil
+}
+
+const testValue = "61rBZ_4knHblO0MNoxFsXZ_eTFUHum0B6IVRbhvUn5I"
+
+func newTestProvider() (*Provider, *fakeDNS) {
+	f := &fakeDNS{
+		zones: []gobizfly.Zone{
+			{ID: "zone-example", Name: "example.com", NameServer: []string{"ns4.bizflycloud.vn.", "ns5.bizflycloud.vn."}},
+			{ID: "zone-sub", Name: "sub.example.com", NameServer: []string{"ns4.bizflycloud.vn.", "ns5.bizflycloud.vn."}},
+			{ID: "zone-other", Name: "ample.com"},
+			{ID: "zone-last", Name: "example.net", NameServer: []string{"ns4.bizflycloud.vn."}},
+		},
+		records: map[string][]gobizfly.Record{},
+	}
+	return &Provider{DNS: f, PollingInterval: time.Millisecond}, f
+}
+
+func TestChallengeRecord(t *testing.T) {
+	fqdn, value := ChallengeRecord("*.Example.com", "token.thumbprint")
+	assert.Equal(t, "_acme-challenge.example.com.", fqdn)
+	assert.Equal(t, testValue, value)
+}
+
+func TestPresentAndCleanUp(t *testing.T) {
+	p, f := newTestProvider()
+	resolver := &fakeResolver{value: testValue, after: map[string]int{"ns5.bizflycloud.vn.": 2}, lookups: map[string]int{}}
+	p.Resolver = resolver
+	p.WaitForPropagation = true
+
+	require.NoError(t, p.Present("www.sub.example.com", "token", "token.thumbprint"))
+	require.Len(t, f.created, 1)
+	assert.Equal(t, "_acme-challenge.www", f.created[0].Name)
+	assert.Equal(t, "TXT", f.created[0].Type)
+	assert.Equal(t, 60, f.created[0].TTL)
+	assert.Equal(t, []string{testValue}, f.created[0].Data)
+	assert.Equal(t, map[string]int{"ns4.bizflycloud.vn.": 1, "ns5.bizflycloud.vn.": 3}, resolver.lookups)
+
+	require.NoError(t, p.Present("example.com", "token", "token.thumbprint"))
+	assert.Equal(t, "_acme-challenge", f.created[1].Name)
+
+	// Presenting the same challenge again leaves the record set as is.
+	require.NoError(t, p.Present("example.com", "token", "token.thumbprint"))
+	assert.Len(t, f.created, 2)
+	assert.Empty(t, f.updated)
+
+	require.NoError(t, p.CleanUp("www.sub.example.com", "token", "token.thumbprint"))
+	assert.Equal(t, []string{"rec-_acme-challenge.www"}, f.deleted)
+}
+
+func TestPresentWildcardAndBaseDomain(t *testing.T) {
+	p, f := newTestProvider()
+
+	// An order for example.com and *.example.com has two challenges at the same name.
+	require.NoError(t, p.Present("example.com", "token", "token.base"))
+	require.NoError(t, p.Present("*.example.com", "token", "token.wildcard"))
+	_, base := ChallengeRecord("example.com", "token.base")
+	_, wildcard := ChallengeRecord("*.example.com", "token.wildcard")
+	require.Len(t, f.created, 1)
+	assert.Equal(t, []string{"rec-_acme-challenge [" + base + " " + wildcard + "]"}, f.updated)
+
+	require.NoError(t, p.CleanUp("example.com", "token", "token.base"))
+	assert.Empty(t, f.deleted)
+	require.Len(t, f.records["zone-example"], 1)
+	assert.Equal(t, []string{wildcard}, f.records["zone-example"][0].AsTXT())
+
+	require.NoError(t, p.CleanUp("*.example.com", "token", "token.wildcard"))
+	assert.Equal(t, []string{"rec-_acme-challenge"}, f.deleted)
+	assert.Empty(t, f.records["zone-example"])
+}
+
+func TestFindZoneOnLaterPage(t *testing.T) {
+	p, f := newTestProvider()
+	require.NoError(t, p.Present("www.example.net", "token", "token.thumbprint"))
+	require.Len(t, f.created, 1)
+	assert.Len(t, f.records["zone-last"], 1)
+}
+
+func TestCleanUpAfterRestart(t *testing.T) {
+	p, f := newTestProvider()
+	f.records["zone-example"] = []gobizfly.Record{
+		{ID: "shared", Name: "_acme-challenge.www", Type: "TXT", Data: []interface{}{testValue, "other"}},
+		{ID: "mine", Name: "_acme-challenge.www", Type: "TXT", Data: []interface{}{`"` + testValue + `"`}},
+	}
+	require.NoError(t, p.CleanUp("www.example.com", "token", "token.thumbprint"))
+	assert.Equal(t, []string{"shared [other]"}, f.updated)
+	assert.Equal(t, []string{"mine"}, f.deleted)
+
+	f.deleted = nil
+	require.NoError(t, p.CleanUp("api.example.com", "token", "token.thumbprint"))
+	assert.Empty(t, f.deleted)
+}
+
+func TestPresentErrors(t *testing.T) {
+	p, f := newTestProvider()
+	err := p.Present("example.org", "token", "token.thumbprint")
+	assert.True(t, errors.Is(err, gobizfly.ErrNotFound))
+	assert.Empty(t, f.created)
+
+	p.Resolver = &fakeResolver{value: testValue, after: map[string]int{"ns4.bizflycloud.vn.": 1000}, lookups: map[string]int{}}
+	p.WaitForPropagation = true
+	p.PropagationTimeout = 20 * time.Millisecond
+	err = p.Present("www.example.com", "token", "token.thumbprint")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Contains(t, err.Error(), "ns4.bizflycloud.vn.")
+}
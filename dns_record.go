@@ -97,7 +97,10 @@ type Records struct {
 }
 
 // CreateRecord - Creates a DNS record.
-func (d *dnsService) CreateRecord(ctx context.Context, zoneID string, crpl interface{}) (*Record, error) {
+func (d *dnsService) CreateRecord(ctx context.Context, zoneID string, crpl RecordPayload) (*Record, error) {
+	if err := crpl.Validate(); err != nil {
+		return nil, err
+	}
 	payload := WrappedRecordPayload{
 		Record: crpl,
 	}
@@ -144,7 +147,10 @@ func (d *dnsService) GetRecord(ctx context.Context, recordID string) (*Record, e
 }
 
 // UpdateRecord - Update a DNS record
-func (d *dnsService) UpdateRecord(ctx context.Context, recordID string, urpl interface{}) (*Record, error) {
+func (d *dnsService) UpdateRecord(ctx context.Context, recordID string, urpl RecordPayload) (*Record, error) {
+	if err := urpl.Validate(); err != nil {
+		return nil, err
+	}
 	payload := WrappedRecordPayload{
 		Record: urpl,
 	}
@@ -0,0 +1,413 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+const maxRecordTTL = 1<<31 - 1
+
+// RecordPayload is a typed payload accepted by CreateRecord and UpdateRecord. It is implemented by
+// CreateNormalRecordPayload, CreateMXRecordPayload, CreateSRVRecordPayload and their Update counterparts.
+type RecordPayload interface {
+	Validate() error
+	recordPayload()
+}
+
+func (*CreateNormalRecordPayload) recordPayload() {}
+func (*CreateMXRecordPayload) recordPayload()     {}
+func (*CreateSRVRecordPayload) recordPayload()    {}
+func (*UpdateNormalRecordPayload) recordPayload() {}
+func (*UpdateMXRecordPayload) recordPayload()     {}
+func (*UpdateSRVRecordPayload) recordPayload()    {}
+
+// NewARecord returns the payload of an A record.
+func NewARecord(name string, ttl int, addrs ...netip.Addr) *CreateNormalRecordPayload {
+	data := make([]string, len(addrs))
+	for i, addr := range addrs {
+		data[i] = addr.Unmap().String()
+	}
+	return newNormalRecord(name, "A", ttl, data)
+}
+
+// NewAAAARecord returns the payload of an AAAA record.
+func NewAAAARecord(name string, ttl int, addrs ...netip.Addr) *CreateNormalRecordPayload {
+	data := make([]string, len(addrs))
+	for i, addr := range addrs {
+		data[i] = addr.String()
+	}
+	return newNormalRecord(name, "AAAA", ttl, data)
+}
+
+// NewCNAMERecord returns the payload of a CNAME record.
+func NewCNAMERecord(name string, ttl int, target string) *CreateNormalRecordPayload {
+	return newNormalRecord(name, "CNAME", ttl, []string{absoluteHost(target)})
+}
+
+// NewNSRecord returns the payload of an NS record delegating name to hosts.
+func NewNSRecord(name string, ttl int, hosts ...string) *CreateNormalRecordPayload {
+	data := make([]string, len(hosts))
+	for i, host := range hosts {
+		data[i] = absoluteHost(host)
+	}
+	return newNormalRecord(name, "NS", ttl, data)
+}
+
+// NewTXTRecord returns the payload of a TXT record. Values longer than 255 bytes are split into
+// quoted character strings.
+func NewTXTRecord(name string, ttl int, values ...string) *CreateNormalRecordPayload {
+	data := make([]string, len(values))
+	for i, value := range values {
+		data[i] = chunkTXT(value)
+	}
+	return newNormalRecord(name, "TXT", ttl, data)
+}
+
+// NewCAARecord returns the payload of a CAA record, e.g. NewCAARecord("@", 3600, 0, "issue", "letsencrypt.org").
+func NewCAARecord(name string, ttl int, flags uint8, tag, value string) *CreateNormalRecordPayload {
+	return newNormalRecord(name, "CAA", ttl, []string{fmt.Sprintf("%d %s %s", flags, strings.ToLower(tag), quoteZoneString(value))})
+}
+
+// NewMXRecord returns the payload of an MX record.
+func NewMXRecord(name string, ttl int, mx ...MXData) *CreateMXRecordPayload {
+	return &CreateMXRecordPayload{
+		BaseCreateRecordPayload: BaseCreateRecordPayload{Name: name, Type: "MX", TTL: ttl},
+		Data:                    mx,
+	}
+}
+
+// NewSRVRecord returns the payload of an SRV record.
+func NewSRVRecord(name string, ttl int, srv ...SRVData) *CreateSRVRecordPayload {
+	return &CreateSRVRecordPayload{
+		BaseCreateRecordPayload: BaseCreateRecordPayload{Name: name, Type: "SRV", TTL: ttl},
+		Data:                    srv,
+	}
+}
+
+func newNormalRecord(name, recordType string, ttl int, data []string) *CreateNormalRecordPayload {
+	return &CreateNormalRecordPayload{
+		BaseCreateRecordPayload: BaseCreateRecordPayload{Name: name, Type: recordType, TTL: ttl},
+		Data:                    data,
+	}
+}
+
+// Validate checks the name, TTL and data of the record.
+func (p *CreateNormalRecordPayload) Validate() error {
+	if err := p.BaseCreateRecordPayload.validate(); err != nil {
+		return err
+	}
+	return validateRecordValues(p.Type, p.Data)
+}
+
+// Validate checks the name, TTL and data of the MX record.
+func (p *CreateMXRecordPayload) Validate() error {
+	if err := p.BaseCreateRecordPayload.validate(); err != nil {
+		return err
+	}
+	return validateMXRecord(p.Type, p.Data)
+}
+
+// Validate checks the name, TTL and data of the SRV record.
+func (p *CreateSRVRecordPayload) Validate() error {
+	if err := p.BaseCreateRecordPayload.validate(); err != nil {
+		return err
+	}
+	return validateSRVRecord(p.Type, p.Data)
+}
+
+// Validate checks the fields set on the update and the data of the record.
+func (p *UpdateNormalRecordPayload) Validate() error {
+	if err := p.BaseUpdateRecordPayload.validate(); err != nil {
+		return err
+	}
+	return validateRecordValues(p.Type, p.Data)
+}
+
+// Validate checks the fields set on the update and the data of the MX record.
+func (p *UpdateMXRecordPayload) Validate() error {
+	if err := p.BaseUpdateRecordPayload.validate(); err != nil {
+		return err
+	}
+	return validateMXRecord(p.Type, p.Data)
+}
+
+// Validate checks the fields set on the update and the data of the SRV record.
+func (p *UpdateSRVRecordPayload) Validate() error {
+	if err := p.BaseUpdateRecordPayload.validate(); err != nil {
+		return err
+	}
+	return validateSRVRecord(p.Type, p.Data)
+}
+
+// recordError is an invalid record payload. It wraps ErrCommon and keeps the reason on its own, so that
+// zone file imports can report it.
+type recordError struct {
+	reason string
+}
+
+func invalidRecord(format string, args ...interface{}) error {
+	return &recordError{reason: fmt.Sprintf(format, args...)}
+}
+
+func (e *recordError) Error() string {
+	return e.reason + ": " + ErrCommon.Error()
+}
+
+func (e *recordError) Unwrap() error {
+	return ErrCommon
+}
+
+func (p *BaseCreateRecordPayload) validate() error {
+	if p.Type == "" {
+		return invalidRecord("record type is required")
+	}
+	if err := validateRecordName(p.Name); err != nil {
+		return err
+	}
+	return validateRecordTTL(p.TTL)
+}
+
+// validate checks the fields set on the update, the others are left unchanged by the API.
+func (p *BaseUpdateRecordPayload) validate() error {
+	if p.Name != "" {
+		if err := validateRecordName(p.Name); err != nil {
+			return err
+		}
+	}
+	if p.TTL != 0 {
+		return validateRecordTTL(p.TTL)
+	}
+	return nil
+}
+
+func validateRecordTTL(ttl int) error {
+	if ttl < 1 || ttl > maxRecordTTL {
+		return invalidRecord("record TTL must be between 1 and %d, got %d", maxRecordTTL, ttl)
+	}
+	return nil
+}
+
+// validateRecordName checks a record name, which may be "@" for the apex or start with a "*" wildcard label.
+func validateRecordName(name string) error {
+	if name == "@" || name == "*" {
+		return nil
+	}
+	return validateHostname(strings.TrimPrefix(name, "*."))
+}
+
+// validateHostname checks the length and characters of a domain name. Underscores are allowed for
+// service labels such as _acme-challenge.
+func validateHostname(host string) error {
+	name := strings.TrimSuffix(host, ".")
+	if name == "" || len(name) > 253 {
+		return invalidRecord("invalid host name %q", host)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return invalidRecord("invalid host name %q", host)
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i] | 0x20
+			if (c < 'a' || c > 'z') && (label[i] < '0' || label[i] > '9') && label[i] != '-' && label[i] != '_' {
+				return invalidRecord("invalid host name %q", host)
+			}
+		}
+	}
+	return nil
+}
+
+// validateRecordValues checks the data of a normal record. The type may be empty on updates,
+// in which case the values can not be checked.
+func validateRecordValues(recordType string, values []string) error {
+	switch recordType {
+	case "MX", "SRV":
+		return invalidRecord("%s records need a %s payload", recordType, recordType)
+	case "CNAME":
+		if len(values) > 1 {
+			return invalidRecord("a CNAME record holds a single value")
+		}
+	}
+	if len(values) == 0 {
+		return invalidRecord("record data is required")
+	}
+	for _, value := range values {
+		if err := validateRecordValue(recordType, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateRecordValue(recordType, value string) error {
+	switch recordType {
+	case "A", "AAAA":
+		addr, err := netip.ParseAddr(value)
+		if err != nil || (recordType == "A") != addr.Is4() {
+			return invalidRecord("invalid %s address %q", recordType, value)
+		}
+	case "CNAME", "NS", "PTR":
+		return validateHostname(value)
+	case "TXT":
+		chunks, ok := txtChunks(value)
+		if !ok {
+			return invalidRecord("invalid TXT value %q", value)
+		}
+		for _, chunk := range chunks {
+			if len(chunk) > txtChunkSize {
+				return invalidRecord("TXT character strings are limited to %d bytes, split the value with NewTXTRecord", txtChunkSize)
+			}
+		}
+	case "CAA":
+		fields := strings.SplitN(value, " ", 3)
+		if len(fields) != 3 || fields[1] == "" || fields[2] == "" {
+			return invalidRecord("invalid CAA value %q", value)
+		}
+		if _, err := strconv.ParseUint(fields[0], 10, 8); err != nil {
+			return invalidRecord("invalid CAA flags %q", fields[0])
+		}
+	}
+	return nil
+}
+
+func validateMXRecord(recordType string, data []MXData) error {
+	if recordType != "" && recordType != "MX" {
+		return invalidRecord("MX payloads can not hold %s records", recordType)
+	}
+	if len(data) == 0 {
+		return invalidRecord("record data is required")
+	}
+	for _, mx := range data {
+		if mx.Priority < 0 || mx.Priority > 65535 {
+			return invalidRecord("invalid MX priority %d", mx.Priority)
+		}
+		if err := validateHostname(mx.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateSRVRecord(recordType string, data []SRVData) error {
+	if recordType != "" && recordType != "SRV" {
+		return invalidRecord("SRV payloads can not hold %s records", recordType)
+	}
+	if len(data) == 0 {
+		return invalidRecord("record data is required")
+	}
+	for _, srv := range data {
+		for _, v := range []int{srv.Priority, srv.Weight, srv.Port} {
+			if v < 0 || v > 65535 {
+				return invalidRecord("SRV priority, weight and port must be between 0 and 65535, got %d", v)
+			}
+		}
+		if err := validateHostname(srvLabel(srv.Service) + "." + srvLabel(srv.Protocol)); err != nil {
+			return err
+		}
+		// A target of "." means the service is not available.
+		if srv.Target != "." {
+			if err := validateHostname(srv.Target); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// chunkTXT splits a TXT value longer than 255 bytes into quoted character strings.
+func chunkTXT(value string) string {
+	if len(value) <= txtChunkSize && !strings.HasPrefix(value, `"`) {
+		return value
+	}
+	return quoteTXT(value)
+}
+
+// txtChunks returns the character strings of a TXT value. Values which do not start with a quote
+// are a single character string.
+func txtChunks(value string) ([]string, bool) {
+	if !strings.HasPrefix(value, `"`) {
+		return []string{value}, true
+	}
+	entries, err := scanZoneFile(strings.NewReader(value))
+	if err != nil || len(entries) != 1 {
+		return nil, false
+	}
+	chunks := make([]string, len(entries[0].tokens))
+	for i, tok := range entries[0].tokens {
+		if !tok.quoted {
+			return nil, false
+		}
+		chunks[i] = tok.text
+	}
+	return chunks, true
+}
+
+// decodeRecordData converts the untyped data of a record into out, which must be a pointer to a slice.
+func decodeRecordData(rec *Record, out interface{}) error {
+	buf, err := json.Marshal(rec.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, out)
+}
+
+// AsA returns the addresses of an A record, or nil for other record types. Invalid values are skipped.
+func (r *Record) AsA() []netip.Addr {
+	return r.addrs("A")
+}
+
+// AsAAAA returns the addresses of an AAAA record, or nil for other record types. Invalid values are skipped.
+func (r *Record) AsAAAA() []netip.Addr {
+	return r.addrs("AAAA")
+}
+
+func (r *Record) addrs(recordType string) []netip.Addr {
+	if r.Type != recordType {
+		return nil
+	}
+	addrs := make([]netip.Addr, 0, len(r.Data))
+	for _, item := range r.Data {
+		if addr, err := netip.ParseAddr(fmt.Sprint(item)); err == nil {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// AsMX returns the data of an MX record, or nil for other record types.
+func (r *Record) AsMX() []MXData {
+	var data []MXData
+	if r.Type != "MX" || decodeRecordData(r, &data) != nil {
+		return nil
+	}
+	return data
+}
+
+// AsSRV returns the data of an SRV record, or nil for other record types.
+func (r *Record) AsSRV() []SRVData {
+	var data []SRVData
+	if r.Type != "SRV" || decodeRecordData(r, &data) != nil {
+		return nil
+	}
+	return data
+}
+
+// AsTXT returns the values of a TXT record with character strings joined, or nil for other record types.
+func (r *Record) AsTXT() []string {
+	if r.Type != "TXT" {
+		return nil
+	}
+	values := make([]string, len(r.Data))
+	for i, item := range r.Data {
+		value := fmt.Sprint(item)
+		if chunks, ok := txtChunks(value); ok {
+			value = strings.Join(chunks, "")
+		}
+		values[i] = value
+	}
+	return values
+}
@@ -0,0 +1,83 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAccessors(t *testing.T) {
+	var records []Record
+	require.NoError(t, json.Unmarshal([]byte(`[
+    {"name": "www", "type": "A", "ttl": 300, "data": ["10.0.0.1", "bogus", "10.0.0.2"]},
+    {"name": "v6", "type": "AAAA", "ttl": 300, "data": ["2001:db8::1"]},
+    {"name": "mx", "type": "MX", "ttl": 300, "data": [{"value": "imap1.vccloud.vn", "priority": 20}]},
+    {"name": "sip", "type": "SRV", "ttl": 300, "data": [{"port": 5060, "priority": 10, "protocol": "_tcp", "service": "_sip", "target": "sip.example.com.", "weight": 5}]},
+    {"name": "dkim", "type": "TXT", "ttl": 300, "data": ["v=spf1 -all", "\"v=DKIM1; \" \"p=MIGf\""]}
+]`), &records))
+
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")}, records[0].AsA())
+	assert.Nil(t, records[0].AsAAAA())
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("2001:db8::1")}, records[1].AsAAAA())
+	assert.Equal(t, []MXData{{Value: "imap1.vccloud.vn", Priority: 20}}, records[2].AsMX())
+	assert.Nil(t, records[2].AsSRV())
+	assert.Equal(t, []SRVData{{Port: 5060, Priority: 10, Protocol: "_tcp", Service: "_sip", Target: "sip.example.com.", Weight: 5}}, records[3].AsSRV())
+	assert.Equal(t, []string{"v=spf1 -all", "v=DKIM1; p=MIGf"}, records[4].AsTXT())
+}
+
+func TestRecordPayloadConstructors(t *testing.T) {
+	a := NewARecord("www", 300, netip.MustParseAddr("::ffff:10.0.0.1"))
+	require.NoError(t, a.Validate())
+	assert.Equal(t, []string{"10.0.0.1"}, a.Data)
+
+	cname := NewCNAMERecord("api", 300, "www.example.com")
+	require.NoError(t, cname.Validate())
+	assert.Equal(t, []string{"www.example.com."}, cname.Data)
+
+	long := strings.Repeat("a", 300)
+	txt := NewTXTRecord("dkim._domainkey", 300, "v=spf1 -all", long)
+	require.NoError(t, txt.Validate())
+	assert.Equal(t, "v=spf1 -all", txt.Data[0])
+	assert.Equal(t, `"`+long[:255]+`" "`+long[255:]+`"`, txt.Data[1])
+
+	caa := NewCAARecord("@", 3600, 0, "ISSUE", "letsencrypt.org")
+	require.NoError(t, caa.Validate())
+	assert.Equal(t, []string{`0 issue "letsencrypt.org"`}, caa.Data)
+
+	require.NoError(t, NewMXRecord("@", 300, MXData{Value: "mail.example.com.", Priority: 10}).Validate())
+	require.NoError(t, NewSRVRecord("@", 300, SRVData{Service: "_sip", Protocol: "_tcp", Port: 5060, Target: "."}).Validate())
+	require.NoError(t, NewNSRecord("sub", 300, "ns1.example.net").Validate())
+}
+
+func TestRecordPayloadValidate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	for name, payload := range map[string]RecordPayload{
+		"ttl":            NewARecord("www", 0, netip.MustParseAddr("10.0.0.1")),
+		"ipv6 in A":      NewARecord("www", 300, netip.MustParseAddr("2001:db8::1")),
+		"no data":        NewAAAARecord("www", 300),
+		"name":           NewCNAMERecord("bad name", 300, "www.example.com"),
+		"hostname":       NewCNAMERecord("api", 300, "-www.example.com"),
+		"txt chunk":      &CreateNormalRecordPayload{BaseCreateRecordPayload: BaseCreateRecordPayload{Name: "x", Type: "TXT", TTL: 300}, Data: []string{strings.Repeat("a", 256)}},
+		"caa":            &CreateNormalRecordPayload{BaseCreateRecordPayload: BaseCreateRecordPayload{Name: "@", Type: "CAA", TTL: 300}, Data: []string{"256 issue x"}},
+		"mx as normal":   &CreateNormalRecordPayload{BaseCreateRecordPayload: BaseCreateRecordPayload{Name: "@", Type: "MX", TTL: 300}, Data: []string{"10 mail"}},
+		"mx priority":    NewMXRecord("@", 300, MXData{Value: "mail.example.com", Priority: 70000}),
+		"srv port":       NewSRVRecord("@", 300, SRVData{Service: "_sip", Protocol: "_tcp", Port: -1, Target: "sip.example.com"}),
+		"update ttl":     &UpdateMXRecordPayload{BaseUpdateRecordPayload: BaseUpdateRecordPayload{TTL: -5}, Data: []MXData{{Value: "mail.example.com"}}},
+		"update no data": &UpdateNormalRecordPayload{BaseUpdateRecordPayload: BaseUpdateRecordPayload{Type: "A"}},
+	} {
+		err := payload.Validate()
+		assert.True(t, errors.Is(err, ErrCommon), name)
+	}
+
+	_, err := client.DNS.CreateRecord(ctx, "zone", NewARecord("www", -1, netip.MustParseAddr("10.0.0.1")))
+	assert.True(t, errors.Is(err, ErrCommon))
+}
@@ -0,0 +1,304 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+const (
+	// recordOwnerPrefix names the TXT records marking record sets managed by SyncRecords.
+	recordOwnerPrefix = "_gobizfly"
+	recordOwnerValue  = "heritage=gobizfly,owner="
+)
+
+// RecordSpec is the desired state of a record set. Values holds the data of A, AAAA, CNAME, NS,
+// TXT and CAA records, MX and SRV hold the data of MX and SRV records.
+type RecordSpec struct {
+	Name   string    `json:"name" yaml:"name"`
+	Type   string    `json:"type" yaml:"type"`
+	TTL    int       `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	Values []string  `json:"values,omitempty" yaml:"values,omitempty"`
+	MX     []MXData  `json:"mx,omitempty" yaml:"mx,omitempty"`
+	SRV    []SRVData `json:"srv,omitempty" yaml:"srv,omitempty"`
+}
+
+// SyncOptions controls how SyncRecords reconciles a zone.
+type SyncOptions struct {
+	// Owner identifies the record sets managed by this caller. Each managed record set is marked
+	// with a TXT record, and record sets without the marker of this owner are never changed.
+	Owner string
+	// DryRun computes the plan without applying it.
+	DryRun bool
+	// Output, when set, receives the plan before any change is applied.
+	Output io.Writer
+}
+
+// RecordSyncChange describes a record set change made, or planned, by SyncRecords.
+type RecordSyncChange struct {
+	// Action is one of create, update, delete or conflict. Conflict marks a desired record set
+	// which already exists in the zone without the ownership marker and is left untouched.
+	Action string   `json:"action"`
+	ID     string   `json:"id,omitempty"`
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	TTL    int      `json:"ttl"`
+	Data   []string `json:"data"`
+
+	set *zoneRecordSet
+}
+
+// RecordSyncResult is the plan computed by SyncRecords.
+type RecordSyncResult struct {
+	Changes []RecordSyncChange `json:"changes"`
+}
+
+// String renders the plan, one change per line.
+func (r *RecordSyncResult) String() string {
+	symbols := map[string]string{"create": "+", "update": "~", "delete": "-", "conflict": "!"}
+	var b strings.Builder
+	for _, c := range r.Changes {
+		_, _ = fmt.Fprintf(&b, "%s %s %s %s %d %s\n", symbols[c.Action], c.Action, c.Name, c.Type, c.TTL, strings.Join(c.Data, ", "))
+	}
+	if len(r.Changes) == 0 {
+		b.WriteString("no changes\n")
+	}
+	return b.String()
+}
+
+// SyncRecords reconciles the record sets of a zone owned by opts.Owner with desired.
+// Owned record sets missing from desired are deleted. When a request fails, the returned
+// result lists the changes applied before the failure.
+func (d *dnsService) SyncRecords(ctx context.Context, zoneID string, desired []RecordSpec, opts SyncOptions) (*RecordSyncResult, error) {
+	if opts.Owner == "" || strings.ContainsAny(opts.Owner, " ,\"") {
+		return nil, fmt.Errorf("invalid owner %q: %w", opts.Owner, ErrCommon)
+	}
+	zone, err := d.GetZone(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+	sets := make([]*zoneRecordSet, 0, len(desired))
+	seen := make(map[string]bool, len(desired))
+	for i := range desired {
+		set, err := desired[i].recordSet(zone)
+		if err != nil {
+			return nil, err
+		}
+		key := zoneRecordKey(relativeRecordName(set.Name, zone.Name), set.Type)
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate record set %s %s: %w", set.Name, set.Type, ErrCommon)
+		}
+		seen[key] = true
+		sets = append(sets, set)
+	}
+
+	plan := planRecordSync(zone, sets, opts.Owner)
+	if opts.Output != nil {
+		_, _ = io.WriteString(opts.Output, plan.String())
+	}
+	if opts.DryRun {
+		return plan, nil
+	}
+	result := &RecordSyncResult{}
+	for _, change := range plan.Changes {
+		if change.Action != "conflict" {
+			if err := d.writeRecordSet(ctx, zoneID, change.Action, change.ID, change.set); err != nil {
+				return result, fmt.Errorf("%s %s %s record: %w", change.Action, change.Name, change.Type, err)
+			}
+		}
+		result.Changes = append(result.Changes, change)
+	}
+	return result, nil
+}
+
+// writeRecordSet creates, updates or deletes a record set. Creates are not retried, a create which
+// failed after the record set was added would add it twice.
+func (d *dnsService) writeRecordSet(ctx context.Context, zoneID, action, id string, set *zoneRecordSet) error {
+	if action == "create" {
+		_, err := d.CreateRecord(ctx, zoneID, set.createPayload())
+		return err
+	}
+	return d.client.retryTransient(ctx, func() error {
+		var err error
+		switch action {
+		case "update":
+			_, err = d.UpdateRecord(ctx, id, set.updatePayload())
+		case "delete":
+			err = d.DeleteRecord(ctx, id)
+		}
+		return err
+	})
+}
+
+// planRecordSync orders deletions first, deleting a record set before its marker, and creates the
+// marker before its record set, so an interrupted sync never leaves an unowned record set behind.
+func planRecordSync(zone *ExtendedZone, desired []*zoneRecordSet, owner string) *RecordSyncResult {
+	existing := make(map[string][]Record)
+	for _, rec := range zone.RecordsSet {
+		key := zoneRecordKey(relativeRecordName(rec.Name, zone.Name), rec.Type)
+		existing[key] = append(existing[key], rec)
+	}
+	marker := recordOwnerValue + owner
+	owned := func(rel, recordType string) bool {
+		for _, rec := range existing[zoneRecordKey(recordOwnerName(rel, recordType), "TXT")] {
+			if hasOwnerMarker(rec, marker) {
+				return true
+			}
+		}
+		return false
+	}
+	markerSet := func(rel string, set *zoneRecordSet) *zoneRecordSet {
+		return &zoneRecordSet{Name: recordOwnerName(rel, set.Type), Type: "TXT", TTL: set.TTL, data: []string{marker}, values: []string{marker}}
+	}
+
+	var deletes, updates, creates []RecordSyncChange
+	wanted := make(map[string]bool, len(desired))
+	for _, set := range desired {
+		rel := relativeRecordName(set.Name, zone.Name)
+		wanted[zoneRecordKey(rel, set.Type)] = true
+		change := RecordSyncChange{Name: set.Name, Type: set.Type, TTL: set.TTL, Data: set.values, set: set}
+		recs := existing[zoneRecordKey(rel, set.Type)]
+		isOwned := owned(rel, set.Type)
+		switch {
+		case len(recs) == 0:
+			if !isOwned {
+				m := markerSet(rel, set)
+				creates = append(creates, RecordSyncChange{Action: "create", Name: m.Name, Type: m.Type, TTL: m.TTL, Data: m.values, set: m})
+			}
+			change.Action = "create"
+			creates = append(creates, change)
+		case !isOwned:
+			change.Action, change.ID = "conflict", recs[0].ID
+			updates = append(updates, change)
+		case recs[0].TTL != set.TTL || !sameValues(zoneRecordValues(recs[0]), set.values):
+			change.Action, change.ID = "update", recs[0].ID
+			updates = append(updates, change)
+		}
+	}
+	for _, rec := range zone.RecordsSet {
+		rel := relativeRecordName(rec.Name, zone.Name)
+		if rec.Type == "TXT" || wanted[zoneRecordKey(rel, rec.Type)] {
+			continue
+		}
+		if owned(rel, rec.Type) {
+			deletes = append(deletes, RecordSyncChange{Action: "delete", ID: rec.ID, Name: rec.Name, Type: rec.Type, TTL: rec.TTL, Data: zoneRecordValues(rec)})
+		}
+	}
+	// Markers of deleted record sets, and of TXT record sets which have their own markers.
+	for _, rec := range zone.RecordsSet {
+		rel := relativeRecordName(rec.Name, zone.Name)
+		if rec.Type != "TXT" || !strings.HasPrefix(rel, recordOwnerPrefix+".") || !hasOwnerMarker(rec, marker) {
+			continue
+		}
+		recordType, name := recordOwnerTarget(rel)
+		if wanted[zoneRecordKey(name, recordType)] {
+			continue
+		}
+		if recordType == "TXT" {
+			for _, txt := range existing[zoneRecordKey(name, "TXT")] {
+				deletes = append(deletes, RecordSyncChange{Action: "delete", ID: txt.ID, Name: txt.Name, Type: txt.Type, TTL: txt.TTL, Data: zoneRecordValues(txt)})
+			}
+		}
+		deletes = append(deletes, RecordSyncChange{Action: "delete", ID: rec.ID, Name: rec.Name, Type: rec.Type, TTL: rec.TTL, Data: zoneRecordValues(rec)})
+	}
+	sortRecordDeletes(deletes)
+	return &RecordSyncResult{Changes: append(append(deletes, updates...), creates...)}
+}
+
+// hasOwnerMarker reports whether a TXT record carries the given ownership marker.
+func hasOwnerMarker(rec Record, marker string) bool {
+	for _, value := range rec.AsTXT() {
+		if value == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// sortRecordDeletes moves the deletion of ownership markers after the record sets they mark.
+func sortRecordDeletes(deletes []RecordSyncChange) {
+	sort.SliceStable(deletes, func(i, j int) bool {
+		return !strings.HasPrefix(deletes[i].Name, recordOwnerPrefix+".") && strings.HasPrefix(deletes[j].Name, recordOwnerPrefix+".")
+	})
+}
+
+// recordOwnerName returns the name of the TXT record marking the owner of a record set,
+// e.g. _gobizfly.cname.www for the CNAME record set www.
+func recordOwnerName(rel, recordType string) string {
+	name := recordOwnerPrefix + "." + strings.ToLower(recordType)
+	if rel != "@" {
+		name += "." + rel
+	}
+	return name
+}
+
+// recordOwnerTarget is the inverse of recordOwnerName.
+func recordOwnerTarget(markerName string) (recordType, rel string) {
+	parts := strings.SplitN(strings.TrimPrefix(markerName, recordOwnerPrefix+"."), ".", 2)
+	if len(parts) == 1 {
+		return strings.ToUpper(parts[0]), "@"
+	}
+	return strings.ToUpper(parts[0]), parts[1]
+}
+
+// recordSet validates and normalizes the spec against the zone.
+func (s *RecordSpec) recordSet(zone *ExtendedZone) (*zoneRecordSet, error) {
+	rel := relativeRecordName(s.Name, zone.Name)
+	recordType := strings.ToUpper(s.Type)
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("record set %s %s: %s: %w", s.Name, s.Type, fmt.Sprintf(format, args...), ErrCommon)
+	}
+	if strings.HasPrefix(rel, recordOwnerPrefix+".") || rel == recordOwnerPrefix {
+		return nil, invalid("names starting with %s are reserved", recordOwnerPrefix)
+	}
+	if managedZoneRecord(rel, recordType) {
+		return nil, invalid("record is managed by Bizfly DNS")
+	}
+	set := &zoneRecordSet{Name: apiRecordName(rel, zone.Name), Type: recordType, TTL: s.TTL}
+	if set.TTL <= 0 {
+		set.TTL = zone.TTL
+	}
+	if set.TTL <= 0 {
+		set.TTL = zoneFileDefaultTTL
+	}
+	switch recordType {
+	case "A", "AAAA", "CNAME", "NS", "TXT", "CAA", "MX", "SRV":
+	default:
+		return nil, invalid("record type is not supported")
+	}
+	// The data is normalized here and validated with the payload below.
+	for _, value := range s.Values {
+		switch recordType {
+		case "A", "AAAA":
+			if addr, err := netip.ParseAddr(value); err == nil {
+				value = addr.String()
+			}
+		case "CNAME", "NS":
+			value = absoluteHost(value)
+		}
+		set.values = append(set.values, canonicalValue(recordType, value))
+		if recordType == "TXT" {
+			value = chunkTXT(value)
+		}
+		set.data = append(set.data, value)
+	}
+	for _, mx := range s.MX {
+		mx.Value = absoluteHost(mx.Value)
+		set.mx = append(set.mx, mx)
+		set.values = append(set.values, mxValue(mx))
+	}
+	for _, srv := range s.SRV {
+		srv.Service, srv.Protocol, srv.Target = srvLabel(srv.Service), srvLabel(srv.Protocol), absoluteHost(srv.Target)
+		set.srv = append(set.srv, srv)
+		set.values = append(set.values, srvValue(srv))
+	}
+	if err := set.createPayload().Validate(); err != nil {
+		return nil, fmt.Errorf("record set %s %s: %w", s.Name, s.Type, err)
+	}
+	return set, nil
+}
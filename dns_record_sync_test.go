@@ -0,0 +1,166 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSyncZoneID = "48d6ce71-43ed-45d3-9ab3-747dd08f500f"
+
+func handleTestSyncZone(t *testing.T) {
+	var d dnsService
+	mux.HandleFunc(testlib.DNSURL(d.zoneItemPath(testSyncZoneID)), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, `{
+    "id": "48d6ce71-43ed-45d3-9ab3-747dd08f500f",
+    "name": "example.com",
+    "ttl": 3600,
+    "record_set": [
+        {"id": "ns", "name": "example.com", "type": "NS", "ttl": 3600, "data": ["ns4.bizflycloud.vn."]},
+        {"id": "www", "name": "www", "type": "A", "ttl": 300, "data": ["10.0.0.1"]},
+        {"id": "www-owner", "name": "_gobizfly.a.www", "type": "TXT", "ttl": 3600, "data": ["heritage=gobizfly,owner=infra"]},
+        {"id": "old", "name": "old", "type": "CNAME", "ttl": 300, "data": ["www.example.com."]},
+        {"id": "old-owner", "name": "_gobizfly.cname.old", "type": "TXT", "ttl": 3600, "data": ["\"heritage=gobizfly,owner=infra\""]},
+        {"id": "mail", "name": "example.com", "type": "MX", "ttl": 300, "data": [{"value": "mail.example.com", "priority": 10}]},
+        {"id": "blog", "name": "blog", "type": "A", "ttl": 300, "data": ["10.0.0.9"]},
+        {"id": "blog-owner", "name": "_gobizfly.a.blog", "type": "TXT", "ttl": 3600, "data": ["heritage=gobizfly,owner=other"]}
+    ]
+}`)
+	})
+}
+
+func testRecordSpecs() []RecordSpec {
+	return []RecordSpec{
+		{Name: "www", Type: "A", TTL: 300, Values: []string{"10.0.0.2", "10.0.0.1"}},
+		{Name: "api.example.com.", Type: "cname", Values: []string{"www.example.com"}},
+		{Name: "@", Type: "MX", TTL: 300, MX: []MXData{{Value: "mx.example.net", Priority: 5}}},
+	}
+}
+
+func TestSyncRecordsDryRun(t *testing.T) {
+	setup()
+	defer teardown()
+	handleTestSyncZone(t)
+
+	var out bytes.Buffer
+	result, err := client.DNS.SyncRecords(ctx, testSyncZoneID, testRecordSpecs(), SyncOptions{Owner: "infra", DryRun: true, Output: &out})
+	require.NoError(t, err)
+	assert.Equal(t, `- delete old CNAME 300 www.example.com.
+- delete _gobizfly.cname.old TXT 3600 heritage=gobizfly,owner=infra
+~ update www A 300 10.0.0.2, 10.0.0.1
+! conflict example.com MX 300 5 mx.example.net.
++ create _gobizfly.cname.api TXT 3600 heritage=gobizfly,owner=infra
++ create api CNAME 3600 www.example.com.
+`, out.String())
+	assert.Equal(t, out.String(), result.String())
+}
+
+func TestSyncRecords(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+	handleTestSyncZone(t)
+
+	var (
+		d        dnsService
+		requests []string
+	)
+	mux.HandleFunc(testlib.DNSURL(d.zoneItemPath(testSyncZoneID)+"/record"), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		var payload struct {
+			Record CreateNormalRecordPayload `json:"record"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		requests = append(requests, fmt.Sprintf("POST %s %s %d %v", payload.Record.Name, payload.Record.Type, payload.Record.TTL, payload.Record.Data))
+		_, _ = fmt.Fprint(w, `{"record": {"id": "new"}}`)
+	})
+	mux.HandleFunc(testlib.DNSURL(recordPath+"/"), func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, testlib.DNSURL(recordPath+"/"))
+		if r.Method != http.MethodPut {
+			requests = append(requests, r.Method+" "+id)
+			return
+		}
+		var payload struct {
+			Record UpdateNormalRecordPayload `json:"record"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		requests = append(requests, fmt.Sprintf("PUT %s %v", id, payload.Record.Data))
+		_, _ = fmt.Fprint(w, `{"id": "www"}`)
+	})
+
+	result, err := client.DNS.SyncRecords(ctx, testSyncZoneID, testRecordSpecs(), SyncOptions{Owner: "infra"})
+	require.NoError(t, err)
+	assert.Len(t, result.Changes, 6)
+	assert.Equal(t, []string{
+		"DELETE old",
+		"DELETE old-owner",
+		"PUT www [10.0.0.2 10.0.0.1]",
+		"POST _gobizfly.cname.api TXT 3600 [heritage=gobizfly,owner=infra]",
+		"POST api CNAME 3600 [www.example.com.]",
+	}, requests)
+}
+
+func TestSyncRecordsInvalid(t *testing.T) {
+	setup()
+	defer teardown()
+	handleTestSyncZone(t)
+
+	for _, tc := range []struct {
+		specs []RecordSpec
+		owner string
+	}{
+		{specs: testRecordSpecs(), owner: ""},
+		{specs: []RecordSpec{{Name: "www", Type: "A", Values: []string{"2001:db8::1"}}}, owner: "infra"},
+		{specs: []RecordSpec{{Name: "www", Type: "PTR", Values: []string{"host."}}}, owner: "infra"},
+		{specs: []RecordSpec{{Name: "@", Type: "NS", Values: []string{"ns1.example.net."}}}, owner: "infra"},
+		{specs: []RecordSpec{{Name: "_gobizfly.a.www", Type: "TXT", Values: []string{"x"}}}, owner: "infra"},
+		{specs: []RecordSpec{{Name: "www", Type: "A", Values: []string{"10.0.0.1"}}, {Name: "WWW.example.com", Type: "A", Values: []string{"10.0.0.2"}}}, owner: "infra"},
+	} {
+		_, err := client.DNS.SyncRecords(ctx, testSyncZoneID, tc.specs, SyncOptions{Owner: tc.owner, DryRun: true})
+		assert.True(t, errors.Is(err, ErrCommon), "%v", tc.specs)
+	}
+}
+
+func TestSyncRecordsZoneWithoutTTL(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	var (
+		d     dnsService
+		posts []string
+	)
+	mux.HandleFunc(testlib.DNSURL(d.zoneItemPath(testSyncZoneID)), func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": "48d6ce71-43ed-45d3-9ab3-747dd08f500f", "name": "example.com", "ttl": 0, "record_set": []}`)
+	})
+	mux.HandleFunc(testlib.DNSURL(d.zoneItemPath(testSyncZoneID)+"/record"), func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Record CreateNormalRecordPayload `json:"record"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		posts = append(posts, fmt.Sprintf("%s %s %d", payload.Record.Name, payload.Record.Type, payload.Record.TTL))
+		if payload.Record.Type == "A" {
+			// The record set is added, but the gateway fails the request.
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"record": {"id": "new"}}`)
+	})
+
+	specs := []RecordSpec{{Name: "www", Type: "A", TTL: 300, Values: []string{"10.0.0.1"}}}
+	_, err := client.DNS.SyncRecords(ctx, testSyncZoneID, specs, SyncOptions{Owner: "infra"})
+	assert.True(t, errors.Is(err, ErrTransient))
+	assert.Equal(t, []string{"_gobizfly.a.www TXT 300", "www A 300"}, posts)
+}
@@ -54,6 +54,19 @@ func TestZoneList(t *testing.T) {
 	assert.Equal(t, 3600, resp.Zones[0].TTL)
 }
 
+func TestZoneListPage(t *testing.T) {
+	setup()
+	defer teardown()
+	mux.HandleFunc(testlib.DNSURL(zonesPath), func(writer http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2", r.URL.Query().Get("page"))
+		assert.Equal(t, "50", r.URL.Query().Get("limit"))
+		_, _ = fmt.Fprint(writer, `{"zones": [], "_meta": {"max_results": 50, "total": 50, "page": 2}}`)
+	})
+	resp, err := client.DNS.ListZones(ctx, &ListOptions{Page: 2, Limit: 50})
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.Meta.Page)
+}
+
 func TestCreateZone(t *testing.T) {
 	setup()
 	defer teardown()
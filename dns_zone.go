@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"strconv"
 )
 
 // Meta - Metadata of list zone response
@@ -31,6 +33,7 @@ type Zone struct {
 	TenantID   string   `json:"tenant_id"`
 	NameServer []string `json:"nameserver"`
 	TTL        int      `json:"ttl"`
+	Serial     int      `json:"serial,omitempty"`
 	Active     bool     `json:"active"`
 }
 
@@ -58,9 +61,18 @@ type CreateZonePayload struct {
 	Description string `json:"description,omitempty"`
 }
 
-// ListZones - List DNS zones
+// ListZones - List DNS zones, one page at a time when opts sets a page
 func (d *dnsService) ListZones(ctx context.Context, opts *ListOptions) (*ListZoneResp, error) {
-	req, err := d.client.NewRequest(ctx, http.MethodGet, dnsName, d.resourcePath(), nil)
+	u, _ := url.Parse(d.resourcePath())
+	query := url.Values{}
+	if opts != nil && opts.Page != 0 {
+		query.Add("page", strconv.Itoa(opts.Page))
+	}
+	if opts != nil && opts.Limit != 0 {
+		query.Add("limit", strconv.Itoa(opts.Limit))
+	}
+	u.RawQuery = query.Encode()
+	req, err := d.client.NewRequest(ctx, http.MethodGet, dnsName, u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
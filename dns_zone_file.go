@@ -0,0 +1,815 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	zoneFileDefaultTTL = 3600
+	txtChunkSize       = 255
+
+	// SOA timers used when the zone does not expose a complete SOA record.
+	soaRefresh = 3600
+	soaRetry   = 600
+	soaExpire  = 604800
+	soaMinimum = 300
+)
+
+// ImportOptions controls how ImportZone applies a zone file.
+type ImportOptions struct {
+	// Replace updates record sets which differ from the zone file and deletes record sets missing from it.
+	// SOA and apex NS records are managed by Bizfly DNS and are never touched.
+	Replace bool
+	// DryRun computes the changes without applying them.
+	DryRun bool
+}
+
+// ZoneFileChange describes a record set change made, or planned, by ImportZone.
+type ZoneFileChange struct {
+	// Action is one of create, update, delete or skip. Skip marks an existing record set which
+	// differs from the zone file and was kept because ImportOptions.Replace is not set.
+	Action string   `json:"action"`
+	ID     string   `json:"id,omitempty"`
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	TTL    int      `json:"ttl"`
+	Data   []string `json:"data"`
+
+	set *zoneRecordSet
+}
+
+// UnsupportedZoneRecord is a zone file entry which can not be mapped to a Bizfly DNS record.
+type UnsupportedZoneRecord struct {
+	Line   int    `json:"line"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// ZoneImportResult reports the outcome of ImportZone.
+type ZoneImportResult struct {
+	Changes     []ZoneFileChange        `json:"changes"`
+	Unsupported []UnsupportedZoneRecord `json:"unsupported"`
+}
+
+// ExportZone renders a zone and its records as an RFC 1035 zone file.
+func (d *dnsService) ExportZone(ctx context.Context, zoneID string) (io.Reader, error) {
+	zone, err := d.GetZone(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	writeZoneFile(buf, zone)
+	return buf, nil
+}
+
+// ImportZone parses an RFC 1035 zone file and creates the A, AAAA, CNAME, TXT, MX, SRV, NS and CAA
+// records it contains. SOA and apex NS records are skipped, records of other types are reported in
+// ZoneImportResult.Unsupported.
+// When a request fails, the returned result lists the changes applied before the failure.
+func (d *dnsService) ImportZone(ctx context.Context, zoneID string, zoneFile io.Reader, opts ImportOptions) (*ZoneImportResult, error) {
+	zone, err := d.GetZone(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+	rrs, err := parseZoneFile(zoneFile, zone.Name, zone.TTL)
+	if err != nil {
+		return nil, err
+	}
+	desired, unsupported := zoneRecordSets(rrs, zone.Name)
+	result := &ZoneImportResult{Unsupported: unsupported}
+	plan := planZoneImport(zone, desired, opts.Replace)
+	if opts.DryRun {
+		result.Changes = plan
+		return result, nil
+	}
+	for _, change := range plan {
+		if change.Action != "skip" {
+			if err := d.writeRecordSet(ctx, zoneID, change.Action, change.ID, change.set); err != nil {
+				return result, fmt.Errorf("%s %s %s record: %w", change.Action, change.Name, change.Type, err)
+			}
+		}
+		result.Changes = append(result.Changes, change)
+	}
+	return result, nil
+}
+
+// planZoneImport compares the record sets of a zone file with the records of the zone.
+// Deletions come first so a name can change type, e.g. from A to CNAME.
+func planZoneImport(zone *ExtendedZone, desired []*zoneRecordSet, replace bool) []ZoneFileChange {
+	existing := make(map[string][]Record)
+	for _, rec := range zone.RecordsSet {
+		key := zoneRecordKey(relativeRecordName(rec.Name, zone.Name), rec.Type)
+		existing[key] = append(existing[key], rec)
+	}
+	wanted := make(map[string]bool, len(desired))
+	var deletes, updates, creates []ZoneFileChange
+	for _, set := range desired {
+		key := zoneRecordKey(relativeRecordName(set.Name, zone.Name), set.Type)
+		wanted[key] = true
+		change := ZoneFileChange{Name: set.Name, Type: set.Type, TTL: set.TTL, Data: set.values, set: set}
+		recs := existing[key]
+		switch {
+		case len(recs) == 0:
+			change.Action = "create"
+			creates = append(creates, change)
+			continue
+		case recs[0].TTL == set.TTL && sameValues(zoneRecordValues(recs[0]), set.values):
+		case replace:
+			change.Action, change.ID = "update", recs[0].ID
+			updates = append(updates, change)
+		default:
+			change.Action, change.ID = "skip", recs[0].ID
+			updates = append(updates, change)
+		}
+		if replace {
+			for _, rec := range recs[1:] {
+				deletes = append(deletes, deleteZoneChange(rec))
+			}
+		}
+	}
+	if replace {
+		for _, rec := range zone.RecordsSet {
+			rel := relativeRecordName(rec.Name, zone.Name)
+			if wanted[zoneRecordKey(rel, rec.Type)] || managedZoneRecord(rel, rec.Type) {
+				continue
+			}
+			deletes = append(deletes, deleteZoneChange(rec))
+		}
+	}
+	return append(append(deletes, updates...), creates...)
+}
+
+func deleteZoneChange(rec Record) ZoneFileChange {
+	return ZoneFileChange{Action: "delete", ID: rec.ID, Name: rec.Name, Type: rec.Type, TTL: rec.TTL, Data: zoneRecordValues(rec)}
+}
+
+// managedZoneRecord reports whether a record is maintained by Bizfly DNS itself.
+func managedZoneRecord(rel, recordType string) bool {
+	return recordType == "SOA" || (recordType == "NS" && rel == "@")
+}
+
+func zoneRecordKey(rel, recordType string) string {
+	return strings.ToLower(rel) + " " + strings.ToUpper(recordType)
+}
+
+func sameValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// relativeRecordName returns a record name relative to the zone, using "@" for the apex.
+func relativeRecordName(name, zoneName string) string {
+	n := strings.ToLower(strings.TrimSuffix(name, "."))
+	z := strings.ToLower(strings.TrimSuffix(zoneName, "."))
+	switch {
+	case n == "" || n == "@" || n == z:
+		return "@"
+	case strings.HasSuffix(n, "."+z):
+		return strings.TrimSuffix(n, "."+z)
+	}
+	return n
+}
+
+// apiRecordName returns the record name in the form used by the API, which names apex records after the zone.
+func apiRecordName(rel, zoneName string) string {
+	if rel == "@" {
+		return strings.TrimSuffix(zoneName, ".")
+	}
+	return rel
+}
+
+// absoluteHost returns a host name with the trailing dot. The API stores targets as absolute names.
+func absoluteHost(host string) string {
+	return strings.TrimSuffix(host, ".") + "."
+}
+
+func canonicalHost(host string) string {
+	return strings.ToLower(absoluteHost(host))
+}
+
+func srvLabel(label string) string {
+	return "_" + strings.TrimPrefix(label, "_")
+}
+
+func mxValue(mx MXData) string {
+	return fmt.Sprintf("%d %s", mx.Priority, canonicalHost(mx.Value))
+}
+
+func srvValue(srv SRVData) string {
+	return fmt.Sprintf("%s %s %d %d %d %s", strings.ToLower(srvLabel(srv.Service)), strings.ToLower(srvLabel(srv.Protocol)),
+		srv.Priority, srv.Weight, srv.Port, canonicalHost(srv.Target))
+}
+
+// canonicalValue normalizes a plain record value so values from the API and from a zone file compare equal.
+func canonicalValue(recordType, value string) string {
+	switch recordType {
+	case "A", "AAAA":
+		if ip := net.ParseIP(value); ip != nil {
+			return ip.String()
+		}
+	case "CNAME", "NS":
+		return canonicalHost(value)
+	}
+	return value
+}
+
+// zoneRecordValues returns the canonical values of a record.
+func zoneRecordValues(rec Record) []string {
+	var values []string
+	switch rec.Type {
+	case "MX":
+		for _, mx := range rec.AsMX() {
+			values = append(values, mxValue(mx))
+		}
+	case "SRV":
+		for _, srv := range rec.AsSRV() {
+			values = append(values, srvValue(srv))
+		}
+	case "TXT":
+		values = rec.AsTXT()
+	default:
+		for _, item := range rec.Data {
+			values = append(values, canonicalValue(rec.Type, fmt.Sprint(item)))
+		}
+	}
+	return values
+}
+
+// zoneRecordSet groups the resource records of a zone file sharing a name and type.
+type zoneRecordSet struct {
+	Name   string
+	Type   string
+	TTL    int
+	values []string
+	data   []string
+	mx     []MXData
+	srv    []SRVData
+}
+
+func (s *zoneRecordSet) createPayload() RecordPayload {
+	base := BaseCreateRecordPayload{Name: s.Name, Type: s.Type, TTL: s.TTL}
+	switch s.Type {
+	case "MX":
+		return &CreateMXRecordPayload{BaseCreateRecordPayload: base, Data: s.mx}
+	case "SRV":
+		return &CreateSRVRecordPayload{BaseCreateRecordPayload: base, Data: s.srv}
+	}
+	return &CreateNormalRecordPayload{BaseCreateRecordPayload: base, Data: s.data}
+}
+
+func (s *zoneRecordSet) updatePayload() RecordPayload {
+	base := BaseUpdateRecordPayload{Name: s.Name, Type: s.Type, TTL: s.TTL}
+	switch s.Type {
+	case "MX":
+		return &UpdateMXRecordPayload{BaseUpdateRecordPayload: base, Data: s.mx}
+	case "SRV":
+		return &UpdateSRVRecordPayload{BaseUpdateRecordPayload: base, Data: s.srv}
+	}
+	return &UpdateNormalRecordPayload{BaseUpdateRecordPayload: base, Data: s.data}
+}
+
+// zoneRecordSets maps parsed resource records to record sets, in the order they first appear.
+// The first TTL of a set wins, since the API keeps a single TTL per record.
+func zoneRecordSets(rrs []zoneFileRR, zoneName string) ([]*zoneRecordSet, []UnsupportedZoneRecord) {
+	origin := canonicalHost(zoneName)
+	var (
+		sets        []*zoneRecordSet
+		unsupported []UnsupportedZoneRecord
+	)
+	index := make(map[string]*zoneRecordSet)
+	for _, rr := range rrs {
+		reject := func(reason string) {
+			unsupported = append(unsupported, UnsupportedZoneRecord{Line: rr.line, Name: rr.owner, Type: rr.typ, Reason: reason})
+		}
+		var rel string
+		switch {
+		case rr.owner == origin:
+			rel = "@"
+		case strings.HasSuffix(rr.owner, "."+origin):
+			rel = strings.TrimSuffix(rr.owner, "."+origin)
+		default:
+			reject("name is outside of the zone")
+			continue
+		}
+		// SOA and apex NS records, as written by ExportZone, are managed by Bizfly DNS.
+		if managedZoneRecord(rel, rr.typ) {
+			continue
+		}
+		switch rr.typ {
+		case "A", "AAAA", "CNAME", "TXT", "MX", "SRV", "NS", "CAA":
+		default:
+			reject("record type is not supported")
+			continue
+		}
+		var (
+			srv SRVData
+			err error
+		)
+		if rr.typ == "SRV" {
+			labels := strings.SplitN(rel, ".", 3)
+			if len(labels) < 2 || !strings.HasPrefix(labels[0], "_") || !strings.HasPrefix(labels[1], "_") {
+				reject("SRV name must start with _service._protocol")
+				continue
+			}
+			srv.Service, srv.Protocol, rel = labels[0], labels[1], "@"
+			if len(labels) == 3 {
+				rel = labels[2]
+			}
+		}
+		key := zoneRecordKey(rel, rr.typ)
+		set, ok := index[key]
+		if !ok {
+			set = &zoneRecordSet{Name: apiRecordName(rel, zoneName), Type: rr.typ, TTL: rr.ttl}
+		}
+		// Records are added to a copy so an invalid record does not leave a partial set behind.
+		next := *set
+		if err = next.add(rr, srv); err == nil {
+			err = next.createPayload().Validate()
+		}
+		if err != nil {
+			var invalid *recordError
+			if errors.As(err, &invalid) {
+				reject(invalid.reason)
+			} else {
+				reject(err.Error())
+			}
+			continue
+		}
+		*set = next
+		if !ok {
+			index[key] = set
+			sets = append(sets, set)
+		}
+	}
+	return sets, unsupported
+}
+
+// add appends the data of a resource record to the set. srv carries the service and protocol taken from the owner name.
+func (s *zoneRecordSet) add(rr zoneFileRR, srv SRVData) error {
+	args := make([]string, len(rr.rdata))
+	for i, tok := range rr.rdata {
+		args[i] = tok.text
+	}
+	want := map[string]int{"A": 1, "AAAA": 1, "CNAME": 1, "NS": 1, "MX": 2, "SRV": 4, "CAA": 3}
+	if n, ok := want[rr.typ]; ok && len(args) != n {
+		return fmt.Errorf("%s record needs %d fields, got %d", rr.typ, n, len(args))
+	}
+	switch rr.typ {
+	case "A", "AAAA":
+		ip := net.ParseIP(args[0])
+		if ip == nil || (rr.typ == "A") != (ip.To4() != nil) {
+			return fmt.Errorf("invalid %s address %q", rr.typ, args[0])
+		}
+		s.data = append(s.data, ip.String())
+		s.values = append(s.values, ip.String())
+	case "CNAME", "NS":
+		host := absoluteName(args[0], rr.origin)
+		s.data = append(s.data, host)
+		s.values = append(s.values, canonicalHost(host))
+	case "TXT":
+		if len(args) == 0 {
+			return fmt.Errorf("TXT record has no data")
+		}
+		text := strings.Join(args, "")
+		s.data = append(s.data, chunkTXT(text))
+		s.values = append(s.values, text)
+	case "MX":
+		priority, err := strconv.ParseUint(args[0], 10, 16)
+		if err != nil {
+			return fmt.Errorf("invalid MX priority %q", args[0])
+		}
+		mx := MXData{Priority: int(priority), Value: absoluteName(args[1], rr.origin)}
+		s.mx = append(s.mx, mx)
+		s.values = append(s.values, mxValue(mx))
+	case "SRV":
+		var fields [3]int
+		for i, arg := range args[:3] {
+			v, err := strconv.ParseUint(arg, 10, 16)
+			if err != nil {
+				return fmt.Errorf("invalid SRV field %q", arg)
+			}
+			fields[i] = int(v)
+		}
+		srv.Priority, srv.Weight, srv.Port = fields[0], fields[1], fields[2]
+		srv.Target = absoluteName(args[3], rr.origin)
+		s.srv = append(s.srv, srv)
+		s.values = append(s.values, srvValue(srv))
+	case "CAA":
+		if _, err := strconv.ParseUint(args[0], 10, 8); err != nil {
+			return fmt.Errorf("invalid CAA flags %q", args[0])
+		}
+		value := fmt.Sprintf("%s %s %s", args[0], strings.ToLower(args[1]), quoteZoneString(args[2]))
+		s.data = append(s.data, value)
+		s.values = append(s.values, value)
+	}
+	return nil
+}
+
+// writeZoneFile renders a zone in the BIND presentation format.
+func writeZoneFile(w io.Writer, zone *ExtendedZone) {
+	origin := absoluteHost(zone.Name)
+	ttl := zone.TTL
+	if ttl <= 0 {
+		ttl = zoneFileDefaultTTL
+	}
+	_, _ = fmt.Fprintf(w, "$ORIGIN %s\n$TTL %d\n", origin, ttl)
+
+	var soa *Record
+	hasNS := false
+	records := make([]Record, 0, len(zone.RecordsSet))
+	for i, rec := range zone.RecordsSet {
+		switch {
+		case rec.Type == "SOA":
+			soa = &zone.RecordsSet[i]
+			continue
+		case rec.Type == "NS" && relativeRecordName(rec.Name, zone.Name) == "@":
+			hasNS = true
+		}
+		records = append(records, rec)
+	}
+	if line := soaLine(zone, soa, origin, ttl); line != "" {
+		_, _ = fmt.Fprintln(w, line)
+	}
+	if !hasNS {
+		for _, ns := range zone.NameServer {
+			_, _ = fmt.Fprintf(w, "@\t%d\tIN\tNS\t%s\n", ttl, absoluteHost(ns))
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		ni, nj := relativeRecordName(records[i].Name, zone.Name), relativeRecordName(records[j].Name, zone.Name)
+		if ni != nj {
+			return ni == "@" || (nj != "@" && ni < nj)
+		}
+		return records[i].Type < records[j].Type
+	})
+	for _, rec := range records {
+		rel := relativeRecordName(rec.Name, zone.Name)
+		recTTL := rec.TTL
+		if recTTL <= 0 {
+			recTTL = ttl
+		}
+		for _, line := range zoneFileLines(rec, rel) {
+			_, _ = fmt.Fprintf(w, "%s\t%d\tIN\t%s\t%s\n", line[0], recTTL, rec.Type, line[1])
+		}
+	}
+}
+
+// soaLine renders the SOA record. The API only returns the primary name server, so the remaining
+// fields are filled with the zone serial and common timer values.
+func soaLine(zone *ExtendedZone, soa *Record, origin string, ttl int) string {
+	var fields []string
+	if soa != nil {
+		for _, item := range soa.Data {
+			fields = append(fields, strings.Fields(fmt.Sprint(item))...)
+		}
+	}
+	if len(fields) != 7 {
+		primary := ""
+		if len(fields) > 0 {
+			primary = fields[0]
+		} else if len(zone.NameServer) > 0 {
+			primary = zone.NameServer[0]
+		}
+		if primary == "" {
+			return ""
+		}
+		serial := zone.Serial
+		if serial <= 0 {
+			serial = 1
+		}
+		fields = []string{absoluteHost(primary), "hostmaster." + origin, strconv.Itoa(serial),
+			strconv.Itoa(soaRefresh), strconv.Itoa(soaRetry), strconv.Itoa(soaExpire), strconv.Itoa(soaMinimum)}
+	}
+	return fmt.Sprintf("@\t%d\tIN\tSOA\t%s", ttl, strings.Join(fields, " "))
+}
+
+// zoneFileLines returns the owner and rdata of each resource record of a record set.
+func zoneFileLines(rec Record, rel string) [][2]string {
+	var lines [][2]string
+	switch rec.Type {
+	case "MX":
+		for _, mx := range rec.AsMX() {
+			lines = append(lines, [2]string{rel, fmt.Sprintf("%d %s", mx.Priority, absoluteHost(mx.Value))})
+		}
+	case "SRV":
+		for _, srv := range rec.AsSRV() {
+			owner := srvLabel(srv.Service) + "." + srvLabel(srv.Protocol)
+			if rel != "@" {
+				owner += "." + rel
+			}
+			lines = append(lines, [2]string{owner, fmt.Sprintf("%d %d %d %s", srv.Priority, srv.Weight, srv.Port, absoluteHost(srv.Target))})
+		}
+	case "TXT":
+		for _, value := range rec.AsTXT() {
+			lines = append(lines, [2]string{rel, quoteTXT(value)})
+		}
+	default:
+		for _, item := range rec.Data {
+			value := fmt.Sprint(item)
+			if rec.Type == "CNAME" || rec.Type == "NS" {
+				value = absoluteHost(value)
+			}
+			lines = append(lines, [2]string{rel, value})
+		}
+	}
+	return lines
+}
+
+// quoteTXT quotes a TXT value, splitting it into character strings of at most 255 bytes.
+func quoteTXT(value string) string {
+	var chunks []string
+	for {
+		n := len(value)
+		if n > txtChunkSize {
+			n = txtChunkSize
+		}
+		chunks = append(chunks, quoteZoneString(value[:n]))
+		value = value[n:]
+		if value == "" {
+			return strings.Join(chunks, " ")
+		}
+	}
+}
+
+// quoteZoneString quotes a character string, escaping quotes and backslashes as zone files do.
+func quoteZoneString(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// zoneToken is a field of a zone file entry.
+type zoneToken struct {
+	text   string
+	quoted bool
+}
+
+// zoneEntry is a logical line of a zone file, with parentheses already joined.
+type zoneEntry struct {
+	line       int
+	blankOwner bool
+	tokens     []zoneToken
+}
+
+// zoneFileRR is a resource record read from a zone file.
+type zoneFileRR struct {
+	line   int
+	owner  string
+	ttl    int
+	typ    string
+	origin string
+	rdata  []zoneToken
+}
+
+func zoneFileError(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("zone file line %d: %s: %w", line, fmt.Sprintf(format, args...), ErrCommon)
+}
+
+// scanZoneFile splits a zone file into entries, handling comments, quoting and parentheses.
+func scanZoneFile(r io.Reader) ([]zoneEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		entries []zoneEntry
+		cur     = zoneEntry{line: 1}
+		depth   int
+		line    = 1
+	)
+	flush := func() {
+		if len(cur.tokens) > 0 {
+			entries = append(entries, cur)
+		}
+		cur = zoneEntry{line: line}
+	}
+	lineStart := true
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case '\n':
+			line++
+			if depth == 0 {
+				flush()
+				lineStart = true
+			}
+			continue
+		case ' ', '\t', '\r':
+			if lineStart && len(cur.tokens) == 0 {
+				cur.blankOwner = true
+			}
+			lineStart = false
+			continue
+		case ';':
+			for i+1 < len(data) && data[i+1] != '\n' {
+				i++
+			}
+			continue
+		case '(':
+			depth++
+			lineStart = false
+			continue
+		case ')':
+			if depth == 0 {
+				return nil, zoneFileError(line, "unbalanced parenthesis")
+			}
+			depth--
+			continue
+		}
+		lineStart = false
+		if len(cur.tokens) == 0 {
+			cur.line = line
+		}
+		var (
+			text   strings.Builder
+			quoted = c == '"'
+		)
+		if quoted {
+			i++
+		}
+		for ; i < len(data); i++ {
+			c = data[i]
+			if quoted && c == '"' {
+				break
+			}
+			if !quoted && strings.IndexByte(" \t\r\n;()\"", c) >= 0 {
+				i--
+				break
+			}
+			if c == '\n' {
+				return nil, zoneFileError(line, "unterminated quoted string")
+			}
+			if c == '\\' && i+1 < len(data) {
+				i++
+				c = data[i]
+				if c >= '0' && c <= '9' && i+2 < len(data) {
+					if v, err := strconv.ParseUint(string(data[i:i+3]), 10, 8); err == nil {
+						c = byte(v)
+						i += 2
+					}
+				}
+			}
+			text.WriteByte(c)
+		}
+		if quoted && i >= len(data) {
+			return nil, zoneFileError(line, "unterminated quoted string")
+		}
+		cur.tokens = append(cur.tokens, zoneToken{text: text.String(), quoted: quoted})
+	}
+	if depth > 0 {
+		return nil, zoneFileError(line, "unbalanced parenthesis")
+	}
+	flush()
+	return entries, nil
+}
+
+// parseZoneFile reads the resource records of a zone file. Owner names are returned as
+// lower case absolute names. Records without a TTL inherit $TTL, then the previous TTL, then defaultTTL.
+func parseZoneFile(r io.Reader, zoneName string, defaultTTL int) ([]zoneFileRR, error) {
+	entries, err := scanZoneFile(r)
+	if err != nil {
+		return nil, err
+	}
+	if defaultTTL <= 0 {
+		defaultTTL = zoneFileDefaultTTL
+	}
+	var (
+		rrs       []zoneFileRR
+		origin    = absoluteHost(zoneName)
+		owner     string
+		zoneTTL   = -1
+		lastTTL   = -1
+		directive = func(e zoneEntry) error {
+			if len(e.tokens) != 2 {
+				return zoneFileError(e.line, "%s needs one argument", e.tokens[0].text)
+			}
+			switch strings.ToUpper(e.tokens[0].text) {
+			case "$ORIGIN":
+				origin = absoluteName(e.tokens[1].text, origin)
+			case "$TTL":
+				ttl, err := parseZoneTTL(e.tokens[1].text)
+				if err != nil {
+					return zoneFileError(e.line, "invalid $TTL %q", e.tokens[1].text)
+				}
+				zoneTTL = ttl
+			default:
+				return zoneFileError(e.line, "unsupported directive %s", e.tokens[0].text)
+			}
+			return nil
+		}
+	)
+	for _, e := range entries {
+		toks := e.tokens
+		if !e.blankOwner && !toks[0].quoted && strings.HasPrefix(toks[0].text, "$") {
+			if err := directive(e); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !e.blankOwner {
+			owner = strings.ToLower(absoluteName(toks[0].text, origin))
+			toks = toks[1:]
+		} else if owner == "" {
+			return nil, zoneFileError(e.line, "record has no owner name")
+		}
+		// The TTL and class are both optional and may come in either order.
+		ttl := -1
+		for n := 0; n < 2 && len(toks) > 0; n++ {
+			class := strings.ToUpper(toks[0].text)
+			if class == "CH" || class == "HS" || class == "CS" {
+				return nil, zoneFileError(e.line, "class %s is not supported", class)
+			}
+			if class != "IN" {
+				v, err := parseZoneTTL(toks[0].text)
+				if err != nil {
+					break
+				}
+				ttl = v
+			}
+			toks = toks[1:]
+		}
+		if len(toks) == 0 {
+			return nil, zoneFileError(e.line, "record has no type")
+		}
+		switch {
+		case ttl >= 0:
+		case zoneTTL >= 0:
+			ttl = zoneTTL
+		case lastTTL >= 0:
+			ttl = lastTTL
+		default:
+			ttl = defaultTTL
+		}
+		lastTTL = ttl
+		rrs = append(rrs, zoneFileRR{
+			line:   e.line,
+			owner:  owner,
+			ttl:    ttl,
+			typ:    strings.ToUpper(toks[0].text),
+			origin: origin,
+			rdata:  toks[1:],
+		})
+	}
+	return rrs, nil
+}
+
+// absoluteName resolves a possibly relative domain name against the origin.
+func absoluteName(name, origin string) string {
+	switch {
+	case name == "@":
+		return origin
+	case strings.HasSuffix(name, "."):
+		return name
+	case origin == ".":
+		return name + "."
+	}
+	return name + "." + origin
+}
+
+// parseZoneTTL parses a TTL in seconds or in the BIND unit form, e.g. 1h30m.
+func parseZoneTTL(s string) (int, error) {
+	if v, err := strconv.ParseUint(s, 10, 31); err == nil {
+		return int(v), nil
+	}
+	units := map[byte]int{'s': 1, 'm': 60, 'h': 3600, 'd': 86400, 'w': 604800}
+	total, num, digits := 0, 0, 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= '0' && c <= '9' {
+			num = num*10 + int(c-'0')
+			digits++
+			continue
+		}
+		unit, ok := units[c|0x20]
+		if !ok || digits == 0 {
+			return 0, fmt.Errorf("invalid TTL %q: %w", s, ErrCommon)
+		}
+		total += num * unit
+		num, digits = 0, 0
+	}
+	if digits > 0 || len(s) == 0 {
+		return 0, fmt.Errorf("invalid TTL %q: %w", s, ErrCommon)
+	}
+	return total, nil
+}
@@ -0,0 +1,224 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testZoneFileZoneID = "5f01ffb6-ddb5-4541-b978-87a7eed71058"
+
+const testZoneFileZone = `{
+    "id": "5f01ffb6-ddb5-4541-b978-87a7eed71058",
+    "name": "example.com",
+    "serial": 1625481955,
+    "nameserver": ["ns4.bizflycloud.vn.", "ns5.bizflycloud.vn."],
+    "ttl": 3600,
+    "record_set": [
+        {"id": "ns", "name": "example.com", "type": "NS", "ttl": 3600, "data": ["ns4.bizflycloud.vn.", "ns5.bizflycloud.vn."]},
+        {"id": "soa", "name": "example.com", "type": "SOA", "ttl": 3600, "data": ["ns4.bizflycloud.vn."]},
+        {"id": "www", "name": "www", "type": "A", "ttl": 300, "data": ["10.0.0.1", "10.0.0.2"]},
+        {"id": "mx", "name": "example.com", "type": "MX", "ttl": 300, "data": [{"value": "mail.example.com", "priority": 10}]},
+        {"id": "srv", "name": "example.com", "type": "SRV", "ttl": 300, "data": [{"port": 5060, "priority": 10, "protocol": "_tcp", "service": "_sip", "target": "sip.example.com.", "weight": 5}]},
+        {"id": "txt", "name": "example.com", "type": "TXT", "ttl": 300, "data": ["v=spf1 include:\"x\" -all"]},
+        {"id": "old", "name": "old", "type": "CNAME", "ttl": 300, "data": ["www.example.com."]}
+    ]
+}`
+
+func handleTestZoneFileZone(t *testing.T) {
+	var d dnsService
+	mux.HandleFunc(testlib.DNSURL(d.zoneItemPath(testZoneFileZoneID)), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, testZoneFileZone)
+	})
+}
+
+func TestExportZone(t *testing.T) {
+	setup()
+	defer teardown()
+	handleTestZoneFileZone(t)
+
+	r, err := client.DNS.ExportZone(ctx, testZoneFileZoneID)
+	require.NoError(t, err)
+	buf, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, `$ORIGIN example.com.
+$TTL 3600
+@	3600	IN	SOA	ns4.bizflycloud.vn. hostmaster.example.com. 1625481955 3600 600 604800 300
+@	300	IN	MX	10 mail.example.com.
+@	3600	IN	NS	ns4.bizflycloud.vn.
+@	3600	IN	NS	ns5.bizflycloud.vn.
+_sip._tcp	300	IN	SRV	10 5 5060 sip.example.com.
+@	300	IN	TXT	"v=spf1 include:\"x\" -all"
+old	300	IN	CNAME	www.example.com.
+www	300	IN	A	10.0.0.1
+www	300	IN	A	10.0.0.2
+`, string(buf))
+
+	// The exported file imports back without changes.
+	rrs, err := parseZoneFile(strings.NewReader(string(buf)), "example.com", 3600)
+	require.NoError(t, err)
+	var zone *ExtendedZone
+	require.NoError(t, json.Unmarshal([]byte(testZoneFileZone), &zone))
+	sets, unsupported := zoneRecordSets(rrs, zone.Name)
+	assert.Empty(t, unsupported)
+	assert.Empty(t, planZoneImport(zone, sets, true))
+}
+
+func TestZoneRecordSetsUnsupportedReason(t *testing.T) {
+	rrs, err := parseZoneFile(strings.NewReader("www 0 IN A 10.0.0.1\nbad 300 IN CNAME -www\n"), "example.com", 3600)
+	require.NoError(t, err)
+	sets, unsupported := zoneRecordSets(rrs, "example.com")
+	assert.Empty(t, sets)
+	require.Len(t, unsupported, 2)
+	assert.Equal(t, "record TTL must be between 1 and 2147483647, got 0", unsupported[0].Reason)
+	assert.Equal(t, `invalid host name "-www.example.com."`, unsupported[1].Reason)
+}
+
+func TestZoneFileCAAEscaping(t *testing.T) {
+	caa := NewCAARecord("@", 3600, 0, "iodef", "mailto:\"ops\"\tca\\x@example.com")
+	assert.Equal(t, []string{`0 iodef "mailto:\"ops\"	ca\\x@example.com"`}, caa.Data)
+
+	// The value written to the API reads back unchanged from a zone file.
+	rrs, err := parseZoneFile(strings.NewReader("@ 3600 IN CAA "+caa.Data[0]+"\n"), "example.com", 3600)
+	require.NoError(t, err)
+	sets, unsupported := zoneRecordSets(rrs, "example.com")
+	assert.Empty(t, unsupported)
+	require.Len(t, sets, 1)
+	assert.Equal(t, caa.Data, sets[0].data)
+}
+
+const testZoneFile = `$ORIGIN example.com.
+$TTL 1h
+@       IN  SOA ns1.example.net. admin.example.com. (
+            2024010101 ; serial
+            7200 3600 1209600 300 )
+        IN  NS  ns1.example.net.
+@       300 IN  MX  10 mail
+        300 IN  MX  20 mail2.example.org.
+www     IN  300 A   10.0.0.1
+        A   10.0.0.3
+v6          AAAA 2001:db8::1
+old         CNAME www
+@           TXT "v=spf1 include:\"x\" -all"
+dkim._domainkey TXT ( "v=DKIM1; k=rsa; "
+                      "p=MIGf" )
+_sip._tcp   SRV 10 5 5060 sip
+@           CAA 0 issue "letsencrypt.org"
+sub         NS  ns1.other.net.
+1           PTR host.example.com.
+ext.example.org. A 10.0.0.9
+`
+
+func TestImportZoneDryRun(t *testing.T) {
+	setup()
+	defer teardown()
+	handleTestZoneFileZone(t)
+	mux.HandleFunc(testlib.DNSURL("/"), func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	})
+
+	result, err := client.DNS.ImportZone(ctx, testZoneFileZoneID, strings.NewReader(testZoneFile), ImportOptions{DryRun: true})
+	require.NoError(t, err)
+
+	var changes []string
+	for _, c := range result.Changes {
+		changes = append(changes, fmt.Sprintf("%s %s %s %d %v", c.Action, c.Name, c.Type, c.TTL, c.Data))
+	}
+	assert.Equal(t, []string{
+		"skip example.com MX 300 [10 mail.example.com. 20 mail2.example.org.]",
+		"skip www A 300 [10.0.0.1 10.0.0.3]",
+		"skip old CNAME 3600 [www.example.com.]",
+		"skip example.com TXT 3600 [v=spf1 include:\"x\" -all]",
+		"skip example.com SRV 3600 [_sip _tcp 10 5 5060 sip.example.com.]",
+		"create v6 AAAA 3600 [2001:db8::1]",
+		"create dkim._domainkey TXT 3600 [v=DKIM1; k=rsa; p=MIGf]",
+		"create example.com CAA 3600 [0 issue \"letsencrypt.org\"]",
+		"create sub NS 3600 [ns1.other.net.]",
+	}, changes)
+
+	var unsupported []string
+	for _, u := range result.Unsupported {
+		unsupported = append(unsupported, fmt.Sprintf("%d %s %s", u.Line, u.Name, u.Type))
+	}
+	assert.Equal(t, []string{
+		"19 1.example.com. PTR",
+		"20 ext.example.org. A",
+	}, unsupported)
+}
+
+func TestImportZoneReplace(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+	handleTestZoneFileZone(t)
+
+	var (
+		d       dnsService
+		actions []string
+		created []map[string]interface{}
+	)
+	mux.HandleFunc(testlib.DNSURL(d.zoneItemPath(testZoneFileZoneID)+"/record"), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		var payload struct {
+			Record map[string]interface{} `json:"record"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		created = append(created, payload.Record)
+		actions = append(actions, fmt.Sprintf("create %s %s", payload.Record["name"], payload.Record["type"]))
+		_, _ = fmt.Fprint(w, `{"record": {"id": "new"}}`)
+	})
+	mux.HandleFunc(testlib.DNSURL(recordPath+"/"), func(w http.ResponseWriter, r *http.Request) {
+		actions = append(actions, r.Method+" "+strings.TrimPrefix(r.URL.Path, testlib.DNSURL(recordPath+"/")))
+		if r.Method == http.MethodPut {
+			_, _ = fmt.Fprint(w, `{"id": "updated"}`)
+		}
+	})
+
+	zoneFile := `$ORIGIN example.com.
+www 300 IN A 10.0.0.1
+www 300 IN A 10.0.0.2
+@ 300 IN MX 10 mail
+@ 300 IN MX 20 mail2
+_sip._tcp.voice 600 IN SRV 10 5 5060 sip.example.com.
+`
+	result, err := client.DNS.ImportZone(ctx, testZoneFileZoneID, strings.NewReader(zoneFile), ImportOptions{Replace: true})
+	require.NoError(t, err)
+	assert.Empty(t, result.Unsupported)
+	assert.Equal(t, []string{
+		"DELETE srv",
+		"DELETE txt",
+		"DELETE old",
+		"PUT mx",
+		"create voice SRV",
+	}, actions)
+	require.Len(t, created, 1)
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"port": float64(5060), "priority": float64(10), "protocol": "_tcp",
+		"service": "_sip", "target": "sip.example.com.", "weight": float64(5),
+	}}, created[0]["data"])
+}
+
+func TestParseZoneFileErrors(t *testing.T) {
+	for _, zoneFile := range []string{
+		"www IN A (10.0.0.1\n",
+		"www IN TXT \"unterminated\n",
+		"$INCLUDE other.zone\n",
+		"  IN A 10.0.0.1\n",
+		"www CH A 10.0.0.1\n",
+	} {
+		_, err := parseZoneFile(strings.NewReader(zoneFile), "example.com", 3600)
+		assert.True(t, errors.Is(err, ErrCommon), zoneFile)
+	}
+}
@@ -2,10 +2,15 @@ module github.com/bizflycloud/gobizfly
 
 go 1.24
 
-require github.com/stretchr/testify v1.4.0
+require (
+	github.com/coder/websocket v1.8.15
+	github.com/stretchr/testify v1.4.0
+	golang.org/x/crypto v0.40.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.34.0 // indirect
 	gopkg.in/yaml.v2 v2.2.8 // indirect
 )
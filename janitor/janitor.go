@@ -0,0 +1,363 @@
+// This file is part of gobizfly
+
+// Package janitor finds network and storage resources which are no longer attached to anything,
+// such as detached WAN IPs, network interfaces and volumes, or snapshots of deleted volumes.
+package janitor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/bizflycloud/gobizfly"
+)
+
+// Kind is the kind of an orphaned resource.
+type Kind string
+
+const (
+	KindWanIP            Kind = "wan_ip"
+	KindNetworkInterface Kind = "network_interface"
+	KindVolume           Kind = "volume"
+	KindSnapshot         Kind = "snapshot"
+)
+
+const volumeAvailableStatus = "available"
+
+var timeLayouts = []string{
+	"2006-01-02T15:04:05.000000",
+	"2006-01-02T15:04:05",
+	time.RFC3339Nano,
+	time.RFC3339,
+}
+
+// VolumeAPI is the part of the volume service used by the janitor.
+type VolumeAPI interface {
+	List(ctx context.Context, opts *gobizfly.VolumeListOptions) ([]*gobizfly.Volume, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// SnapshotAPI is the part of the snapshot service used by the janitor.
+type SnapshotAPI interface {
+	List(ctx context.Context, opts *gobizfly.ListSnasphotsOptions) ([]*gobizfly.Snapshot, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// NetworkInterfaceAPI is the part of the network interface service used by the janitor.
+type NetworkInterfaceAPI interface {
+	List(ctx context.Context, opts *gobizfly.ListNetworkInterfaceOptions) ([]*gobizfly.NetworkInterface, error)
+	Delete(ctx context.Context, networkInterfaceID string) error
+}
+
+// WanIPAPI is the part of the public network interface service used by the janitor.
+type WanIPAPI interface {
+	List(ctx context.Context) ([]*gobizfly.CloudServerPublicNetworkInterface, error)
+	Delete(ctx context.Context, publicNetworkInterfaceID string) error
+}
+
+// Janitor scans the project for orphaned resources.
+type Janitor struct {
+	Volumes           VolumeAPI
+	Snapshots         SnapshotAPI
+	NetworkInterfaces NetworkInterfaceAPI
+	WanIPs            WanIPAPI
+
+	// Now returns the reference time of reports, it defaults to time.Now.
+	Now func() time.Time
+}
+
+// New creates a janitor using the Cloud Server services of client.
+func New(client *gobizfly.Client) *Janitor {
+	return &Janitor{
+		Volumes:           client.CloudServer.Volumes(),
+		Snapshots:         client.CloudServer.Snapshots(),
+		NetworkInterfaces: client.CloudServer.NetworkInterfaces(),
+		WanIPs:            client.CloudServer.PublicNetworkInterfaces(),
+	}
+}
+
+// Orphan is a resource which is not attached to anything.
+// Age is zero when the creation time of the resource is unknown.
+type Orphan struct {
+	Kind      Kind          `json:"kind"`
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Reason    string        `json:"reason"`
+	CreatedAt time.Time     `json:"created_at"`
+	Age       time.Duration `json:"age"`
+}
+
+// Report contains the orphans found by a scan, oldest first.
+type Report struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Orphans     []*Orphan `json:"orphans"`
+}
+
+// ScanOptions represents options when scanning for orphans.
+// MinAge skips orphans younger than the given duration. Orphans of unknown age are skipped as well
+// when MinAge is set, since they may have just been created.
+type ScanOptions struct {
+	MinAge time.Duration
+}
+
+func (j *Janitor) now() time.Time {
+	if j.Now != nil {
+		return j.Now()
+	}
+	return time.Now()
+}
+
+func parseTime(value string) time.Time {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Scan lists WAN IPs, network interfaces, volumes and snapshots and reports the orphaned ones.
+func (j *Janitor) Scan(ctx context.Context, opts *ScanOptions) (*Report, error) {
+	if opts == nil {
+		opts = &ScanOptions{}
+	}
+	now := j.now()
+	report := &Report{GeneratedAt: now, Orphans: []*Orphan{}}
+	add := func(kind Kind, id string, name string, createdAt string, reason string) {
+		orphan := &Orphan{Kind: kind, ID: id, Name: name, Reason: reason, CreatedAt: parseTime(createdAt)}
+		if !orphan.CreatedAt.IsZero() {
+			orphan.Age = now.Sub(orphan.CreatedAt)
+		}
+		if opts.MinAge > 0 && (orphan.CreatedAt.IsZero() || orphan.Age < opts.MinAge) {
+			return
+		}
+		report.Orphans = append(report.Orphans, orphan)
+	}
+
+	wanIPs, err := j.WanIPs.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, wanIP := range wanIPs {
+		if wanIPOrphaned(wanIP) {
+			add(KindWanIP, wanIP.ID, wanIP.Name, wanIP.CreatedAt, fmt.Sprintf("WAN IP %s is not attached to a server", wanIP.IPAddress))
+		}
+	}
+
+	networkInterfaces, err := j.NetworkInterfaces.List(ctx, &gobizfly.ListNetworkInterfaceOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, networkInterface := range networkInterfaces {
+		if networkInterfaceOrphaned(networkInterface) {
+			add(KindNetworkInterface, networkInterface.ID, networkInterface.Name, networkInterface.CreatedAt, "network interface is not attached to a device")
+		}
+	}
+
+	volumes, err := j.Volumes.List(ctx, &gobizfly.VolumeListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	existingVolumes := make(map[string]bool, len(volumes))
+	for _, volume := range volumes {
+		existingVolumes[volume.ID] = true
+		if volumeOrphaned(volume) {
+			add(KindVolume, volume.ID, volume.Name, volume.CreatedAt, fmt.Sprintf("%d GB volume is not attached to a server", volume.Size))
+		}
+	}
+
+	snapshots, err := j.Snapshots.List(ctx, &gobizfly.ListSnasphotsOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		// A snapshot without a source volume ID cannot be told apart from one whose volume is gone.
+		if snapshot.VolumeID != "" && !existingVolumes[snapshot.VolumeID] {
+			add(KindSnapshot, snapshot.ID, snapshot.Name, snapshot.CreateAt, fmt.Sprintf("source volume %s was deleted", snapshot.VolumeID))
+		}
+	}
+
+	sort.SliceStable(report.Orphans, func(a, b int) bool {
+		return report.Orphans[a].Age > report.Orphans[b].Age
+	})
+	return report, nil
+}
+
+func wanIPOrphaned(wanIP *gobizfly.CloudServerPublicNetworkInterface) bool {
+	return wanIP.AttachedServer.ID == "" && wanIP.DeviceID == ""
+}
+
+func networkInterfaceOrphaned(networkInterface *gobizfly.NetworkInterface) bool {
+	return networkInterface.DeviceID == "" && networkInterface.AttachedServer.ID == ""
+}
+
+func volumeOrphaned(volume *gobizfly.Volume) bool {
+	return volume.Status == volumeAvailableStatus && len(volume.Attachments) == 0
+}
+
+// CleanupOptions represents options when deleting orphans.
+// Allow and Deny are lists of IDs or name patterns in path.Match syntax, Deny takes precedence.
+// When Allow is not empty only matching orphans are deleted. Kinds restricts the deleted kinds.
+// DryRun reports what would be deleted without deleting anything.
+// A nil CleanupOptions selects every orphan.
+type CleanupOptions struct {
+	Allow  []string
+	Deny   []string
+	Kinds  []Kind
+	DryRun bool
+}
+
+// CleanupResult contains the outcome of a cleanup.
+type CleanupResult struct {
+	Deleted []*Orphan         `json:"deleted"`
+	Skipped []*Orphan         `json:"skipped"`
+	Failed  map[string]string `json:"failed"`
+}
+
+func matchesAny(orphan *Orphan, patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern == orphan.ID {
+			return true
+		}
+		if ok, _ := path.Match(pattern, orphan.Name); ok && orphan.Name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *CleanupOptions) selects(orphan *Orphan) bool {
+	if len(o.Kinds) > 0 {
+		found := false
+		for _, kind := range o.Kinds {
+			found = found || kind == orphan.Kind
+		}
+		if !found {
+			return false
+		}
+	}
+	if matchesAny(orphan, o.Deny) {
+		return false
+	}
+	return len(o.Allow) == 0 || matchesAny(orphan, o.Allow)
+}
+
+// Cleanup deletes the orphans of a report selected by opts. The report may be stale, so every orphan
+// is fetched again before it is deleted and skipped when it is attached or in use by now.
+// A failed deletion does not stop the cleanup, it is recorded in the result.
+func (j *Janitor) Cleanup(ctx context.Context, report *Report, opts *CleanupOptions) (*CleanupResult, error) {
+	if opts == nil {
+		opts = &CleanupOptions{}
+	}
+	result := &CleanupResult{Failed: map[string]string{}}
+	for _, orphan := range report.Orphans {
+		if !opts.selects(orphan) {
+			result.Skipped = append(result.Skipped, orphan)
+			continue
+		}
+		orphaned, err := j.stillOrphaned(ctx, orphan)
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			result.Failed[orphan.ID] = err.Error()
+			continue
+		}
+		if !orphaned {
+			result.Skipped = append(result.Skipped, orphan)
+			continue
+		}
+		if opts.DryRun {
+			result.Deleted = append(result.Deleted, orphan)
+			continue
+		}
+		if err := j.delete(ctx, orphan); err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			result.Failed[orphan.ID] = err.Error()
+			continue
+		}
+		result.Deleted = append(result.Deleted, orphan)
+	}
+	return result, nil
+}
+
+// stillOrphaned fetches an orphan again and reports whether it still exists and is still orphaned.
+func (j *Janitor) stillOrphaned(ctx context.Context, orphan *Orphan) (bool, error) {
+	switch orphan.Kind {
+	case KindWanIP:
+		wanIPs, err := j.WanIPs.List(ctx)
+		if err != nil {
+			return false, err
+		}
+		for _, wanIP := range wanIPs {
+			if wanIP.ID == orphan.ID {
+				return wanIPOrphaned(wanIP), nil
+			}
+		}
+	case KindNetworkInterface:
+		networkInterfaces, err := j.NetworkInterfaces.List(ctx, &gobizfly.ListNetworkInterfaceOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, networkInterface := range networkInterfaces {
+			if networkInterface.ID == orphan.ID {
+				return networkInterfaceOrphaned(networkInterface), nil
+			}
+		}
+	case KindVolume:
+		volumes, err := j.Volumes.List(ctx, &gobizfly.VolumeListOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, volume := range volumes {
+			if volume.ID == orphan.ID {
+				return volumeOrphaned(volume), nil
+			}
+		}
+	case KindSnapshot:
+		snapshots, err := j.Snapshots.List(ctx, &gobizfly.ListSnasphotsOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, snapshot := range snapshots {
+			if snapshot.ID != orphan.ID {
+				continue
+			}
+			if snapshot.VolumeID == "" {
+				return false, nil
+			}
+			volumes, err := j.Volumes.List(ctx, &gobizfly.VolumeListOptions{})
+			if err != nil {
+				return false, err
+			}
+			for _, volume := range volumes {
+				if volume.ID == snapshot.VolumeID {
+					return false, nil
+				}
+			}
+			return true, nil
+		}
+	default:
+		return false, fmt.Errorf("unknown orphan kind %s", orphan.Kind)
+	}
+	return false, nil
+}
+
+func (j *Janitor) delete(ctx context.Context, orphan *Orphan) error {
+	switch orphan.Kind {
+	case KindWanIP:
+		return j.WanIPs.Delete(ctx, orphan.ID)
+	case KindNetworkInterface:
+		return j.NetworkInterfaces.Delete(ctx, orphan.ID)
+	case KindVolume:
+		return j.Volumes.Delete(ctx, orphan.ID)
+	case KindSnapshot:
+		return j.Snapshots.Delete(ctx, orphan.ID)
+	default:
+		return fmt.Errorf("unknown orphan kind %s", orphan.Kind)
+	}
+}
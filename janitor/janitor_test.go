@@ -0,0 +1,159 @@
+// This file is part of gobizfly
+
+package janitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bizflycloud/gobizfly"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResources struct {
+	volumes           []*gobizfly.Volume
+	snapshots         []*gobizfly.Snapshot
+	networkInterfaces []*gobizfly.NetworkInterface
+	wanIPs            []*gobizfly.CloudServerPublicNetworkInterface
+	deleted           []string
+	failDelete        string
+}
+
+func (f *fakeResources) remove(id string) error {
+	if id == f.failDelete {
+		return errors.New("in use")
+	}
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+type fakeVolumes struct{ *fakeResources }
+
+func (f fakeVolumes) List(ctx context.Context, opts *gobizfly.VolumeListOptions) ([]*gobizfly.Volume, error) {
+	return f.volumes, nil
+}
+
+func (f fakeVolumes) Delete(ctx context.Context, id string) error { return f.remove(id) }
+
+type fakeSnapshots struct{ *fakeResources }
+
+func (f fakeSnapshots) List(ctx context.Context, opts *gobizfly.ListSnasphotsOptions) ([]*gobizfly.Snapshot, error) {
+	return f.snapshots, nil
+}
+
+func (f fakeSnapshots) Delete(ctx context.Context, id string) error { return f.remove(id) }
+
+type fakeNetworkInterfaces struct{ *fakeResources }
+
+func (f fakeNetworkInterfaces) List(ctx context.Context, opts *gobizfly.ListNetworkInterfaceOptions) ([]*gobizfly.NetworkInterface, error) {
+	return f.networkInterfaces, nil
+}
+
+func (f fakeNetworkInterfaces) Delete(ctx context.Context, id string) error { return f.remove(id) }
+
+type fakeWanIPs struct{ *fakeResources }
+
+func (f fakeWanIPs) List(ctx context.Context) ([]*gobizfly.CloudServerPublicNetworkInterface, error) {
+	return f.wanIPs, nil
+}
+
+func (f fakeWanIPs) Delete(ctx context.Context, id string) error { return f.remove(id) }
+
+func newTestJanitor() (*Janitor, *fakeResources) {
+	f := &fakeResources{}
+	f.volumes = []*gobizfly.Volume{
+		{ID: "vol-free", Name: "data-old", Status: "available", Size: 20, CreatedAt: "2024-01-01T00:00:00.000000"},
+		{ID: "vol-used", Name: "root", Status: "in-use", CreatedAt: "2024-01-01T00:00:00.000000",
+			Attachments: []gobizfly.VolumeAttachment{{ServerID: "srv"}}},
+		{ID: "vol-new", Name: "data-new", Status: "available", CreatedAt: "2024-03-09T12:00:00.000000"},
+	}
+	f.snapshots = []*gobizfly.Snapshot{
+		{ID: "snap-kept", Name: "root-snap", VolumeID: "vol-used", CreateAt: "2024-02-01T00:00:00.000000"},
+		{ID: "snap-orphan", Name: "gone-snap", VolumeID: "vol-deleted", CreateAt: "2024-02-01T00:00:00.000000"},
+		{ID: "snap-unknown", Name: "imported-snap", CreateAt: "2024-02-01T00:00:00.000000"},
+	}
+	f.networkInterfaces = []*gobizfly.NetworkInterface{
+		{ID: "nic-free", Name: "vip", CreatedAt: "2024-02-10T00:00:00Z"},
+		{ID: "nic-used", Name: "eth1", DeviceID: "srv", CreatedAt: "2024-02-10T00:00:00Z"},
+	}
+	f.wanIPs = []*gobizfly.CloudServerPublicNetworkInterface{
+		{ID: "wan-free", Name: "wan-1", IPAddress: "103.1.1.1"},
+	}
+	f.wanIPs = append(f.wanIPs, &gobizfly.CloudServerPublicNetworkInterface{ID: "wan-used", Name: "wan-2"})
+	f.wanIPs[1].AttachedServer.ID = "srv"
+	j := &Janitor{
+		Volumes:           fakeVolumes{f},
+		Snapshots:         fakeSnapshots{f},
+		NetworkInterfaces: fakeNetworkInterfaces{f},
+		WanIPs:            fakeWanIPs{f},
+		Now:               func() time.Time { return time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC) },
+	}
+	return j, f
+}
+
+func orphanIDs(orphans []*Orphan) []string {
+	ids := []string{}
+	for _, orphan := range orphans {
+		ids = append(ids, orphan.ID)
+	}
+	return ids
+}
+
+func TestScan(t *testing.T) {
+	j, _ := newTestJanitor()
+	report, err := j.Scan(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vol-free", "snap-orphan", "nic-free", "vol-new", "wan-free"}, orphanIDs(report.Orphans))
+	assert.Equal(t, KindVolume, report.Orphans[0].Kind)
+	assert.Equal(t, 69*24*time.Hour, report.Orphans[0].Age)
+	assert.Zero(t, report.Orphans[4].Age)
+
+	report, err = j.Scan(context.Background(), &ScanOptions{MinAge: 7 * 24 * time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vol-free", "snap-orphan", "nic-free"}, orphanIDs(report.Orphans))
+}
+
+func TestCleanup(t *testing.T) {
+	j, f := newTestJanitor()
+	report, err := j.Scan(context.Background(), nil)
+	require.NoError(t, err)
+
+	result, err := j.Cleanup(context.Background(), report, &CleanupOptions{DryRun: true, Deny: []string{"data-*"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"snap-orphan", "nic-free", "wan-free"}, orphanIDs(result.Deleted))
+	assert.Equal(t, []string{"vol-free", "vol-new"}, orphanIDs(result.Skipped))
+	assert.Empty(t, f.deleted)
+
+	f.failDelete = "wan-free"
+	result, err = j.Cleanup(context.Background(), report, &CleanupOptions{
+		Allow: []string{"vol-free", "wan-*", "nic-free"},
+		Kinds: []Kind{KindVolume, KindWanIP},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vol-free"}, orphanIDs(result.Deleted))
+	assert.Equal(t, []string{"vol-free"}, f.deleted)
+	assert.Equal(t, map[string]string{"wan-free": "in use"}, result.Failed)
+}
+
+func TestCleanupSkipsResourcesNoLongerOrphaned(t *testing.T) {
+	j, f := newTestJanitor()
+	report, err := j.Scan(context.Background(), nil)
+	require.NoError(t, err)
+
+	// After the scan, vol-free and wan-free got attached, nic-free was deleted and vol-deleted came back.
+	f.volumes[0].Status = "in-use"
+	f.volumes[0].Attachments = []gobizfly.VolumeAttachment{{ServerID: "srv"}}
+	f.wanIPs[0].AttachedServer.ID = "srv"
+	f.networkInterfaces = f.networkInterfaces[1:]
+	f.volumes = append(f.volumes, &gobizfly.Volume{ID: "vol-deleted", Status: "available"})
+
+	result, err := j.Cleanup(context.Background(), report, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vol-new"}, orphanIDs(result.Deleted))
+	assert.Equal(t, []string{"vol-free", "snap-orphan", "nic-free", "wan-free"}, orphanIDs(result.Skipped))
+	assert.Equal(t, []string{"vol-new"}, f.deleted)
+	assert.Empty(t, result.Failed)
+}
@@ -0,0 +1,824 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	loadBalancerActiveStatus = "ACTIVE"
+	loadBalancerErrorStatus  = "ERROR"
+)
+
+// LoadBalancerSpec is the desired state of a load balancer, identified by its name.
+// Pools are referenced by name from listeners and L7 policies. Zero values of optional fields
+// leave the server defaults, and are not compared with the live load balancer.
+type LoadBalancerSpec struct {
+	Name         string
+	Description  string
+	Type         LoadBalancerType
+	NetworkType  LoadBalancerNetworkType
+	VPCNetworkID string
+	Pools        []LoadBalancerPoolSpec
+	Listeners    []LoadBalancerListenerSpec
+}
+
+// LoadBalancerPoolSpec is the desired state of a pool, identified by its name.
+// The members and the health monitor of a pool are authoritative: live ones not in the spec are removed.
+type LoadBalancerPoolSpec struct {
+	Name               string
+	Protocol           LoadBalancerProtocol
+	Algorithm          LBAlgorithm
+	SessionPersistence *SessionPersistence
+	HealthMonitor      *LoadBalancerHealthMonitorSpec
+	Members            []LoadBalancerMemberSpec
+}
+
+// LoadBalancerHealthMonitorSpec is the desired health monitor of a pool.
+type LoadBalancerHealthMonitorSpec struct {
+	Type           HealthMonitorType
+	Delay          int
+	Timeout        int
+	MaxRetries     int
+	MaxRetriesDown int
+	HTTPMethod     HealthMonitorHTTPMethod
+	URLPath        string
+	ExpectedCodes  string
+}
+
+// LoadBalancerMemberSpec is a desired member of a pool, identified by its address and port.
+// A nil Backup leaves the backup flag of the member as it is.
+type LoadBalancerMemberSpec struct {
+	Name    string
+	Address string
+	Port    int
+	Weight  int
+	Backup  *bool
+}
+
+// LoadBalancerListenerSpec is the desired state of a listener, identified by its port.
+// DefaultPool is the name of a pool of the spec. The L7 policies of a listener are authoritative
+// and their order in the spec is their position.
+type LoadBalancerListenerSpec struct {
+	Name                   string
+	Protocol               LoadBalancerProtocol
+	Port                   int
+	DefaultPool            string
+	DefaultTLSContainerRef string
+	L7Policies             []LoadBalancerL7PolicySpec
+}
+
+// LoadBalancerL7PolicySpec is the desired state of an L7 policy, identified by its name.
+// RedirectPool is the name of a pool of the spec.
+type LoadBalancerL7PolicySpec struct {
+	Name           string
+	Action         L7PolicyAction
+	RedirectPool   string
+	RedirectURL    string
+	RedirectPrefix string
+	Rules          []L7PolicyRuleRequest
+}
+
+// LoadBalancerApplyOptions represents options when applying a spec.
+// DryRun only reports the changes. Prune deletes the listeners and pools which are not in the spec.
+type LoadBalancerApplyOptions struct {
+	DryRun bool
+	Prune  bool
+}
+
+// LoadBalancerChange is a change made, or planned in dry run, by Apply. ID is empty in dry run.
+type LoadBalancerChange struct {
+	Action string `json:"action"`
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	ID     string `json:"id"`
+}
+
+// LoadBalancerApplyReport lists the changes of an apply in the order they were made.
+type LoadBalancerApplyReport struct {
+	LoadBalancerID string                `json:"loadbalancer_id"`
+	Changes        []*LoadBalancerChange `json:"changes"`
+}
+
+type loadBalancerApplier struct {
+	lbs    *cloudLoadBalancerService
+	opts   *LoadBalancerApplyOptions
+	report *LoadBalancerApplyReport
+}
+
+// Apply makes the load balancer named in spec match it, creating the load balancer if needed. Changes are
+// made in dependency order: pools with their health monitors and members, then listeners and their L7
+// policies, then pruned resources. The load balancer rejects changes while it is not ACTIVE, so Apply
+// waits for it after every change.
+func (l *cloudLoadBalancerService) Apply(ctx context.Context, spec *LoadBalancerSpec, opts *LoadBalancerApplyOptions) (*LoadBalancerApplyReport, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &LoadBalancerApplyOptions{}
+	}
+	a := &loadBalancerApplier{lbs: l, opts: opts, report: &LoadBalancerApplyReport{Changes: []*LoadBalancerChange{}}}
+
+	lbs, err := l.List(ctx, &ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var lb *LoadBalancer
+	for _, candidate := range lbs {
+		if candidate.Name == spec.Name {
+			if lb != nil {
+				return nil, fmt.Errorf("several load balancers are named %s: %w", spec.Name, ErrCommon)
+			}
+			lb = candidate
+		}
+	}
+	if lb == nil {
+		id, err := a.change(ctx, "create", "loadbalancer", spec.Name, "", func(string) (string, error) {
+			created, err := l.Create(ctx, &LoadBalancerCreateRequest{
+				Name:         spec.Name,
+				Description:  spec.Description,
+				NetworkType:  spec.NetworkType,
+				VPCNetworkID: spec.VPCNetworkID,
+				Type:         spec.Type,
+			})
+			if err != nil {
+				return "", err
+			}
+			return created.ID, nil
+		})
+		if err != nil {
+			return a.report, err
+		}
+		lb = &LoadBalancer{ID: id, Name: spec.Name}
+	} else if spec.Description != "" && spec.Description != lb.Description {
+		_, err := a.change(ctx, "update", "loadbalancer", spec.Name, lb.ID, func(id string) (string, error) {
+			description := spec.Description
+			_, err := l.Update(ctx, id, &LoadBalancerUpdateRequest{Description: &description})
+			return id, err
+		})
+		if err != nil {
+			return a.report, err
+		}
+	}
+	a.report.LoadBalancerID = lb.ID
+
+	live, err := a.loadLive(ctx, lb.ID)
+	if err != nil {
+		return a.report, err
+	}
+	poolIDs := make(map[string]string, len(spec.Pools))
+	for _, pool := range spec.Pools {
+		id, err := a.applyPool(ctx, lb.ID, pool, live.pools[pool.Name])
+		if err != nil {
+			return a.report, err
+		}
+		poolIDs[pool.Name] = id
+	}
+	for _, listener := range spec.Listeners {
+		if err := a.applyListener(ctx, lb.ID, listener, live.listeners[listener.Port], poolIDs); err != nil {
+			return a.report, err
+		}
+	}
+
+	if a.opts.Prune {
+		wanted := make(map[int]bool, len(spec.Listeners))
+		for _, listener := range spec.Listeners {
+			wanted[listener.Port] = true
+		}
+		for _, listenerTree := range live.tree.Listeners {
+			listener := listenerTree.Listener
+			if wanted[listener.ProtocolPort] {
+				continue
+			}
+			_, err := a.change(ctx, "delete", "listener", listener.Name, listener.ID, func(id string) (string, error) {
+				return id, l.Listeners().Delete(ctx, id)
+			})
+			if err != nil {
+				return a.report, err
+			}
+		}
+		for _, poolTree := range live.tree.Pools {
+			pool := poolTree.Pool
+			if _, ok := poolIDs[pool.Name]; ok {
+				continue
+			}
+			_, err := a.change(ctx, "delete", "pool", pool.Name, pool.ID, func(id string) (string, error) {
+				return id, l.Pools().Delete(ctx, id)
+			})
+			if err != nil {
+				return a.report, err
+			}
+		}
+	}
+	return a.report, nil
+}
+
+// Validate checks that names are unique, pool references resolve and L7 policies are valid.
+func (s *LoadBalancerSpec) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("load balancer name is required: %w", ErrCommon)
+	}
+	pools := make(map[string]bool, len(s.Pools))
+	for _, pool := range s.Pools {
+		if pool.Name == "" || pools[pool.Name] {
+			return fmt.Errorf("pool names must be unique and not empty, got %q: %w", pool.Name, ErrCommon)
+		}
+		pools[pool.Name] = true
+		if pool.Algorithm == "" {
+			return fmt.Errorf("pool %s needs an algorithm: %w", pool.Name, ErrCommon)
+		}
+		if err := validatePool(pool.Algorithm, pool.Protocol); err != nil {
+			return fmt.Errorf("pool %s: %w", pool.Name, err)
+		}
+		if hm := pool.HealthMonitor; hm != nil {
+			if err := validatePoolHealthMonitor(pool.Protocol, hm.Type); err != nil {
+				return fmt.Errorf("pool %s: %w", pool.Name, err)
+			}
+			err := healthMonitorParams{
+				Type:           hm.Type,
+				Delay:          hm.Delay,
+				Timeout:        hm.Timeout,
+				MaxRetries:     hm.MaxRetries,
+				MaxRetriesDown: hm.MaxRetriesDown,
+				HTTPMethod:     hm.HTTPMethod,
+				URLPath:        hm.URLPath,
+				ExpectedCodes:  hm.ExpectedCodes,
+			}.validate()
+			if err != nil {
+				return fmt.Errorf("pool %s: %w", pool.Name, err)
+			}
+		}
+		members := make(map[string]bool, len(pool.Members))
+		for _, member := range pool.Members {
+			key := memberKey(member.Address, member.Port)
+			if member.Address == "" || member.Port <= 0 || member.Port > 65535 || members[key] {
+				return fmt.Errorf("invalid or duplicate member %s of pool %s: %w", key, pool.Name, ErrCommon)
+			}
+			members[key] = true
+		}
+	}
+	ports := make(map[int]bool, len(s.Listeners))
+	for _, listener := range s.Listeners {
+		if ports[listener.Port] {
+			return fmt.Errorf("duplicate listener port %d: %w", listener.Port, ErrCommon)
+		}
+		if err := validateListener(listener.Protocol, listener.Port, listener.DefaultTLSContainerRef); err != nil {
+			return fmt.Errorf("listener %d: %w", listener.Port, err)
+		}
+		ports[listener.Port] = true
+		if listener.DefaultPool != "" && !pools[listener.DefaultPool] {
+			return fmt.Errorf("listener %d uses unknown pool %s: %w", listener.Port, listener.DefaultPool, ErrCommon)
+		}
+		policies := make(map[string]bool, len(listener.L7Policies))
+		for _, policy := range listener.L7Policies {
+			if policy.Name == "" || policies[policy.Name] {
+				return fmt.Errorf("l7 policy names must be unique and not empty, got %q: %w", policy.Name, ErrCommon)
+			}
+			policies[policy.Name] = true
+			if policy.RedirectPool != "" && !pools[policy.RedirectPool] {
+				return fmt.Errorf("l7 policy %s uses unknown pool %s: %w", policy.Name, policy.RedirectPool, ErrCommon)
+			}
+			request := policy.createRequest(policy.RedirectPool, 0)
+			if err := request.Validate(); err != nil {
+				return fmt.Errorf("l7 policy %s: %w", policy.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// change records a change and, unless in dry run, makes it and waits for the load balancer to be ACTIVE
+// again. Updates and deletes are retried on transient errors. Creates are not, a create which failed after
+// the backend committed it would be made twice, and applying the spec again picks it up instead.
+// fn receives the ID of the changed resource and returns it, or the ID of the created resource.
+func (a *loadBalancerApplier) change(ctx context.Context, action string, kind string, name string, id string,
+	fn func(id string) (string, error)) (string, error) {
+	c := &LoadBalancerChange{Action: action, Kind: kind, Name: name, ID: id}
+	if !a.opts.DryRun {
+		var err error
+		if action == "create" {
+			c.ID, err = fn(id)
+		} else {
+			err = a.lbs.client.retryTransient(ctx, func() error {
+				var err error
+				c.ID, err = fn(id)
+				return err
+			})
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s %s %s: %w", action, kind, name, err)
+		}
+		lbID := a.report.LoadBalancerID
+		if kind == "loadbalancer" {
+			lbID = c.ID
+		}
+		if err := a.waitActive(ctx, lbID); err != nil {
+			return "", err
+		}
+	}
+	a.report.Changes = append(a.report.Changes, c)
+	return c.ID, nil
+}
+
+func (a *loadBalancerApplier) waitActive(ctx context.Context, lbID string) error {
+	return a.lbs.client.waitFor(ctx, func() (bool, error) {
+		lb, err := a.lbs.Get(ctx, lbID)
+		if err != nil {
+			return false, err
+		}
+		if lb.ProvisioningStatus == loadBalancerErrorStatus {
+			return false, fmt.Errorf("load balancer %s is in %s status: %w", lbID, lb.ProvisioningStatus, ErrCommon)
+		}
+		return lb.ProvisioningStatus == loadBalancerActiveStatus, nil
+	})
+}
+
+type liveLoadBalancer struct {
+	tree      *LoadBalancerTree
+	pools     map[string]*LoadBalancerPoolTree
+	listeners map[int]*LoadBalancerListenerTree
+}
+
+// loadLive describes the load balancer and indexes its pools by name and its listeners by port.
+func (a *loadBalancerApplier) loadLive(ctx context.Context, lbID string) (*liveLoadBalancer, error) {
+	live := &liveLoadBalancer{
+		tree:      &LoadBalancerTree{},
+		pools:     map[string]*LoadBalancerPoolTree{},
+		listeners: map[int]*LoadBalancerListenerTree{},
+	}
+	if lbID == "" {
+		return live, nil
+	}
+	tree, err := a.lbs.Describe(ctx, lbID)
+	if err != nil {
+		return nil, err
+	}
+	live.tree = tree
+	for _, pool := range tree.Pools {
+		live.pools[pool.Pool.Name] = pool
+	}
+	for _, listener := range tree.Listeners {
+		live.listeners[listener.Listener.ProtocolPort] = listener
+	}
+	return live, nil
+}
+
+func (a *loadBalancerApplier) applyPool(ctx context.Context, lbID string, spec LoadBalancerPoolSpec, live *LoadBalancerPoolTree) (string, error) {
+	pools := a.lbs.Pools()
+	if live == nil {
+		name := spec.Name
+		id, err := a.change(ctx, "create", "pool", spec.Name, "", func(string) (string, error) {
+			pool, err := pools.Create(ctx, lbID, &CloudLoadBalancerPoolCreateRequest{
+				LBAlgorithm:        spec.Algorithm,
+				Name:               &name,
+				Protocol:           spec.Protocol,
+				SessionPersistence: spec.SessionPersistence,
+			})
+			if err != nil {
+				return "", err
+			}
+			return pool.ID, nil
+		})
+		if err != nil {
+			return "", err
+		}
+		live = &LoadBalancerPoolTree{Pool: &CloudLoadBalancerPool{ID: id, Name: spec.Name}}
+	} else {
+		if !strings.EqualFold(live.Pool.Protocol, string(spec.Protocol)) {
+			return "", fmt.Errorf("protocol of pool %s cannot change from %s to %s: %w",
+				spec.Name, live.Pool.Protocol, spec.Protocol, ErrCommon)
+		}
+		if (spec.Algorithm != "" && !strings.EqualFold(live.Pool.LBAlgorithm, string(spec.Algorithm))) ||
+			!sessionPersistenceEqual(live.Pool.SessionPersistence, spec.SessionPersistence) {
+			_, err := a.change(ctx, "update", "pool", spec.Name, live.Pool.ID, func(id string) (string, error) {
+				update := &CloudLoadBalancerPoolUpdateRequest{SessionPersistence: spec.SessionPersistence}
+				if spec.Algorithm != "" {
+					update.LBAlgorithm = &spec.Algorithm
+				}
+				_, err := pools.Update(ctx, id, update)
+				return id, err
+			})
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	poolID := live.Pool.ID
+	if err := a.applyHealthMonitor(ctx, poolID, spec, live.HealthMonitor); err != nil {
+		return "", err
+	}
+	return poolID, a.applyMembers(ctx, poolID, spec, live.Members)
+}
+
+func (a *loadBalancerApplier) applyHealthMonitor(ctx context.Context, poolID string, pool LoadBalancerPoolSpec,
+	live *CloudLoadBalancerHealthMonitor) error {
+	healthMonitors := a.lbs.HealthMonitors()
+	spec := pool.HealthMonitor
+	name := pool.Name + "-healthmonitor"
+	switch {
+	case spec == nil && live == nil:
+		return nil
+	case spec == nil:
+		_, err := a.change(ctx, "delete", "healthmonitor", live.Name, live.ID, func(id string) (string, error) {
+			return id, healthMonitors.Delete(ctx, id)
+		})
+		return err
+	case live == nil:
+		_, err := a.change(ctx, "create", "healthmonitor", name, "", func(string) (string, error) {
+			hm, err := healthMonitors.Create(ctx, poolID, &CloudLoadBalancerHealthMonitorCreateRequest{
+				Name:           name,
+				Type:           spec.Type,
+				TimeOut:        spec.Timeout,
+				Delay:          spec.Delay,
+				MaxRetries:     spec.MaxRetries,
+				MaxRetriesDown: spec.MaxRetriesDown,
+				HTTPMethod:     spec.HTTPMethod,
+				URLPath:        spec.URLPath,
+				ExpectedCodes:  spec.ExpectedCodes,
+			})
+			if err != nil {
+				return "", err
+			}
+			return hm.ID, nil
+		})
+		return err
+	case !strings.EqualFold(live.Type, string(spec.Type)):
+		// The type of a health monitor cannot be updated, replace it.
+		if _, err := a.change(ctx, "delete", "healthmonitor", live.Name, live.ID, func(id string) (string, error) {
+			return id, healthMonitors.Delete(ctx, id)
+		}); err != nil {
+			return err
+		}
+		return a.applyHealthMonitor(ctx, poolID, pool, nil)
+	case healthMonitorDiffers(live, spec):
+		_, err := a.change(ctx, "update", "healthmonitor", live.Name, live.ID, func(id string) (string, error) {
+			update := &CloudLoadBalancerHealthMonitorUpdateRequest{Name: live.Name}
+			if spec.Timeout != 0 {
+				update.TimeOut = &spec.Timeout
+			}
+			if spec.Delay != 0 {
+				update.Delay = &spec.Delay
+			}
+			if spec.MaxRetries != 0 {
+				update.MaxRetries = &spec.MaxRetries
+			}
+			if spec.MaxRetriesDown != 0 {
+				update.MaxRetriesDown = &spec.MaxRetriesDown
+			}
+			if spec.HTTPMethod != "" {
+				update.HTTPMethod = &spec.HTTPMethod
+			}
+			if spec.URLPath != "" {
+				update.URLPath = &spec.URLPath
+			}
+			if spec.ExpectedCodes != "" {
+				update.ExpectedCodes = &spec.ExpectedCodes
+			}
+			_, err := healthMonitors.Update(ctx, id, update)
+			return id, err
+		})
+		return err
+	}
+	return nil
+}
+
+func (a *loadBalancerApplier) applyMembers(ctx context.Context, poolID string, pool LoadBalancerPoolSpec, live []*CloudLoadBalancerMember) error {
+	members := a.lbs.Members()
+	byKey := make(map[string]*CloudLoadBalancerMember, len(live))
+	for _, member := range live {
+		byKey[memberKey(member.Address, member.ProtocolPort)] = member
+	}
+	wanted := make(map[string]bool, len(pool.Members))
+	for _, spec := range pool.Members {
+		spec := spec
+		key := memberKey(spec.Address, spec.Port)
+		wanted[key] = true
+		name := spec.Name
+		if name == "" {
+			name = key
+		}
+		existing, ok := byKey[key]
+		if !ok {
+			_, err := a.change(ctx, "create", "member", name, "", func(string) (string, error) {
+				member, err := members.Create(ctx, poolID, &CloudLoadBalancerMemberCreateRequest{
+					Name:         name,
+					Weight:       spec.Weight,
+					Address:      spec.Address,
+					ProtocolPort: spec.Port,
+					Backup:       spec.Backup != nil && *spec.Backup,
+				})
+				if err != nil {
+					return "", err
+				}
+				return member.ID, nil
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if (spec.Name != "" && existing.Name != spec.Name) || (spec.Weight != 0 && existing.Weight != spec.Weight) ||
+			(spec.Backup != nil && existing.Backup != *spec.Backup) {
+			_, err := a.change(ctx, "update", "member", name, existing.ID, func(id string) (string, error) {
+				return id, members.setMember(ctx, poolID, id, name, spec.Weight, spec.Backup)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, member := range live {
+		if wanted[memberKey(member.Address, member.ProtocolPort)] {
+			continue
+		}
+		_, err := a.change(ctx, "delete", "member", member.Name, member.ID, func(id string) (string, error) {
+			return id, members.Delete(ctx, poolID, id)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *loadBalancerApplier) applyListener(ctx context.Context, lbID string, spec LoadBalancerListenerSpec,
+	live *LoadBalancerListenerTree, poolIDs map[string]string) error {
+	listeners := a.lbs.Listeners()
+	name := spec.Name
+	if name == "" {
+		name = fmt.Sprintf("%s-%d", strings.ToLower(string(spec.Protocol)), spec.Port)
+	}
+	defaultPoolID := poolIDs[spec.DefaultPool]
+	if live == nil {
+		id, err := a.change(ctx, "create", "listener", name, "", func(string) (string, error) {
+			request := &CloudLoadBalancerListenerCreateRequest{Protocol: spec.Protocol, ProtocolPort: spec.Port, Name: &name}
+			if defaultPoolID != "" {
+				request.DefaultPoolID = &defaultPoolID
+			}
+			if spec.DefaultTLSContainerRef != "" {
+				request.DefaultTLSContainerRef = &spec.DefaultTLSContainerRef
+			}
+			listener, err := listeners.Create(ctx, lbID, request)
+			if err != nil {
+				return "", err
+			}
+			return listener.ID, nil
+		})
+		if err != nil {
+			return err
+		}
+		live = &LoadBalancerListenerTree{Listener: &CloudLoadBalancerListener{ID: id, Name: name}}
+	} else {
+		listener := live.Listener
+		if !strings.EqualFold(listener.Protocol, string(spec.Protocol)) {
+			return fmt.Errorf("protocol of listener %d cannot change from %s to %s: %w",
+				spec.Port, listener.Protocol, spec.Protocol, ErrCommon)
+		}
+		update := &CloudLoadBalancerListenerUpdateRequest{}
+		changed := false
+		if spec.Name != "" && listener.Name != spec.Name {
+			update.Name, changed = &name, true
+		}
+		if spec.DefaultPool != "" && listener.DefaultPoolID != defaultPoolID {
+			update.DefaultPoolID, changed = &defaultPoolID, true
+		}
+		if spec.DefaultTLSContainerRef != "" && stringValue(listener.DefaultTLSContainerRef) != spec.DefaultTLSContainerRef {
+			update.DefaultTLSContainerRef, changed = &spec.DefaultTLSContainerRef, true
+		}
+		if changed {
+			_, err := a.change(ctx, "update", "listener", name, listener.ID, func(id string) (string, error) {
+				_, err := listeners.Update(ctx, id, update)
+				return id, err
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return a.applyL7Policies(ctx, spec, live, poolIDs)
+}
+
+func (a *loadBalancerApplier) applyL7Policies(ctx context.Context, listener LoadBalancerListenerSpec,
+	live *LoadBalancerListenerTree, poolIDs map[string]string) error {
+	policies := a.lbs.L7Policies()
+	byName := make(map[string]*DetailL7Policy, len(live.L7Policies))
+	rules := make(map[string][]DetailL7PolicyRule, len(live.L7Policies))
+	for _, policy := range live.L7Policies {
+		byName[policy.L7Policy.Name] = policy.L7Policy
+		rules[policy.L7Policy.ID] = policy.Rules
+	}
+	wanted := make(map[string]bool, len(listener.L7Policies))
+	for i, spec := range listener.L7Policies {
+		spec := spec
+		wanted[spec.Name] = true
+		existing, ok := byName[spec.Name]
+		if !ok {
+			_, err := a.change(ctx, "create", "l7policy", spec.Name, "", func(string) (string, error) {
+				policy, err := policies.Create(ctx, live.Listener.ID, spec.createRequest(poolIDs[spec.RedirectPool], i+1))
+				if err != nil {
+					return "", err
+				}
+				return policy.ID, nil
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		redirectPoolID := poolIDs[spec.RedirectPool]
+		if existing.Action != string(spec.Action) || stringValue(existing.RedirectPoolID) != redirectPoolID ||
+			stringValue(existing.RedirectURL) != spec.RedirectURL || stringValue(existing.RedirectPrefix) != spec.RedirectPrefix {
+			_, err := a.change(ctx, "update", "l7policy", spec.Name, existing.ID, func(id string) (string, error) {
+				payload, err := policies.updateRequestOf(ctx, existing)
+				if err != nil {
+					return "", err
+				}
+				payload.Action = spec.Action
+				payload.RedirectPoolID = optionalString(redirectPoolID)
+				payload.RedirectURL = optionalString(spec.RedirectURL)
+				payload.RedirectPrefix = optionalString(spec.RedirectPrefix)
+				_, err = policies.Update(ctx, id, payload)
+				return id, err
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if err := a.applyL7Rules(ctx, existing.ID, spec, rules[existing.ID]); err != nil {
+			return err
+		}
+	}
+	for _, policyTree := range live.L7Policies {
+		policy := policyTree.L7Policy
+		if wanted[policy.Name] {
+			continue
+		}
+		_, err := a.change(ctx, "delete", "l7policy", policy.Name, policy.ID, func(id string) (string, error) {
+			return id, policies.Delete(ctx, id)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return a.orderL7Policies(ctx, listener, byName)
+}
+
+// orderL7Policies moves the policies to their position in the spec. Moving a policy shifts the others,
+// so positions are read again before each move.
+func (a *loadBalancerApplier) orderL7Policies(ctx context.Context, listener LoadBalancerListenerSpec, existing map[string]*DetailL7Policy) error {
+	policies := a.lbs.L7Policies()
+	for i, spec := range listener.L7Policies {
+		policy, ok := existing[spec.Name]
+		if !ok {
+			continue
+		}
+		if !a.opts.DryRun {
+			current, err := policies.Get(ctx, policy.ID)
+			if err != nil {
+				return err
+			}
+			policy = current
+		}
+		if policy.Position == i+1 {
+			continue
+		}
+		position := i + 1
+		_, err := a.change(ctx, "update", "l7policy", spec.Name, policy.ID, func(id string) (string, error) {
+			payload, err := policies.updateRequestOf(ctx, policy)
+			if err != nil {
+				return "", err
+			}
+			payload.Position = position
+			_, err = policies.Update(ctx, id, payload)
+			return id, err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *loadBalancerApplier) applyL7Rules(ctx context.Context, policyID string, spec LoadBalancerL7PolicySpec, live []DetailL7PolicyRule) error {
+	policies := a.lbs.L7Policies()
+	liveKeys := make(map[string]bool, len(live))
+	for _, rule := range live {
+		liveKeys[l7RuleKey(L7RuleType(rule.Type), L7CompareType(rule.CompareType), stringValue(rule.Key), rule.Value, rule.Invert)] = true
+	}
+	wanted := make(map[string]bool, len(spec.Rules))
+	for _, rule := range spec.Rules {
+		rule := rule
+		key := l7RuleKey(rule.Type, rule.CompareType, rule.Key, rule.Value, rule.Invert)
+		wanted[key] = true
+		if liveKeys[key] {
+			continue
+		}
+		_, err := a.change(ctx, "create", "l7rule", spec.Name+" "+key, "", func(string) (string, error) {
+			created, err := policies.CreateL7PolicyRule(ctx, policyID, rule)
+			if err != nil {
+				return "", err
+			}
+			return created.ID, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, rule := range live {
+		key := l7RuleKey(L7RuleType(rule.Type), L7CompareType(rule.CompareType), stringValue(rule.Key), rule.Value, rule.Invert)
+		if wanted[key] {
+			continue
+		}
+		_, err := a.change(ctx, "delete", "l7rule", spec.Name+" "+key, rule.ID, func(id string) (string, error) {
+			return id, policies.DeleteL7PolicyRule(ctx, policyID, id)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p LoadBalancerL7PolicySpec) createRequest(redirectPoolID string, position int) *CreateL7PolicyRequest {
+	request := &CreateL7PolicyRequest{
+		Action:         p.Action,
+		Name:           p.Name,
+		RedirectPoolID: redirectPoolID,
+		RedirectPrefix: optionalString(p.RedirectPrefix),
+		RedirectURL:    p.RedirectURL,
+		Rules:          p.Rules,
+	}
+	if position > 0 {
+		request.Position = strconv.Itoa(position)
+	}
+	return request
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func memberKey(address string, port int) string {
+	return fmt.Sprintf("%s:%d", address, port)
+}
+
+func l7RuleKey(ruleType L7RuleType, compareType L7CompareType, key string, value string, invert bool) string {
+	rule := fmt.Sprintf("%s %s %s", ruleType, compareType, value)
+	if key != "" {
+		rule = fmt.Sprintf("%s[%s] %s %s", ruleType, key, compareType, value)
+	}
+	if invert {
+		rule = "not " + rule
+	}
+	return rule
+}
+
+func sessionPersistenceEqual(live *SessionPersistence, spec *SessionPersistence) bool {
+	if live == nil || spec == nil {
+		return live == nil && spec == nil
+	}
+	return live.Type == spec.Type && stringValue(live.CookieName) == stringValue(spec.CookieName)
+}
+
+func healthMonitorDiffers(live *CloudLoadBalancerHealthMonitor, spec *LoadBalancerHealthMonitorSpec) bool {
+	return (spec.Delay != 0 && live.Delay != spec.Delay) ||
+		(spec.Timeout != 0 && live.TimeOut != spec.Timeout) ||
+		(spec.MaxRetries != 0 && live.MaxRetries != spec.MaxRetries) ||
+		(spec.MaxRetriesDown != 0 && live.MaxRetriesDown != spec.MaxRetriesDown) ||
+		(spec.HTTPMethod != "" && !strings.EqualFold(live.HTTPMethod, string(spec.HTTPMethod))) ||
+		(spec.URLPath != "" && live.URLPath != spec.URLPath) ||
+		(spec.ExpectedCodes != "" && live.ExpectedCodes != spec.ExpectedCodes)
+}
+
+// setMember updates the name, weight and backup flag of a member. Update omits a false backup flag from its
+// payload, which could then never be cleared, so a set flag is always sent. A zero weight and a nil backup
+// flag keep the current ones.
+func (m *cloudLoadBalancerMemberResource) setMember(ctx context.Context, poolID string, id string, name string, weight int, backup *bool) error {
+	var data struct {
+		Member struct {
+			Name   string `json:"name"`
+			Weight int    `json:"weight,omitempty"`
+			Backup *bool  `json:"backup,omitempty"`
+		} `json:"member"`
+	}
+	data.Member.Name = name
+	data.Member.Weight = weight
+	data.Member.Backup = backup
+	req, err := m.client.NewRequest(ctx, http.MethodPut, loadBalancerServiceName, m.itemPath(poolID, id), &data)
+	if err != nil {
+		return err
+	}
+	resp, err := m.client.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
@@ -0,0 +1,544 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLoadBalancerBackend keeps the state of one load balancer. It goes PENDING_UPDATE after each change
+// and rejects changes until it has been seen ACTIVE again.
+type fakeLoadBalancerBackend struct {
+	t         *testing.T
+	mu        sync.Mutex
+	nextID    int
+	changes   int
+	lb        *LoadBalancer
+	pools     []*CloudLoadBalancerPool
+	members   map[string][]*CloudLoadBalancerMember
+	monitors  map[string]*CloudLoadBalancerHealthMonitor
+	listeners []*CloudLoadBalancerListener
+	policies  map[string]*DetailL7Policy
+	rules     map[string][]DetailL7PolicyRule
+	// memberCreateGatewayErrors makes member creates fail with 502 after they were made.
+	memberCreateGatewayErrors int
+}
+
+func newFakeLoadBalancerBackend(t *testing.T) *fakeLoadBalancerBackend {
+	f := &fakeLoadBalancerBackend{
+		t:        t,
+		members:  map[string][]*CloudLoadBalancerMember{},
+		monitors: map[string]*CloudLoadBalancerHealthMonitor{},
+		policies: map[string]*DetailL7Policy{},
+		rules:    map[string][]DetailL7PolicyRule{},
+	}
+	mux.HandleFunc(testlib.LoadBalancerURL("/"), f.serveHTTP)
+	return f
+}
+
+func (f *fakeLoadBalancerBackend) id(kind string) string {
+	f.nextID++
+	return fmt.Sprintf("%s-%d", kind, f.nextID)
+}
+
+func (f *fakeLoadBalancerBackend) decode(r *http.Request, key string, v interface{}) {
+	body := map[string]json.RawMessage{}
+	require.NoError(f.t, json.NewDecoder(r.Body).Decode(&body))
+	require.NoError(f.t, json.Unmarshal(body[key], v))
+}
+
+func (f *fakeLoadBalancerBackend) write(w http.ResponseWriter, key string, v interface{}) {
+	if key != "" {
+		v = map[string]interface{}{key: v}
+	}
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeLoadBalancerBackend) pool(id string) (int, *CloudLoadBalancerPool) {
+	for i, pool := range f.pools {
+		if pool.ID == id {
+			return i, pool
+		}
+	}
+	return -1, nil
+}
+
+func (f *fakeLoadBalancerBackend) listener(id string) (int, *CloudLoadBalancerListener) {
+	for i, listener := range f.listeners {
+		if listener.ID == id {
+			return i, listener
+		}
+	}
+	return -1, nil
+}
+
+// placePolicy moves a policy of a listener to a position and renumbers the policies.
+func (f *fakeLoadBalancerBackend) placePolicy(listener *CloudLoadBalancerListener, policyID string, position int) {
+	ids := []resourceID{}
+	for _, ref := range listener.L7Policies {
+		if ref.ID != policyID {
+			ids = append(ids, ref)
+		}
+	}
+	if position > 0 {
+		if position > len(ids)+1 {
+			position = len(ids) + 1
+		}
+		ids = append(ids[:position-1], append([]resourceID{{ID: policyID}}, ids[position-1:]...)...)
+	}
+	listener.L7Policies = ids
+	for i, ref := range ids {
+		f.policies[ref.ID].Position = i + 1
+	}
+}
+
+func (f *fakeLoadBalancerBackend) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, testlib.LoadBalancerURL("/")), "/")
+	if r.Method != http.MethodGet {
+		if f.lb != nil && f.lb.ProvisioningStatus != loadBalancerActiveStatus {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.changes++
+		if f.lb != nil {
+			defer func() { f.lb.ProvisioningStatus = "PENDING_UPDATE" }()
+		}
+	}
+	switch {
+	case parts[0] == "loadbalancers" && r.Method == http.MethodGet:
+		lbs := []*LoadBalancer{}
+		if f.lb != nil {
+			lbs = append(lbs, f.lb)
+		}
+		f.write(w, "loadbalancers", lbs)
+	case parts[0] == "loadbalancers":
+		var req LoadBalancerCreateRequest
+		f.decode(r, "loadbalancer", &req)
+		f.lb = &LoadBalancer{ID: f.id("lb"), Name: req.Name, Description: req.Description}
+		f.write(w, "loadbalancer", f.lb)
+	case parts[0] == "loadbalancer" && len(parts) == 2:
+		require.Equal(f.t, http.MethodGet, r.Method)
+		f.write(w, "", f.lb)
+		f.lb.ProvisioningStatus = loadBalancerActiveStatus
+	case parts[0] == "loadbalancer" && parts[2] == "pools" && r.Method == http.MethodGet:
+		f.write(w, "pools", f.pools)
+	case parts[0] == "loadbalancer" && parts[2] == "pools":
+		var req CloudLoadBalancerPoolCreateRequest
+		f.decode(r, "pool", &req)
+		pool := &CloudLoadBalancerPool{ID: f.id("pool"), Name: *req.Name, Protocol: string(req.Protocol),
+			LBAlgorithm: string(req.LBAlgorithm), SessionPersistence: req.SessionPersistence}
+		f.pools = append(f.pools, pool)
+		f.write(w, "pool", pool)
+	case parts[0] == "loadbalancer" && parts[2] == "listeners" && r.Method == http.MethodGet:
+		f.write(w, "listeners", f.listeners)
+	case parts[0] == "loadbalancer" && parts[2] == "listeners":
+		var req CloudLoadBalancerListenerCreateRequest
+		f.decode(r, "listener", &req)
+		listener := &CloudLoadBalancerListener{ID: f.id("listener"), Name: *req.Name, Protocol: string(req.Protocol),
+			ProtocolPort: req.ProtocolPort, DefaultPoolID: stringValue(req.DefaultPoolID), L7Policies: []resourceID{}}
+		f.listeners = append(f.listeners, listener)
+		f.write(w, "listener", listener)
+	case parts[0] == "pool" && len(parts) == 2:
+		i, pool := f.pool(parts[1])
+		require.NotNil(f.t, pool)
+		switch r.Method {
+		case http.MethodPut:
+			var req CloudLoadBalancerPoolUpdateRequest
+			f.decode(r, "pool", &req)
+			if req.LBAlgorithm != nil {
+				pool.LBAlgorithm = string(*req.LBAlgorithm)
+			}
+			pool.SessionPersistence = req.SessionPersistence
+			f.write(w, "pool", pool)
+		case http.MethodDelete:
+			f.pools = append(f.pools[:i], f.pools[i+1:]...)
+			delete(f.members, pool.ID)
+		}
+	case parts[0] == "pool" && parts[2] == "member" && len(parts) == 3 && r.Method == http.MethodGet:
+		members := f.members[parts[1]]
+		if members == nil {
+			members = []*CloudLoadBalancerMember{}
+		}
+		f.write(w, "members", members)
+	case parts[0] == "pool" && parts[2] == "member" && len(parts) == 3:
+		var req CloudLoadBalancerMemberCreateRequest
+		f.decode(r, "member", &req)
+		member := &CloudLoadBalancerMember{ID: f.id("member"), Name: req.Name, Address: req.Address,
+			ProtocolPort: req.ProtocolPort, Weight: 1, Backup: req.Backup}
+		if req.Weight != 0 {
+			member.Weight = req.Weight
+		}
+		f.members[parts[1]] = append(f.members[parts[1]], member)
+		if f.memberCreateGatewayErrors > 0 {
+			f.memberCreateGatewayErrors--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		f.write(w, "member", member)
+	case parts[0] == "pool" && parts[2] == "member":
+		members := f.members[parts[1]]
+		for i, member := range members {
+			if member.ID != parts[3] {
+				continue
+			}
+			if r.Method == http.MethodDelete {
+				f.members[parts[1]] = append(members[:i], members[i+1:]...)
+				return
+			}
+			var req struct {
+				Name   string `json:"name"`
+				Weight int    `json:"weight"`
+				Backup *bool  `json:"backup"`
+			}
+			f.decode(r, "member", &req)
+			member.Name = req.Name
+			if req.Backup != nil {
+				member.Backup = *req.Backup
+			}
+			if req.Weight != 0 {
+				member.Weight = req.Weight
+			}
+			f.write(w, "member", member)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case parts[0] == "pool" && parts[2] == "healthmonitor":
+		var req CloudLoadBalancerHealthMonitorCreateRequest
+		f.decode(r, "healthmonitor", &req)
+		hm := &CloudLoadBalancerHealthMonitor{ID: f.id("hm"), Name: req.Name, Type: string(req.Type), Delay: req.Delay,
+			TimeOut: req.TimeOut, MaxRetries: req.MaxRetries, HTTPMethod: "GET", URLPath: req.URLPath, ExpectedCodes: "200"}
+		f.monitors[hm.ID] = hm
+		_, pool := f.pool(parts[1])
+		pool.HealthMonitorID = hm.ID
+		f.write(w, "healthmonitor", hm)
+	case parts[0] == "healthmonitor":
+		hm := f.monitors[parts[1]]
+		require.NotNil(f.t, hm)
+		switch r.Method {
+		case http.MethodGet:
+			f.write(w, "", hm)
+		case http.MethodDelete:
+			delete(f.monitors, hm.ID)
+			for _, pool := range f.pools {
+				if pool.HealthMonitorID == hm.ID {
+					pool.HealthMonitorID = ""
+				}
+			}
+		}
+	case parts[0] == "listener" && len(parts) == 2:
+		i, listener := f.listener(parts[1])
+		require.NotNil(f.t, listener)
+		switch r.Method {
+		case http.MethodGet:
+			f.write(w, "", listener)
+		case http.MethodPut:
+			var req CloudLoadBalancerListenerUpdateRequest
+			f.decode(r, "listener", &req)
+			if req.DefaultPoolID != nil {
+				listener.DefaultPoolID = *req.DefaultPoolID
+			}
+			f.write(w, "listener", listener)
+		case http.MethodDelete:
+			f.listeners = append(f.listeners[:i], f.listeners[i+1:]...)
+		}
+	case parts[0] == "listener" && parts[2] == "l7policy":
+		var req CreateL7PolicyRequest
+		f.decode(r, "l7policy", &req)
+		policy := &DetailL7Policy{ID: f.id("policy"), ListenerID: parts[1], Name: req.Name, Action: string(req.Action),
+			RedirectPrefix: req.RedirectPrefix}
+		if req.RedirectPoolID != "" {
+			policy.RedirectPoolID = &req.RedirectPoolID
+		}
+		f.policies[policy.ID] = policy
+		for _, rule := range req.Rules {
+			f.rules[policy.ID] = append(f.rules[policy.ID], DetailL7PolicyRule{ID: f.id("rule"), Type: string(rule.Type),
+				CompareType: string(rule.CompareType), Value: rule.Value, Invert: rule.Invert})
+		}
+		position, _ := strconv.Atoi(req.Position)
+		_, listener := f.listener(parts[1])
+		f.placePolicy(listener, policy.ID, position)
+		f.write(w, "l7policy", policy)
+	case parts[0] == "l7policy" && len(parts) == 2:
+		policy := f.policies[parts[1]]
+		require.NotNil(f.t, policy)
+		_, listener := f.listener(policy.ListenerID)
+		switch r.Method {
+		case http.MethodGet:
+			f.write(w, "", policy)
+		case http.MethodPut:
+			var req UpdateL7PolicyRequest
+			f.decode(r, "l7policy", &req)
+			policy.Action, policy.RedirectPoolID = string(req.Action), req.RedirectPoolID
+			policy.RedirectURL, policy.RedirectPrefix = req.RedirectURL, req.RedirectPrefix
+			f.placePolicy(listener, policy.ID, req.Position)
+			f.write(w, "l7policy", policy)
+		case http.MethodDelete:
+			f.placePolicy(listener, policy.ID, 0)
+			delete(f.policies, policy.ID)
+			delete(f.rules, policy.ID)
+		}
+	case parts[0] == "l7policy" && len(parts) == 3 && r.Method == http.MethodGet:
+		f.write(w, "rules", f.rules[parts[1]])
+	case parts[0] == "l7policy" && len(parts) == 3:
+		var req L7PolicyRuleRequest
+		f.decode(r, "rule", &req)
+		rule := DetailL7PolicyRule{ID: f.id("rule"), Type: string(req.Type), CompareType: string(req.CompareType),
+			Value: req.Value, Invert: req.Invert}
+		f.rules[parts[1]] = append(f.rules[parts[1]], rule)
+		f.write(w, "rule", rule)
+	case parts[0] == "l7policy" && r.Method == http.MethodDelete:
+		rules := f.rules[parts[1]]
+		for i, rule := range rules {
+			if rule.ID == parts[3] {
+				f.rules[parts[1]] = append(rules[:i], rules[i+1:]...)
+			}
+		}
+	default:
+		f.t.Fatalf("unexpected request %s %s", r.Method, r.URL.Path)
+	}
+}
+
+func changeSummary(report *LoadBalancerApplyReport) []string {
+	summary := make([]string, 0, len(report.Changes))
+	for _, change := range report.Changes {
+		summary = append(summary, change.Action+" "+change.Kind+" "+change.Name)
+	}
+	return summary
+}
+
+func testLoadBalancerSpec() *LoadBalancerSpec {
+	return &LoadBalancerSpec{
+		Name:        "web-lb",
+		NetworkType: "external",
+		Type:        "small",
+		Pools: []LoadBalancerPoolSpec{
+			{
+				Name:          "web",
+				Protocol:      "HTTP",
+				Algorithm:     "ROUND_ROBIN",
+				HealthMonitor: &LoadBalancerHealthMonitorSpec{Type: "HTTP", Delay: 5, Timeout: 3, MaxRetries: 3, URLPath: "/healthz"},
+				Members: []LoadBalancerMemberSpec{
+					{Address: "10.0.0.10", Port: 8080},
+					{Address: "10.0.0.11", Port: 8080},
+				},
+			},
+			{
+				Name:      "api",
+				Protocol:  "HTTP",
+				Algorithm: "LEAST_CONNECTIONS",
+				Members:   []LoadBalancerMemberSpec{{Name: "api-1", Address: "10.0.0.20", Port: 9000}},
+			},
+		},
+		Listeners: []LoadBalancerListenerSpec{
+			{
+				Name:        "http",
+				Protocol:    "HTTP",
+				Port:        80,
+				DefaultPool: "web",
+				L7Policies: []LoadBalancerL7PolicySpec{
+					{
+						Name:         "api",
+						Action:       L7PolicyActionRedirectToPool,
+						RedirectPool: "api",
+						Rules:        []L7PolicyRuleRequest{{Type: L7RuleTypePath, CompareType: L7CompareTypeStartsWith, Value: "/api"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestLoadBalancerApply(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+	backend := newFakeLoadBalancerBackend(t)
+
+	spec := testLoadBalancerSpec()
+	report, err := client.CloudLoadBalancer.Apply(ctx, spec, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "lb-1", report.LoadBalancerID)
+	assert.Equal(t, []string{
+		"create loadbalancer web-lb",
+		"create pool web",
+		"create healthmonitor web-healthmonitor",
+		"create member 10.0.0.10:8080",
+		"create member 10.0.0.11:8080",
+		"create pool api",
+		"create member api-1",
+		"create listener http",
+		"create l7policy api",
+	}, changeSummary(report))
+	for _, change := range report.Changes {
+		assert.NotEmpty(t, change.ID)
+	}
+	require.Len(t, backend.listeners, 1)
+	assert.Equal(t, backend.pools[0].ID, backend.listeners[0].DefaultPoolID)
+
+	report, err = client.CloudLoadBalancer.Apply(ctx, spec, nil)
+	require.NoError(t, err)
+	assert.Empty(t, report.Changes)
+
+	spec.Pools[0].HealthMonitor = nil
+	spec.Pools[0].Members[1].Address = "10.0.0.12"
+	spec.Pools = spec.Pools[:1]
+	spec.Listeners[0].L7Policies = []LoadBalancerL7PolicySpec{
+		{
+			Name:           "static",
+			Action:         L7PolicyActionRedirectPrefix,
+			RedirectPrefix: "https://static.example.com",
+			Rules:          []L7PolicyRuleRequest{{Type: L7RuleTypePath, CompareType: L7CompareTypeStartsWith, Value: "/static"}},
+		},
+	}
+	expected := []string{
+		"delete healthmonitor web-healthmonitor",
+		"create member 10.0.0.12:8080",
+		"delete member 10.0.0.11:8080",
+		"create l7policy static",
+		"delete l7policy api",
+		"delete pool api",
+	}
+
+	changes := backend.changes
+	report, err = client.CloudLoadBalancer.Apply(ctx, spec, &LoadBalancerApplyOptions{DryRun: true, Prune: true})
+	require.NoError(t, err)
+	assert.Equal(t, expected, changeSummary(report))
+	assert.Equal(t, changes, backend.changes)
+
+	report, err = client.CloudLoadBalancer.Apply(ctx, spec, &LoadBalancerApplyOptions{Prune: true})
+	require.NoError(t, err)
+	assert.Equal(t, expected, changeSummary(report))
+	require.Len(t, backend.pools, 1)
+	assert.Empty(t, backend.monitors)
+	require.Len(t, backend.listeners[0].L7Policies, 1)
+	assert.Equal(t, 1, backend.policies[backend.listeners[0].L7Policies[0].ID].Position)
+
+	report, err = client.CloudLoadBalancer.Apply(ctx, spec, &LoadBalancerApplyOptions{Prune: true})
+	require.NoError(t, err)
+	assert.Empty(t, report.Changes)
+}
+
+func TestLoadBalancerApplyReordersPolicies(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+	backend := newFakeLoadBalancerBackend(t)
+
+	spec := testLoadBalancerSpec()
+	spec.Listeners[0].L7Policies = append(spec.Listeners[0].L7Policies, LoadBalancerL7PolicySpec{
+		Name:   "deny",
+		Action: L7PolicyActionReject,
+		Rules:  []L7PolicyRuleRequest{{Type: L7RuleTypePath, CompareType: L7CompareTypeStartsWith, Value: "/admin"}},
+	})
+	_, err := client.CloudLoadBalancer.Apply(ctx, spec, nil)
+	require.NoError(t, err)
+
+	policies := spec.Listeners[0].L7Policies
+	policies[0], policies[1] = policies[1], policies[0]
+	policies[1].Rules = append(policies[1].Rules, L7PolicyRuleRequest{Type: L7RuleTypeHostName,
+		CompareType: L7CompareTypeEqualTo, Value: "api.example.com"})
+	report, err := client.CloudLoadBalancer.Apply(ctx, spec, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"create l7rule api HOST_NAME EQUAL_TO api.example.com",
+		"update l7policy deny",
+	}, changeSummary(report))
+	var names []string
+	for _, ref := range backend.listeners[0].L7Policies {
+		names = append(names, backend.policies[ref.ID].Name)
+	}
+	assert.Equal(t, []string{"deny", "api"}, names)
+}
+
+func TestLoadBalancerApplyUnsetFields(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+	backend := newFakeLoadBalancerBackend(t)
+
+	backup, noBackup := true, false
+	spec := testLoadBalancerSpec()
+	spec.Pools[1].Members[0].Backup = &backup
+	_, err := client.CloudLoadBalancer.Apply(ctx, spec, nil)
+	require.NoError(t, err)
+	defaultPoolID := backend.listeners[0].DefaultPoolID
+
+	// An unset backup flag keeps the live one, also when the member is updated for another field.
+	spec.Pools[1].Members[0].Backup = nil
+	report, err := client.CloudLoadBalancer.Apply(ctx, spec, nil)
+	require.NoError(t, err)
+	assert.Empty(t, report.Changes)
+	spec.Pools[1].Members[0].Name = "api-1-renamed"
+	report, err = client.CloudLoadBalancer.Apply(ctx, spec, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"update member api-1-renamed"}, changeSummary(report))
+	assert.True(t, backend.members[backend.pools[1].ID][0].Backup)
+	spec.Pools[1].Members[0].Name = "api-1"
+
+	spec.Pools[1].Members[0].Backup = &noBackup
+	spec.Listeners[0].DefaultPool = ""
+	report, err = client.CloudLoadBalancer.Apply(ctx, spec, &LoadBalancerApplyOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"update member api-1"}, changeSummary(report))
+	report, err = client.CloudLoadBalancer.Apply(ctx, spec, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"update member api-1"}, changeSummary(report))
+	assert.False(t, backend.members[backend.pools[1].ID][0].Backup)
+	assert.Equal(t, defaultPoolID, backend.listeners[0].DefaultPoolID)
+
+	report, err = client.CloudLoadBalancer.Apply(ctx, spec, nil)
+	require.NoError(t, err)
+	assert.Empty(t, report.Changes)
+}
+
+func TestLoadBalancerApplyDoesNotRetryCreates(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+	backend := newFakeLoadBalancerBackend(t)
+
+	spec := testLoadBalancerSpec()
+	_, err := client.CloudLoadBalancer.Apply(ctx, spec, nil)
+	require.NoError(t, err)
+
+	backend.memberCreateGatewayErrors = 1
+	spec.Pools[1].Members = append(spec.Pools[1].Members, LoadBalancerMemberSpec{Name: "api-2", Address: "10.0.0.21", Port: 9000})
+	_, err = client.CloudLoadBalancer.Apply(ctx, spec, nil)
+	assert.True(t, errors.Is(err, ErrTransient))
+	assert.Len(t, backend.members[backend.pools[1].ID], 2)
+
+	report, err := client.CloudLoadBalancer.Apply(ctx, spec, nil)
+	require.NoError(t, err)
+	assert.Empty(t, report.Changes)
+	assert.Len(t, backend.members[backend.pools[1].ID], 2)
+}
+
+func TestLoadBalancerSpecValidate(t *testing.T) {
+	spec := testLoadBalancerSpec()
+	require.NoError(t, spec.Validate())
+
+	spec.Listeners[0].DefaultPool = "missing"
+	assert.True(t, errors.Is(spec.Validate(), ErrCommon))
+
+	spec = testLoadBalancerSpec()
+	spec.Pools[0].Members = append(spec.Pools[0].Members, spec.Pools[0].Members[0])
+	assert.True(t, errors.Is(spec.Validate(), ErrCommon))
+
+	spec = testLoadBalancerSpec()
+	spec.Listeners[0].L7Policies[0].RedirectURL = "https://example.com"
+	assert.True(t, errors.Is(spec.Validate(), ErrCommon))
+}
@@ -0,0 +1,198 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"sort"
+)
+
+// LoadBalancerTree is a snapshot of a load balancer with all its sub resources. Listeners are sorted by port,
+// pools by name, members by address and port and L7 policies by position, so that two snapshots can be diffed.
+type LoadBalancerTree struct {
+	LoadBalancer *LoadBalancer               `json:"loadbalancer" yaml:"loadbalancer"`
+	Listeners    []*LoadBalancerListenerTree `json:"listeners" yaml:"listeners"`
+	Pools        []*LoadBalancerPoolTree     `json:"pools" yaml:"pools"`
+}
+
+// LoadBalancerListenerTree is a listener with its L7 policies.
+type LoadBalancerListenerTree struct {
+	Listener   *CloudLoadBalancerListener  `json:"listener" yaml:"listener"`
+	L7Policies []*LoadBalancerL7PolicyTree `json:"l7policies" yaml:"l7policies"`
+}
+
+// LoadBalancerL7PolicyTree is an L7 policy with its rules.
+type LoadBalancerL7PolicyTree struct {
+	L7Policy *DetailL7Policy      `json:"l7policy" yaml:"l7policy"`
+	Rules    []DetailL7PolicyRule `json:"rules" yaml:"rules"`
+}
+
+// LoadBalancerPoolTree is a pool with its health monitor and members.
+type LoadBalancerPoolTree struct {
+	Pool          *CloudLoadBalancerPool          `json:"pool" yaml:"pool"`
+	HealthMonitor *CloudLoadBalancerHealthMonitor `json:"healthmonitor,omitempty" yaml:"healthmonitor,omitempty"`
+	Members       []*CloudLoadBalancerMember      `json:"members" yaml:"members"`
+}
+
+// Describe fetches a load balancer with its listeners, pools, members, health monitors, L7 policies and rules.
+// Sub resources are fetched one after another, not concurrently, because the client is not safe for concurrent
+// requests: Do refreshes the keystone token and NewRequest sets the auth type on the shared client.
+func (l *cloudLoadBalancerService) Describe(ctx context.Context, id string) (*LoadBalancerTree, error) {
+	lb, err := l.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tree := &LoadBalancerTree{LoadBalancer: lb}
+
+	listeners, err := l.Listeners().List(ctx, id, &ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pools, err := l.Pools().List(ctx, id, &ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	tree.Pools = make([]*LoadBalancerPoolTree, len(pools))
+	for i, pool := range pools {
+		poolTree := &LoadBalancerPoolTree{Pool: pool, HealthMonitor: pool.HealthMonitor}
+		tree.Pools[i] = poolTree
+		if poolTree.Members, err = l.Members().List(ctx, pool.ID, &ListOptions{}); err != nil {
+			return nil, err
+		}
+		if poolTree.HealthMonitor == nil && pool.HealthMonitorID != "" {
+			if poolTree.HealthMonitor, err = l.HealthMonitors().Get(ctx, pool.HealthMonitorID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	tree.Listeners = make([]*LoadBalancerListenerTree, len(listeners))
+	for i, listener := range listeners {
+		listenerTree := &LoadBalancerListenerTree{
+			Listener:   listener,
+			L7Policies: make([]*LoadBalancerL7PolicyTree, len(listener.L7Policies)),
+		}
+		tree.Listeners[i] = listenerTree
+		for j, ref := range listener.L7Policies {
+			policyTree := &LoadBalancerL7PolicyTree{}
+			listenerTree.L7Policies[j] = policyTree
+			if policyTree.L7Policy, err = l.L7Policies().Get(ctx, ref.ID); err != nil {
+				return nil, err
+			}
+			if policyTree.Rules, err = l.L7Policies().ListL7PolicyRules(ctx, ref.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	tree.sort()
+	return tree, nil
+}
+
+func (t *LoadBalancerTree) sort() {
+	sort.SliceStable(t.Listeners, func(i, j int) bool {
+		return t.Listeners[i].Listener.ProtocolPort < t.Listeners[j].Listener.ProtocolPort
+	})
+	for _, listener := range t.Listeners {
+		policies := listener.L7Policies
+		sort.SliceStable(policies, func(i, j int) bool {
+			return policies[i].L7Policy.Position < policies[j].L7Policy.Position
+		})
+	}
+	sort.SliceStable(t.Pools, func(i, j int) bool {
+		return t.Pools[i].Pool.Name < t.Pools[j].Pool.Name
+	})
+	for _, pool := range t.Pools {
+		members := pool.Members
+		sort.SliceStable(members, func(i, j int) bool {
+			if members[i].Address != members[j].Address {
+				return members[i].Address < members[j].Address
+			}
+			return members[i].ProtocolPort < members[j].ProtocolPort
+		})
+	}
+}
+
+// Spec returns the spec which recreates the load balancer with Apply, for example in another region.
+// The VPC network is only kept for internal load balancers, and TLS container references are kept as is.
+func (t *LoadBalancerTree) Spec() *LoadBalancerSpec {
+	lb := t.LoadBalancer
+	spec := &LoadBalancerSpec{
+		Name:        lb.Name,
+		Description: lb.Description,
+		Type:        LoadBalancerType(lb.Type),
+		NetworkType: LoadBalancerNetworkType(lb.NetworkType),
+	}
+	if spec.NetworkType != LoadBalancerNetworkTypeExternal {
+		spec.VPCNetworkID = lb.VipNetworkID
+	}
+	poolNames := make(map[string]string, len(t.Pools))
+	for _, poolTree := range t.Pools {
+		pool := poolTree.Pool
+		name := pool.Name
+		if name == "" {
+			name = pool.ID
+		}
+		poolNames[pool.ID] = name
+		poolSpec := LoadBalancerPoolSpec{
+			Name:               name,
+			Protocol:           LoadBalancerProtocol(pool.Protocol),
+			Algorithm:          LBAlgorithm(pool.LBAlgorithm),
+			SessionPersistence: pool.SessionPersistence,
+		}
+		if hm := poolTree.HealthMonitor; hm != nil {
+			poolSpec.HealthMonitor = &LoadBalancerHealthMonitorSpec{
+				Type:           HealthMonitorType(hm.Type),
+				Delay:          hm.Delay,
+				Timeout:        hm.TimeOut,
+				MaxRetries:     hm.MaxRetries,
+				MaxRetriesDown: hm.MaxRetriesDown,
+				HTTPMethod:     HealthMonitorHTTPMethod(hm.HTTPMethod),
+				URLPath:        hm.URLPath,
+				ExpectedCodes:  hm.ExpectedCodes,
+			}
+		}
+		for _, member := range poolTree.Members {
+			backup := member.Backup
+			poolSpec.Members = append(poolSpec.Members, LoadBalancerMemberSpec{
+				Name:    member.Name,
+				Address: member.Address,
+				Port:    member.ProtocolPort,
+				Weight:  member.Weight,
+				Backup:  &backup,
+			})
+		}
+		spec.Pools = append(spec.Pools, poolSpec)
+	}
+	for _, listenerTree := range t.Listeners {
+		listener := listenerTree.Listener
+		listenerSpec := LoadBalancerListenerSpec{
+			Name:                   listener.Name,
+			Protocol:               LoadBalancerProtocol(listener.Protocol),
+			Port:                   listener.ProtocolPort,
+			DefaultPool:            poolNames[listener.DefaultPoolID],
+			DefaultTLSContainerRef: stringValue(listener.DefaultTLSContainerRef),
+		}
+		for _, policyTree := range listenerTree.L7Policies {
+			policy := policyTree.L7Policy
+			policySpec := LoadBalancerL7PolicySpec{
+				Name:           policy.Name,
+				Action:         L7PolicyAction(policy.Action),
+				RedirectPool:   poolNames[stringValue(policy.RedirectPoolID)],
+				RedirectURL:    stringValue(policy.RedirectURL),
+				RedirectPrefix: stringValue(policy.RedirectPrefix),
+			}
+			for _, rule := range policyTree.Rules {
+				policySpec.Rules = append(policySpec.Rules, L7PolicyRuleRequest{
+					Invert:      rule.Invert,
+					Type:        L7RuleType(rule.Type),
+					CompareType: L7CompareType(rule.CompareType),
+					Key:         stringValue(rule.Key),
+					Value:       rule.Value,
+				})
+			}
+			listenerSpec.L7Policies = append(listenerSpec.L7Policies, policySpec)
+		}
+		spec.Listeners = append(spec.Listeners, listenerSpec)
+	}
+	return spec
+}
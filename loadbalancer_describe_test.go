@@ -0,0 +1,54 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadBalancerDescribe(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+	newFakeLoadBalancerBackend(t)
+
+	report, err := client.CloudLoadBalancer.Apply(ctx, testLoadBalancerSpec(), nil)
+	require.NoError(t, err)
+
+	tree, err := client.CloudLoadBalancer.Describe(ctx, report.LoadBalancerID)
+	require.NoError(t, err)
+	assert.Equal(t, "web-lb", tree.LoadBalancer.Name)
+	require.Len(t, tree.Pools, 2)
+	assert.Equal(t, "api", tree.Pools[0].Pool.Name)
+	assert.Nil(t, tree.Pools[0].HealthMonitor)
+	assert.Equal(t, "web", tree.Pools[1].Pool.Name)
+	require.NotNil(t, tree.Pools[1].HealthMonitor)
+	assert.Equal(t, "/healthz", tree.Pools[1].HealthMonitor.URLPath)
+	require.Len(t, tree.Pools[1].Members, 2)
+	assert.Equal(t, "10.0.0.10", tree.Pools[1].Members[0].Address)
+	require.Len(t, tree.Listeners, 1)
+	assert.Equal(t, 80, tree.Listeners[0].Listener.ProtocolPort)
+	require.Len(t, tree.Listeners[0].L7Policies, 1)
+	assert.Equal(t, "api", tree.Listeners[0].L7Policies[0].L7Policy.Name)
+	require.Len(t, tree.Listeners[0].L7Policies[0].Rules, 1)
+	assert.Equal(t, "/api", tree.Listeners[0].L7Policies[0].Rules[0].Value)
+
+	buf, err := json.Marshal(tree)
+	require.NoError(t, err)
+	var decoded LoadBalancerTree
+	require.NoError(t, json.Unmarshal(buf, &decoded))
+	assert.Equal(t, tree, &decoded)
+
+	spec := decoded.Spec()
+	require.NoError(t, spec.Validate())
+	assert.Equal(t, "web", spec.Listeners[0].DefaultPool)
+	assert.Equal(t, "api", spec.Listeners[0].L7Policies[0].RedirectPool)
+	report, err = client.CloudLoadBalancer.Apply(ctx, spec, &LoadBalancerApplyOptions{Prune: true})
+	require.NoError(t, err)
+	assert.Empty(t, report.Changes)
+}
@@ -32,32 +32,32 @@ type CloudLoadBalancerHealthMonitor struct {
 
 // CloudLoadBalancerHealthMonitorCreateRequest represent the request bodfor creating a health monitor
 type CloudLoadBalancerHealthMonitorCreateRequest struct {
-	Name           string  `json:"name"`
-	Type           string  `json:"type"`
-	TimeOut        int     `json:"timeout,omitempty"`
-	PoolID         string  `json:"pool_id"`
-	Delay          int     `json:"delay,omitempty"`
-	MaxRetries     int     `json:"max_retries,omitempty"`
-	MaxRetriesDown int     `json:"max_retries_down,omitempty"`
-	HTTPMethod     string  `json:"http_method,omitempty"`
-	HTTPVersion    float32 `json:"http_version,omitempty"`
-	URLPath        string  `json:"url_path,omitempty"`
-	ExpectedCodes  string  `json:"expected_codes,omitempty"`
-	DomainName     string  `json:"domain_name,omitempty"`
+	Name           string                  `json:"name"`
+	Type           HealthMonitorType       `json:"type"`
+	TimeOut        int                     `json:"timeout,omitempty"`
+	PoolID         string                  `json:"pool_id"`
+	Delay          int                     `json:"delay,omitempty"`
+	MaxRetries     int                     `json:"max_retries,omitempty"`
+	MaxRetriesDown int                     `json:"max_retries_down,omitempty"`
+	HTTPMethod     HealthMonitorHTTPMethod `json:"http_method,omitempty"`
+	HTTPVersion    float32                 `json:"http_version,omitempty"`
+	URLPath        string                  `json:"url_path,omitempty"`
+	ExpectedCodes  string                  `json:"expected_codes,omitempty"`
+	DomainName     string                  `json:"domain_name,omitempty"`
 }
 
 // CloudLoadBalancerHealthMonitorUpdateRequest represent the request bodfor updating a health monitor
 type CloudLoadBalancerHealthMonitorUpdateRequest struct {
-	Name           string   `json:"name"`
-	TimeOut        *int     `json:"timeout,omitempty"`
-	Delay          *int     `json:"delay,omitempty"`
-	MaxRetries     *int     `json:"max_retries,omitempty"`
-	MaxRetriesDown *int     `json:"max_retries_down,omitempty"`
-	HTTPMethod     *string  `json:"http_method,omitempty"`
-	HTTPVersion    *float32 `json:"http_version,omitempty"`
-	URLPath        *string  `json:"url_path,omitempty"`
-	ExpectedCodes  *string  `json:"expected_codes,omitempty"`
-	DomainName     *string  `json:"domain_name,omitempty"`
+	Name           string                   `json:"name"`
+	TimeOut        *int                     `json:"timeout,omitempty"`
+	Delay          *int                     `json:"delay,omitempty"`
+	MaxRetries     *int                     `json:"max_retries,omitempty"`
+	MaxRetriesDown *int                     `json:"max_retries_down,omitempty"`
+	HTTPMethod     *HealthMonitorHTTPMethod `json:"http_method,omitempty"`
+	HTTPVersion    *float32                 `json:"http_version,omitempty"`
+	URLPath        *string                  `json:"url_path,omitempty"`
+	ExpectedCodes  *string                  `json:"expected_codes,omitempty"`
+	DomainName     *string                  `json:"domain_name,omitempty"`
 }
 
 type cloudLoadBalancerHealthMonitorResource struct {
@@ -105,6 +105,9 @@ func (h *cloudLoadBalancerHealthMonitorResource) Get(ctx context.Context, hmID s
 
 // Create creates a health monitor for a pool
 func (h *cloudLoadBalancerHealthMonitorResource) Create(ctx context.Context, poolID string, hmcr *CloudLoadBalancerHealthMonitorCreateRequest) (*CloudLoadBalancerHealthMonitor, error) {
+	if err := hmcr.Validate(); err != nil {
+		return nil, err
+	}
 	var data struct {
 		CloudLoadBalancerHealthMonitor *CloudLoadBalancerHealthMonitorCreateRequest `json:"healthmonitor"`
 	}
@@ -148,6 +151,9 @@ func (h *cloudLoadBalancerHealthMonitorResource) Delete(ctx context.Context, hmI
 
 // Update - updates a health monitor
 func (h *cloudLoadBalancerHealthMonitorResource) Update(ctx context.Context, hmID string, hmur *CloudLoadBalancerHealthMonitorUpdateRequest) (*CloudLoadBalancerHealthMonitor, error) {
+	if err := hmur.Validate(); err != nil {
+		return nil, err
+	}
 	var data struct {
 		CloudLoadBalancerHealthMonitor *CloudLoadBalancerHealthMonitorUpdateRequest `json:"healthmonitor"`
 	}
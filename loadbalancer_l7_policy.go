@@ -3,32 +3,41 @@ package gobizfly
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 )
 
+var _ L7PolicyService = (*cloudLoadBalancerL7PolicyResource)(nil)
+
 // L7PolicyService is an interface to interact with Bizfly API L7Policy endpoint.
 type L7PolicyService interface {
+	List(ctx context.Context, listenerID string) ([]*DetailL7Policy, error)
 	Create(ctx context.Context, listenerID string, payload *CreateL7PolicyRequest) (*DetailL7Policy, error)
 	Get(ctx context.Context, policyID string) (*DetailL7Policy, error)
 	Update(ctx context.Context, policyID string, payload *UpdateL7PolicyRequest) (*DetailL7Policy, error)
 	Delete(ctx context.Context, policyID string) error
+	Reorder(ctx context.Context, listenerID string, policyIDs []string) error
 	ListL7PolicyRules(ctx context.Context, policyID string) ([]DetailL7PolicyRule, error)
 	CreateL7PolicyRule(ctx context.Context, policyID string, payload L7PolicyRuleRequest) (*DetailL7PolicyRule, error)
+	GetL7PolicyRule(ctx context.Context, policyID string, ruleID string) (*DetailL7PolicyRule, error)
+	UpdateL7PolicyRule(ctx context.Context, policyID string, ruleID string, payload L7PolicyRuleRequest) (*DetailL7PolicyRule, error)
+	DeleteL7PolicyRule(ctx context.Context, policyID string, ruleID string) error
 }
 
 // L7PolicyRuleRequest is rule of l7 policy payload
 type L7PolicyRuleRequest struct {
-	Invert      bool   `json:"invert"`
-	Type        string `json:"type"`
-	CompareType string `json:"compare_type"`
-	Key         string `json:"key"`
-	Value       string `json:"value"`
+	Invert      bool          `json:"invert"`
+	Type        L7RuleType    `json:"type"`
+	CompareType L7CompareType `json:"compare_type"`
+	Key         string        `json:"key"`
+	Value       string        `json:"value"`
 }
 
 // CreateL7PolicyRequest is create l7 policy payload
 type CreateL7PolicyRequest struct {
-	Action         string                `json:"action"`
+	Action         L7PolicyAction        `json:"action"`
 	Description    string                `json:"description"`
 	Name           string                `json:"name"`
 	Position       string                `json:"position"`
@@ -89,14 +98,15 @@ type UpdateL7PolicyRuleRequest struct {
 
 // UpdateL7PolicyRequest is update l7 policy payload
 type UpdateL7PolicyRequest struct {
-	Action         string                      `json:"action"`
-	Description    string                      `json:"description"`
-	Name           string                      `json:"name"`
-	Position       int                         `json:"position"`
-	RedirectPoolID *string                     `json:"redirect_pool_id"`
-	RedirectPrefix *string                     `json:"redirect_prefix"`
-	RedirectURL    *string                     `json:"redirect_url"`
-	Rules          []UpdateL7PolicyRuleRequest `json:"rules"`
+	Action           L7PolicyAction              `json:"action"`
+	Description      string                      `json:"description"`
+	Name             string                      `json:"name"`
+	Position         int                         `json:"position"`
+	RedirectHttpCode *int                        `json:"redirect_http_code,omitempty"`
+	RedirectPoolID   *string                     `json:"redirect_pool_id"`
+	RedirectPrefix   *string                     `json:"redirect_prefix"`
+	RedirectURL      *string                     `json:"redirect_url"`
+	Rules            []UpdateL7PolicyRuleRequest `json:"rules"`
 }
 
 type cloudLoadBalancerL7PolicyResource struct {
@@ -111,8 +121,35 @@ func (p *cloudLoadBalancerL7PolicyResource) itemPath(policyID string) string {
 	return strings.Join([]string{l7PolicyPath, policyID}, "/")
 }
 
+func (p *cloudLoadBalancerL7PolicyResource) ruleItemPath(policyID string, ruleID string) string {
+	return strings.Join([]string{l7PolicyPath, policyID, "rules", ruleID}, "/")
+}
+
+// List - list l7 policies of listener, ordered by position
+func (p *cloudLoadBalancerL7PolicyResource) List(ctx context.Context, listenerID string) ([]*DetailL7Policy, error) {
+	listener, err := (&cloudLoadBalancerListenerResource{client: p.client}).Get(ctx, listenerID)
+	if err != nil {
+		return nil, err
+	}
+	policies := make([]*DetailL7Policy, 0, len(listener.L7Policies))
+	for _, policyID := range listener.L7Policies {
+		policy, err := p.Get(ctx, policyID.ID)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	sort.SliceStable(policies, func(i, j int) bool {
+		return policies[i].Position < policies[j].Position
+	})
+	return policies, nil
+}
+
 // Create - create policy for listener
 func (p *cloudLoadBalancerL7PolicyResource) Create(ctx context.Context, listenerID string, payload *CreateL7PolicyRequest) (*DetailL7Policy, error) {
+	if err := payload.Validate(); err != nil {
+		return nil, err
+	}
 	createL7PolicyPath := strings.Join([]string{listenerPath, listenerID, "l7policy"}, "/")
 	clpr := struct {
 		L7Policy CreateL7PolicyRequest `json:"l7policy"`
@@ -160,6 +197,9 @@ func (p *cloudLoadBalancerL7PolicyResource) Get(ctx context.Context, policyID st
 
 // Update - update l7 policy
 func (p *cloudLoadBalancerL7PolicyResource) Update(ctx context.Context, policyID string, payload *UpdateL7PolicyRequest) (*DetailL7Policy, error) {
+	if err := payload.Validate(); err != nil {
+		return nil, err
+	}
 	ulpr := struct {
 		L7Plicy UpdateL7PolicyRequest `json:"l7policy"`
 	}{L7Plicy: *payload}
@@ -219,7 +259,11 @@ func (p *cloudLoadBalancerL7PolicyResource) ListL7PolicyRules(ctx context.Contex
 	return data.Rules, nil
 }
 
+// CreateL7PolicyRule - create a rule of l7 policy
 func (p *cloudLoadBalancerL7PolicyResource) CreateL7PolicyRule(ctx context.Context, policyID string, payload L7PolicyRuleRequest) (*DetailL7PolicyRule, error) {
+	if err := payload.Validate(); err != nil {
+		return nil, err
+	}
 	path := strings.Join([]string{p.itemPath(policyID), "rules"}, "/")
 	clpr := struct {
 		Rule L7PolicyRuleRequest `json:"rule"`
@@ -243,3 +287,140 @@ func (p *cloudLoadBalancerL7PolicyResource) CreateL7PolicyRule(ctx context.Conte
 	}
 	return &data.Rule, nil
 }
+
+// GetL7PolicyRule - get detail rule of l7 policy
+func (p *cloudLoadBalancerL7PolicyResource) GetL7PolicyRule(ctx context.Context, policyID string, ruleID string) (*DetailL7PolicyRule, error) {
+	req, err := p.client.NewRequest(ctx, http.MethodGet, loadBalancerServiceName, p.ruleItemPath(policyID, ruleID), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	var data struct {
+		Rule DetailL7PolicyRule `json:"rule"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	return &data.Rule, nil
+}
+
+// UpdateL7PolicyRule - update rule of l7 policy
+func (p *cloudLoadBalancerL7PolicyResource) UpdateL7PolicyRule(ctx context.Context, policyID string, ruleID string, payload L7PolicyRuleRequest) (*DetailL7PolicyRule, error) {
+	if err := payload.Validate(); err != nil {
+		return nil, err
+	}
+	ulpr := struct {
+		Rule L7PolicyRuleRequest `json:"rule"`
+	}{Rule: payload}
+	req, err := p.client.NewRequest(ctx, http.MethodPut, loadBalancerServiceName, p.ruleItemPath(policyID, ruleID), ulpr)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	var data struct {
+		Rule DetailL7PolicyRule `json:"rule"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	return &data.Rule, nil
+}
+
+// DeleteL7PolicyRule - delete rule of l7 policy
+func (p *cloudLoadBalancerL7PolicyResource) DeleteL7PolicyRule(ctx context.Context, policyID string, ruleID string) error {
+	req, err := p.client.NewRequest(ctx, http.MethodDelete, loadBalancerServiceName, p.ruleItemPath(policyID, ruleID), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Reorder - move the given policies of listener to the first positions, in order. Policies which are not
+// given keep their relative order after them. An update replaces the whole policy, so each moved policy
+// is sent back with its current rules.
+func (p *cloudLoadBalancerL7PolicyResource) Reorder(ctx context.Context, listenerID string, policyIDs []string) error {
+	policies, err := p.List(ctx, listenerID)
+	if err != nil {
+		return err
+	}
+	belongs := make(map[string]bool, len(policies))
+	for _, policy := range policies {
+		belongs[policy.ID] = true
+	}
+	for _, policyID := range policyIDs {
+		if !belongs[policyID] {
+			return fmt.Errorf("l7 policy %s does not belong to listener %s: %w", policyID, listenerID, ErrNotFound)
+		}
+	}
+	for i, policyID := range policyIDs {
+		// Moving a policy shifts the others, read the current position again.
+		policy, err := p.Get(ctx, policyID)
+		if err != nil {
+			return err
+		}
+		if policy.Position == i+1 {
+			continue
+		}
+		payload, err := p.updateRequestOf(ctx, policy)
+		if err != nil {
+			return err
+		}
+		payload.Position = i + 1
+		err = p.client.retryTransient(ctx, func() error {
+			_, err := p.Update(ctx, policyID, payload)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateRequestOf builds the update payload which keeps a policy and its rules unchanged.
+func (p *cloudLoadBalancerL7PolicyResource) updateRequestOf(ctx context.Context, policy *DetailL7Policy) (*UpdateL7PolicyRequest, error) {
+	rules, err := p.ListL7PolicyRules(ctx, policy.ID)
+	if err != nil {
+		return nil, err
+	}
+	payload := &UpdateL7PolicyRequest{
+		Action:           L7PolicyAction(policy.Action),
+		Description:      policy.Description,
+		Name:             policy.Name,
+		Position:         policy.Position,
+		RedirectHttpCode: policy.RedirectHttpCode,
+		RedirectPoolID:   policy.RedirectPoolID,
+		RedirectPrefix:   policy.RedirectPrefix,
+		RedirectURL:      policy.RedirectURL,
+		Rules:            make([]UpdateL7PolicyRuleRequest, 0, len(rules)),
+	}
+	for _, rule := range rules {
+		payload.Rules = append(payload.Rules, UpdateL7PolicyRuleRequest{
+			ID: rule.ID,
+			L7PolicyRuleRequest: L7PolicyRuleRequest{
+				Invert:      rule.Invert,
+				Type:        L7RuleType(rule.Type),
+				CompareType: L7CompareType(rule.CompareType),
+				Key:         stringValue(rule.Key),
+				Value:       rule.Value,
+			},
+		})
+	}
+	return payload, nil
+}
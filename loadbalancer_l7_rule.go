@@ -0,0 +1,169 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// L7PolicyAction is the action of an L7 policy.
+type L7PolicyAction string
+
+// L7RuleType is the part of the request an L7 rule matches.
+type L7RuleType string
+
+// L7CompareType is how an L7 rule compares the request with its value.
+type L7CompareType string
+
+const (
+	L7PolicyActionRedirectToPool L7PolicyAction = "REDIRECT_TO_POOL"
+	L7PolicyActionRedirectToURL  L7PolicyAction = "REDIRECT_TO_URL"
+	L7PolicyActionRedirectPrefix L7PolicyAction = "REDIRECT_PREFIX"
+	L7PolicyActionReject         L7PolicyAction = "REJECT"
+
+	L7RuleTypeCookie          L7RuleType = "COOKIE"
+	L7RuleTypeFileType        L7RuleType = "FILE_TYPE"
+	L7RuleTypeHeader          L7RuleType = "HEADER"
+	L7RuleTypeHostName        L7RuleType = "HOST_NAME"
+	L7RuleTypePath            L7RuleType = "PATH"
+	L7RuleTypeSSLConnHasCert  L7RuleType = "SSL_CONN_HAS_CERT"
+	L7RuleTypeSSLVerifyResult L7RuleType = "SSL_VERIFY_RESULT"
+	L7RuleTypeSSLDNField      L7RuleType = "SSL_DN_FIELD"
+
+	L7CompareTypeContains   L7CompareType = "CONTAINS"
+	L7CompareTypeEndsWith   L7CompareType = "ENDS_WITH"
+	L7CompareTypeEqualTo    L7CompareType = "EQUAL_TO"
+	L7CompareTypeRegex      L7CompareType = "REGEX"
+	L7CompareTypeStartsWith L7CompareType = "STARTS_WITH"
+)
+
+var l7RuleTypes = map[L7RuleType]bool{
+	L7RuleTypeCookie: true, L7RuleTypeFileType: true, L7RuleTypeHeader: true, L7RuleTypeHostName: true,
+	L7RuleTypePath: true, L7RuleTypeSSLConnHasCert: true, L7RuleTypeSSLVerifyResult: true, L7RuleTypeSSLDNField: true,
+}
+
+var l7CompareTypes = map[L7CompareType]bool{
+	L7CompareTypeContains: true, L7CompareTypeEndsWith: true, L7CompareTypeEqualTo: true,
+	L7CompareTypeRegex: true, L7CompareTypeStartsWith: true,
+}
+
+// Validate checks the rule type, compare type, key and value combination.
+func (r L7PolicyRuleRequest) Validate() error {
+	if !l7RuleTypes[r.Type] {
+		return fmt.Errorf("invalid l7 rule type %q: %w", r.Type, ErrCommon)
+	}
+	if !l7CompareTypes[r.CompareType] {
+		return fmt.Errorf("invalid l7 rule compare type %q: %w", r.CompareType, ErrCommon)
+	}
+	if r.Value == "" {
+		return fmt.Errorf("l7 rule %s requires a value: %w", r.Type, ErrCommon)
+	}
+	switch r.Type {
+	case L7RuleTypeCookie, L7RuleTypeHeader, L7RuleTypeSSLDNField:
+		if r.Key == "" {
+			return fmt.Errorf("l7 rule %s requires a key: %w", r.Type, ErrCommon)
+		}
+	default:
+		if r.Key != "" {
+			return fmt.Errorf("l7 rule %s does not take a key: %w", r.Type, ErrCommon)
+		}
+	}
+	switch r.Type {
+	case L7RuleTypeFileType:
+		if r.CompareType != L7CompareTypeEqualTo && r.CompareType != L7CompareTypeRegex {
+			return fmt.Errorf("l7 rule FILE_TYPE only compares with EQUAL_TO or REGEX: %w", ErrCommon)
+		}
+	case L7RuleTypeSSLConnHasCert:
+		if r.CompareType != L7CompareTypeEqualTo || !strings.EqualFold(r.Value, "true") {
+			return fmt.Errorf("l7 rule SSL_CONN_HAS_CERT must be EQUAL_TO True: %w", ErrCommon)
+		}
+	case L7RuleTypeSSLVerifyResult:
+		if r.CompareType != L7CompareTypeEqualTo {
+			return fmt.Errorf("l7 rule SSL_VERIFY_RESULT only compares with EQUAL_TO: %w", ErrCommon)
+		}
+	}
+	return nil
+}
+
+// validateL7PolicyAction checks that the redirect target required by the action is set, and only that one.
+func validateL7PolicyAction(action L7PolicyAction, redirectPoolID string, redirectURL string, redirectPrefix string) error {
+	targets := map[L7PolicyAction]string{
+		L7PolicyActionRedirectToPool: redirectPoolID,
+		L7PolicyActionRedirectToURL:  redirectURL,
+		L7PolicyActionRedirectPrefix: redirectPrefix,
+	}
+	if _, ok := targets[action]; !ok && action != L7PolicyActionReject {
+		return fmt.Errorf("invalid l7 policy action %q: %w", action, ErrCommon)
+	}
+	for targetAction, target := range targets {
+		if targetAction == action && target == "" {
+			return fmt.Errorf("l7 policy action %s requires its redirect target: %w", action, ErrCommon)
+		}
+		if targetAction != action && target != "" {
+			return fmt.Errorf("l7 policy action %s does not take the redirect target of %s: %w", action, targetAction, ErrCommon)
+		}
+	}
+	return validateL7PolicyRedirects(redirectURL, redirectPrefix)
+}
+
+// validateL7PolicyRedirects checks that the redirect URL and prefix which are set are absolute URLs.
+func validateL7PolicyRedirects(redirects ...string) error {
+	for _, redirect := range redirects {
+		if redirect == "" {
+			continue
+		}
+		if u, err := url.Parse(redirect); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid l7 policy redirect %q: %w", redirect, ErrCommon)
+		}
+	}
+	return nil
+}
+
+// Validate checks the action, its redirect target and the rules of the policy.
+func (p *CreateL7PolicyRequest) Validate() error {
+	if err := validateL7PolicyAction(p.Action, p.RedirectPoolID, p.RedirectURL, stringValue(p.RedirectPrefix)); err != nil {
+		return err
+	}
+	for _, rule := range p.Rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the fields which are set. The redirect target is checked against the action only when the
+// action is set, so that a partial update such as a rename or a move keeps the current action.
+func (p *UpdateL7PolicyRequest) Validate() error {
+	var err error
+	if p.Action != "" {
+		err = validateL7PolicyAction(p.Action, stringValue(p.RedirectPoolID), stringValue(p.RedirectURL), stringValue(p.RedirectPrefix))
+	} else {
+		err = validateL7PolicyRedirects(stringValue(p.RedirectURL), stringValue(p.RedirectPrefix))
+	}
+	if err != nil {
+		return err
+	}
+	if p.RedirectHttpCode != nil {
+		switch *p.RedirectHttpCode {
+		case 301, 302, 303, 307, 308:
+		default:
+			return fmt.Errorf("invalid l7 policy redirect http code %d: %w", *p.RedirectHttpCode, ErrCommon)
+		}
+	}
+	for _, rule := range p.Rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,171 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestL7PolicyRuleValidate(t *testing.T) {
+	valid := []L7PolicyRuleRequest{
+		{Type: L7RuleTypeHostName, CompareType: L7CompareTypeEqualTo, Value: "app.example.com"},
+		{Type: L7RuleTypePath, CompareType: L7CompareTypeStartsWith, Value: "/api"},
+		{Type: L7RuleTypeHeader, CompareType: L7CompareTypeContains, Key: "User-Agent", Value: "curl"},
+		{Type: L7RuleTypeFileType, CompareType: L7CompareTypeRegex, Value: "jpe?g"},
+		{Type: L7RuleTypeSSLConnHasCert, CompareType: L7CompareTypeEqualTo, Value: "True"},
+	}
+	for _, rule := range valid {
+		assert.NoError(t, rule.Validate(), string(rule.Type))
+	}
+	invalid := []L7PolicyRuleRequest{
+		{Type: "HOST", CompareType: L7CompareTypeEqualTo, Value: "a"},
+		{Type: L7RuleTypeHostName, CompareType: "LIKE", Value: "a"},
+		{Type: L7RuleTypeHostName, CompareType: L7CompareTypeEqualTo},
+		{Type: L7RuleTypeCookie, CompareType: L7CompareTypeEqualTo, Value: "a"},
+		{Type: L7RuleTypePath, CompareType: L7CompareTypeEqualTo, Key: "k", Value: "/"},
+		{Type: L7RuleTypeFileType, CompareType: L7CompareTypeStartsWith, Value: "jpg"},
+		{Type: L7RuleTypeSSLConnHasCert, CompareType: L7CompareTypeEqualTo, Value: "False"},
+	}
+	for _, rule := range invalid {
+		assert.True(t, errors.Is(rule.Validate(), ErrCommon), fmt.Sprintf("%+v", rule))
+	}
+}
+
+func TestL7PolicyValidate(t *testing.T) {
+	prefix := "https://example.com"
+	assert.NoError(t, (&CreateL7PolicyRequest{Action: L7PolicyActionRedirectToPool, RedirectPoolID: "pool-1"}).Validate())
+	assert.NoError(t, (&CreateL7PolicyRequest{Action: L7PolicyActionRedirectPrefix, RedirectPrefix: &prefix}).Validate())
+	assert.NoError(t, (&CreateL7PolicyRequest{Action: L7PolicyActionReject}).Validate())
+	for _, policy := range []*CreateL7PolicyRequest{
+		{Action: L7PolicyActionRedirectToPool},
+		{Action: L7PolicyActionRedirectToURL, RedirectURL: "/relative"},
+		{Action: L7PolicyActionReject, RedirectPoolID: "pool-1"},
+		{Action: L7PolicyActionRedirectToURL, RedirectURL: "https://example.com", RedirectPoolID: "pool-1"},
+		{Action: "DROP"},
+		{Action: L7PolicyActionReject, Rules: []L7PolicyRuleRequest{{Type: L7RuleTypePath}}},
+	} {
+		assert.True(t, errors.Is(policy.Validate(), ErrCommon), fmt.Sprintf("%+v", policy))
+	}
+	assert.Error(t, (&UpdateL7PolicyRequest{Action: L7PolicyActionRedirectToPool}).Validate())
+	assert.NoError(t, (&UpdateL7PolicyRequest{Name: "renamed"}).Validate())
+	assert.NoError(t, (&UpdateL7PolicyRequest{Position: 2}).Validate())
+	assert.Error(t, (&UpdateL7PolicyRequest{RedirectURL: &prefix, Action: L7PolicyActionReject}).Validate())
+	code := 200
+	assert.Error(t, (&UpdateL7PolicyRequest{RedirectHttpCode: &code}).Validate())
+
+	setup()
+	defer teardown()
+	_, err := client.CloudLoadBalancer.L7Policies().Create(ctx, "listener", &CreateL7PolicyRequest{Action: L7PolicyActionRedirectToPool})
+	assert.True(t, errors.Is(err, ErrCommon))
+}
+
+func TestL7PolicyRuleCRUD(t *testing.T) {
+	setup()
+	defer teardown()
+	var policy cloudLoadBalancerL7PolicyResource
+	mux.HandleFunc(testlib.LoadBalancerURL(policy.ruleItemPath("policy-1", "rule-1")), func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = fmt.Fprint(w, `{"rule": {"id": "rule-1", "type": "PATH", "compare_type": "STARTS_WITH", "value": "/api", "key": null}}`)
+		case http.MethodPut:
+			var payload struct {
+				Rule L7PolicyRuleRequest `json:"rule"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			assert.Equal(t, L7RuleTypePath, payload.Rule.Type)
+			_, _ = fmt.Fprintf(w, `{"rule": {"id": "rule-1", "type": "PATH", "compare_type": "STARTS_WITH", "value": "%s"}}`, payload.Rule.Value)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Fatalf("unexpected method %s", r.Method)
+		}
+	})
+
+	rule, err := client.CloudLoadBalancer.L7Policies().GetL7PolicyRule(ctx, "policy-1", "rule-1")
+	require.NoError(t, err)
+	assert.Equal(t, "/api", rule.Value)
+	rule, err = client.CloudLoadBalancer.L7Policies().UpdateL7PolicyRule(ctx, "policy-1", "rule-1", L7PolicyRuleRequest{
+		Type: L7RuleTypePath, CompareType: L7CompareTypeStartsWith, Value: "/v2",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/v2", rule.Value)
+	_, err = client.CloudLoadBalancer.L7Policies().UpdateL7PolicyRule(ctx, "policy-1", "rule-1", L7PolicyRuleRequest{Type: L7RuleTypePath})
+	assert.True(t, errors.Is(err, ErrCommon))
+	require.NoError(t, client.CloudLoadBalancer.L7Policies().DeleteL7PolicyRule(ctx, "policy-1", "rule-1"))
+}
+
+func TestL7PolicyListAndReorder(t *testing.T) {
+	setup()
+	defer teardown()
+	var mu sync.Mutex
+	positions := map[string]int{"p-api": 1, "p-web": 2, "p-admin": 3}
+	var updates []string
+
+	var listener cloudLoadBalancerListenerResource
+	mux.HandleFunc(testlib.LoadBalancerURL(listener.itemPath("listener-1")), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = fmt.Fprint(w, `{"id": "listener-1", "l7policies": [{"id": "p-web"}, {"id": "p-admin"}, {"id": "p-api"}]}`)
+	})
+	mux.HandleFunc(testlib.LoadBalancerURL(l7PolicyPath+"/"), func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, testlib.LoadBalancerURL(l7PolicyPath+"/")), "/")
+		id := parts[0]
+		if len(parts) == 2 {
+			_, _ = fmt.Fprintf(w, `{"rules": [{"id": "%s-rule", "type": "HOST_NAME", "compare_type": "EQUAL_TO", "value": "%s.example.com", "key": null}]}`, id, id)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = fmt.Fprintf(w, `{"id": "%s", "name": "%s", "action": "REDIRECT_TO_POOL", "redirect_pool_id": "pool-%s", "redirect_http_code": 307, "position": %d}`,
+				id, id, id, positions[id])
+		case http.MethodPut:
+			var payload struct {
+				L7Policy UpdateL7PolicyRequest `json:"l7policy"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			assert.Equal(t, "pool-"+id, *payload.L7Policy.RedirectPoolID)
+			require.NotNil(t, payload.L7Policy.RedirectHttpCode)
+			assert.Equal(t, 307, *payload.L7Policy.RedirectHttpCode)
+			require.Len(t, payload.L7Policy.Rules, 1)
+			assert.Equal(t, id+"-rule", payload.L7Policy.Rules[0].ID)
+			assert.Equal(t, id+".example.com", payload.L7Policy.Rules[0].Value)
+			// Octavia shifts the other policies when a policy moves.
+			old, position := positions[id], payload.L7Policy.Position
+			for other, p := range positions {
+				if other != id && p >= position && p < old {
+					positions[other] = p + 1
+				}
+			}
+			positions[id] = position
+			updates = append(updates, fmt.Sprintf("%s:%d", id, position))
+			_, _ = fmt.Fprintf(w, `{"l7policy": {"id": "%s", "position": %d}}`, id, position)
+		}
+	})
+
+	policies, err := client.CloudLoadBalancer.L7Policies().List(ctx, "listener-1")
+	require.NoError(t, err)
+	require.Len(t, policies, 3)
+	assert.Equal(t, []string{"p-api", "p-web", "p-admin"}, []string{policies[0].ID, policies[1].ID, policies[2].ID})
+
+	require.NoError(t, client.CloudLoadBalancer.L7Policies().Reorder(ctx, "listener-1", []string{"p-admin", "p-web"}))
+	assert.Equal(t, []string{"p-admin:1", "p-web:2"}, updates)
+	assert.Equal(t, map[string]int{"p-admin": 1, "p-web": 2, "p-api": 3}, positions)
+
+	updates = nil
+	require.NoError(t, client.CloudLoadBalancer.L7Policies().Reorder(ctx, "listener-1", []string{"p-admin", "p-web", "p-api"}))
+	assert.Empty(t, updates)
+
+	err = client.CloudLoadBalancer.L7Policies().Reorder(ctx, "listener-1", []string{"p-other"})
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
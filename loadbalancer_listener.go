@@ -21,19 +21,19 @@ type CloudLoadBalancerListenerService interface {
 
 // CloudLoadBalancerListenerCreateRequest represents create new listener request payload.
 type CloudLoadBalancerListenerCreateRequest struct {
-	TimeoutTCPInspect      *int               `json:"timeout_tcp_inspect,omitempty"`
-	TimeoutMemberData      *int               `json:"timeout_member_data,omitempty"`
-	TimeoutMemberConnect   *int               `json:"timeout_member_connect,omitempty"`
-	TimeoutClientData      *int               `json:"timeout_client_data,omitempty"`
-	SNIContainerRefs       *[]string          `json:"sni_container_refs,omitempty"`
-	ProtocolPort           int                `json:"protocol_port"`
-	Protocol               string             `json:"protocol"`
-	Name                   *string            `json:"name,omitempty"`
-	L7Policies             *[]resourceID      `json:"l7policies,omitempty"`
-	InsertHeaders          *map[string]string `json:"insert_headers,omitempty"`
-	Description            *string            `json:"description,omitempty"`
-	DefaultTLSContainerRef *string            `json:"default_tls_container_ref,omitempty"`
-	DefaultPoolID          *string            `json:"default_pool_id,omitempty"`
+	TimeoutTCPInspect      *int                 `json:"timeout_tcp_inspect,omitempty"`
+	TimeoutMemberData      *int                 `json:"timeout_member_data,omitempty"`
+	TimeoutMemberConnect   *int                 `json:"timeout_member_connect,omitempty"`
+	TimeoutClientData      *int                 `json:"timeout_client_data,omitempty"`
+	SNIContainerRefs       *[]string            `json:"sni_container_refs,omitempty"`
+	ProtocolPort           int                  `json:"protocol_port"`
+	Protocol               LoadBalancerProtocol `json:"protocol"`
+	Name                   *string              `json:"name,omitempty"`
+	L7Policies             *[]resourceID        `json:"l7policies,omitempty"`
+	InsertHeaders          *map[string]string   `json:"insert_headers,omitempty"`
+	Description            *string              `json:"description,omitempty"`
+	DefaultTLSContainerRef *string              `json:"default_tls_container_ref,omitempty"`
+	DefaultPoolID          *string              `json:"default_pool_id,omitempty"`
 }
 
 // CloudLoadBalancerListenerUpdateRequest represents update listener request payload.
@@ -122,6 +122,9 @@ func (l *cloudLoadBalancerListenerResource) List(ctx context.Context, lbID strin
 
 // Create - Create a new listener.
 func (l *cloudLoadBalancerListenerResource) Create(ctx context.Context, lbID string, lcr *CloudLoadBalancerListenerCreateRequest) (*CloudLoadBalancerListener, error) {
+	if err := lcr.Validate(); err != nil {
+		return nil, err
+	}
 	var data struct {
 		CloudLoadBalancerListener *CloudLoadBalancerListenerCreateRequest `json:"listener"`
 	}
@@ -170,6 +173,9 @@ func (l *cloudLoadBalancerListenerResource) Get(ctx context.Context, id string)
 
 // Update - Update a listener's information.
 func (l *cloudLoadBalancerListenerResource) Update(ctx context.Context, id string, lur *CloudLoadBalancerListenerUpdateRequest) (*CloudLoadBalancerListener, error) {
+	if err := lur.Validate(); err != nil {
+		return nil, err
+	}
 	var data struct {
 		CloudLoadBalancerListener *CloudLoadBalancerListenerUpdateRequest `json:"listener"`
 	}
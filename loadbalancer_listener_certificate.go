@@ -0,0 +1,283 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ListenerCertificateRotateOptions represents options when rotating the certificate of a listener.
+type ListenerCertificateRotateOptions struct {
+	// Name of the new certificate container, the listener name and the certificate expiry date by default.
+	Name string
+	// Passphrase of the private key, when it is encrypted.
+	Passphrase string
+	// DeleteOld deletes the previous certificate container unless another listener uses it.
+	DeleteOld bool
+}
+
+// ListenerCertificateRotation is the result of a certificate rotation.
+type ListenerCertificateRotation struct {
+	ListenerID      string    `json:"listener_id"`
+	OldContainerRef string    `json:"old_container_ref"`
+	NewContainerRef string    `json:"new_container_ref"`
+	NotAfter        time.Time `json:"not_after"`
+	OldDeleted      bool      `json:"old_deleted"`
+}
+
+// ListenerCertificateExpiry is a listener certificate which expires soon.
+type ListenerCertificateExpiry struct {
+	LoadBalancerID string    `json:"loadbalancer_id"`
+	ListenerID     string    `json:"listener_id"`
+	ListenerName   string    `json:"listener_name"`
+	ContainerRef   string    `json:"container_ref"`
+	SNI            bool      `json:"sni"`
+	CommonName     string    `json:"common_name"`
+	DNSNames       []string  `json:"dns_names"`
+	NotAfter       time.Time `json:"not_after"`
+}
+
+// RotateListenerCertificate uploads a certificate, its private key and its chain of intermediates, all PEM encoded,
+// to KMS and makes it the default certificate of a listener. The key must match the certificate, which must not be
+// expired. When the listener cannot be switched to the new container, it is switched back to the previous one and
+// the new container is deleted again.
+func (l *cloudLoadBalancerService) RotateListenerCertificate(ctx context.Context, listenerID string, certPEM, keyPEM, chainPEM string,
+	opts *ListenerCertificateRotateOptions) (*ListenerCertificateRotation, error) {
+	if opts == nil {
+		opts = &ListenerCertificateRotateOptions{}
+	}
+	cert, err := parseListenerCertificate(certPEM, keyPEM, chainPEM, opts.Passphrase != "")
+	if err != nil {
+		return nil, err
+	}
+	listener, err := l.Listeners().Get(ctx, listenerID)
+	if err != nil {
+		return nil, err
+	}
+	rotation := &ListenerCertificateRotation{
+		ListenerID:      listenerID,
+		OldContainerRef: stringValue(listener.DefaultTLSContainerRef),
+		NotAfter:        cert.NotAfter,
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", listener.Name, cert.NotAfter.UTC().Format("20060102"))
+	}
+	container := KMSCertContainer{
+		Name:                 name,
+		Certificate:          KMSCertificateCreateReqest{Name: name + "-certificate", Payload: certPEM},
+		PrivateKey:           KMSPrivateKeyCreateReqest{Name: name + "-key", Payload: keyPEM},
+		PrivateKeyPassphrase: KMSPrivateKeyPassphraseCreateReqest{Name: name + "-passphrase", Payload: opts.Passphrase},
+	}
+	if chainPEM != "" {
+		container.Intermediates = &KMSIntermediatesCreateReqest{Name: name + "-intermediates", Payload: chainPEM}
+	}
+	certificates := l.client.KMS.Certificates()
+	created, err := certificates.Create(ctx, &KMSCertificateContainerCreateRequest{CertContainer: container})
+	if err != nil {
+		return nil, err
+	}
+	rotation.NewContainerRef = created.CertificateHref
+
+	if err := l.setListenerCertificate(ctx, listenerID, rotation.NewContainerRef); err != nil {
+		// The listener does not use the new container, it can go.
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultWaitTimeout)
+		defer cancel()
+		_ = certificates.Delete(cleanupCtx, containerIDFromRef(rotation.NewContainerRef))
+		return nil, err
+	}
+	if err := l.waitForListenerCertificate(ctx, listenerID, rotation.NewContainerRef); err != nil {
+		// The listener may already serve the new container, it is only deleted once the listener is switched back.
+		rollbackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultWaitTimeout)
+		defer cancel()
+		if rotation.OldContainerRef != "" && l.switchListenerCertificate(rollbackCtx, listenerID, rotation.OldContainerRef) == nil {
+			_ = certificates.Delete(rollbackCtx, containerIDFromRef(rotation.NewContainerRef))
+		}
+		return nil, err
+	}
+
+	if opts.DeleteOld && rotation.OldContainerRef != "" && rotation.OldContainerRef != rotation.NewContainerRef {
+		used, err := l.certificateInUse(ctx, rotation.OldContainerRef)
+		if err != nil {
+			return rotation, err
+		}
+		if !used {
+			if err := certificates.Delete(ctx, containerIDFromRef(rotation.OldContainerRef)); err != nil && !errors.Is(err, ErrNotFound) {
+				return rotation, err
+			}
+			rotation.OldDeleted = true
+		}
+	}
+	return rotation, nil
+}
+
+// switchListenerCertificate sets the default certificate of a listener and waits until the listener serves it.
+func (l *cloudLoadBalancerService) switchListenerCertificate(ctx context.Context, listenerID, ref string) error {
+	if err := l.setListenerCertificate(ctx, listenerID, ref); err != nil {
+		return err
+	}
+	return l.waitForListenerCertificate(ctx, listenerID, ref)
+}
+
+// setListenerCertificate sets the default certificate of a listener.
+func (l *cloudLoadBalancerService) setListenerCertificate(ctx context.Context, listenerID, ref string) error {
+	return l.client.retryTransient(ctx, func() error {
+		_, err := l.Listeners().Update(ctx, listenerID, &CloudLoadBalancerListenerUpdateRequest{DefaultTLSContainerRef: &ref})
+		return err
+	})
+}
+
+// waitForListenerCertificate waits until a listener serves a certificate container.
+func (l *cloudLoadBalancerService) waitForListenerCertificate(ctx context.Context, listenerID, ref string) error {
+	return l.client.waitFor(ctx, func() (bool, error) {
+		listener, err := l.Listeners().Get(ctx, listenerID)
+		if err != nil {
+			return false, err
+		}
+		if listener.ProvisoningStatus == loadBalancerErrorStatus {
+			return false, fmt.Errorf("listener %s is in %s status: %w", listenerID, listener.ProvisoningStatus, ErrCommon)
+		}
+		return stringValue(listener.DefaultTLSContainerRef) == ref && listener.ProvisoningStatus == loadBalancerActiveStatus, nil
+	})
+}
+
+// certificateInUse reports whether a certificate container is used by a listener of any load balancer.
+func (l *cloudLoadBalancerService) certificateInUse(ctx context.Context, ref string) (bool, error) {
+	lbs, err := l.List(ctx, &ListOptions{})
+	if err != nil {
+		return false, err
+	}
+	for _, lb := range lbs {
+		listeners, err := l.Listeners().List(ctx, lb.ID, &ListOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, other := range listeners {
+			for _, otherRef := range listenerCertificateRefs(other) {
+				if otherRef == ref {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// ExpiringListenerCertificates returns the default and SNI certificates of all listeners which expire within the
+// given number of days, or are already expired, soonest first.
+func (l *cloudLoadBalancerService) ExpiringListenerCertificates(ctx context.Context, days int) ([]*ListenerCertificateExpiry, error) {
+	lbs, err := l.List(ctx, &ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().AddDate(0, 0, days)
+	certificates := l.client.KMS.Certificates()
+	parsed := map[string]*x509.Certificate{}
+	expiring := []*ListenerCertificateExpiry{}
+	for _, lb := range lbs {
+		listeners, err := l.Listeners().List(ctx, lb.ID, &ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, listener := range listeners {
+			for i, ref := range listenerCertificateRefs(listener) {
+				cert, ok := parsed[ref]
+				if !ok {
+					container, err := certificates.Get(ctx, containerIDFromRef(ref))
+					if err != nil {
+						return nil, err
+					}
+					if cert, err = parseCertificatePEM(container.Certificate); err != nil {
+						return nil, fmt.Errorf("certificate %s: %w", ref, err)
+					}
+					parsed[ref] = cert
+				}
+				if cert.NotAfter.After(deadline) {
+					continue
+				}
+				expiring = append(expiring, &ListenerCertificateExpiry{
+					LoadBalancerID: lb.ID,
+					ListenerID:     listener.ID,
+					ListenerName:   listener.Name,
+					ContainerRef:   ref,
+					SNI:            i > 0 || stringValue(listener.DefaultTLSContainerRef) == "",
+					CommonName:     cert.Subject.CommonName,
+					DNSNames:       cert.DNSNames,
+					NotAfter:       cert.NotAfter,
+				})
+			}
+		}
+	}
+	sort.SliceStable(expiring, func(i, j int) bool {
+		return expiring[i].NotAfter.Before(expiring[j].NotAfter)
+	})
+	return expiring, nil
+}
+
+// listenerCertificateRefs returns the default certificate container of a listener, then its SNI ones.
+func listenerCertificateRefs(listener *CloudLoadBalancerListener) []string {
+	var refs []string
+	if ref := stringValue(listener.DefaultTLSContainerRef); ref != "" {
+		refs = append(refs, ref)
+	}
+	for _, ref := range listener.SNIContainerRefs {
+		if ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// containerIDFromRef returns the ID of a certificate container from its reference, which is its URL.
+func containerIDFromRef(ref string) string {
+	if u, err := url.Parse(ref); err == nil && u.Path != "" {
+		ref = u.Path
+	}
+	return path.Base(strings.TrimRight(ref, "/"))
+}
+
+func parseCertificatePEM(certPEM string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM certificate found: %w", ErrCommon)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err.Error(), ErrCommon)
+	}
+	return cert, nil
+}
+
+// parseListenerCertificate checks that a certificate is valid now and, unless its key is encrypted, matches the key.
+func parseListenerCertificate(certPEM, keyPEM, chainPEM string, encrypted bool) (*x509.Certificate, error) {
+	cert, err := parseCertificatePEM(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if now.After(cert.NotAfter) || now.Before(cert.NotBefore) {
+		return nil, fmt.Errorf("certificate is only valid from %s to %s: %w", cert.NotBefore, cert.NotAfter, ErrCommon)
+	}
+	if chainPEM != "" {
+		if _, err := parseCertificatePEM(chainPEM); err != nil {
+			return nil, fmt.Errorf("intermediates: %w", err)
+		}
+	}
+	if !encrypted {
+		if _, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM)); err != nil {
+			return nil, fmt.Errorf("%s: %w", err.Error(), ErrCommon)
+		}
+	}
+	return cert, nil
+}
@@ -0,0 +1,252 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCertificate(t *testing.T, commonName string, notAfter time.Time) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+// fakeCertificateStore serves KMS certificate containers and the listeners of load balancers lb-1 and lb-2.
+// Listeners belong to lb-1 unless set otherwise.
+type fakeCertificateStore struct {
+	mu         sync.Mutex
+	nextID     int
+	containers map[string]string
+	listeners  map[string]*CloudLoadBalancerListener
+	deleted    []string
+	// errorRef puts a listener in ERROR status when it is switched to this container.
+	errorRef string
+}
+
+func newFakeCertificateStore(t *testing.T, listeners ...*CloudLoadBalancerListener) *fakeCertificateStore {
+	f := &fakeCertificateStore{containers: map[string]string{}, listeners: map[string]*CloudLoadBalancerListener{}}
+	for _, listener := range listeners {
+		listener.ProvisoningStatus = loadBalancerActiveStatus
+		if len(listener.LoadBalancers) == 0 {
+			listener.LoadBalancers = []resourceID{{ID: "lb-1"}}
+		}
+		f.listeners[listener.ID] = listener
+	}
+	mux.HandleFunc(testlib.KMSURL(certificateServicePath), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		var req KMSCertificateContainerCreateRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.NotEmpty(t, req.CertContainer.PrivateKey.Payload)
+		f.nextID++
+		id := fmt.Sprintf("container-%d", f.nextID)
+		f.containers[id] = req.CertContainer.Certificate.Payload
+		_ = json.NewEncoder(w).Encode(KMSCertificateCreateResponse{CertificateHref: "https://kms.example.com/v1/containers/" + id})
+	})
+	mux.HandleFunc(testlib.KMSURL(certificateServicePath+"/"), func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		certificate, ok := f.containers[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(KMSCertificateGetResponse{ContainerID: id, Certificate: certificate})
+		case http.MethodDelete:
+			delete(f.containers, id)
+			f.deleted = append(f.deleted, id)
+		}
+	})
+	mux.HandleFunc(testlib.LoadBalancerURL(loadBalancersPath), func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"loadbalancers": []*LoadBalancer{{ID: "lb-1"}, {ID: "lb-2"}}})
+	})
+	for _, lbID := range []string{"lb-1", "lb-2"} {
+		mux.HandleFunc(testlib.LoadBalancerURL("/loadbalancer/"+lbID+"/listeners"), func(w http.ResponseWriter, r *http.Request) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			listeners := []*CloudLoadBalancerListener{}
+			for _, id := range []string{"l1", "l2", "l3"} {
+				if listener, ok := f.listeners[id]; ok && listener.LoadBalancers[0].ID == lbID {
+					listeners = append(listeners, listener)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"listeners": listeners})
+		})
+	}
+	mux.HandleFunc(testlib.LoadBalancerURL(listenerPath+"/"), func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		listener := f.listeners[r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]]
+		require.NotNil(t, listener)
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(listener)
+			if f.errorRef != "" && stringValue(listener.DefaultTLSContainerRef) == f.errorRef {
+				listener.ProvisoningStatus = loadBalancerErrorStatus
+			} else {
+				listener.ProvisoningStatus = loadBalancerActiveStatus
+			}
+		case http.MethodPut:
+			var data struct {
+				Listener CloudLoadBalancerListenerUpdateRequest `json:"listener"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&data))
+			listener.DefaultTLSContainerRef = data.Listener.DefaultTLSContainerRef
+			listener.ProvisoningStatus = "PENDING_UPDATE"
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"listener": listener})
+		}
+	})
+	return f
+}
+
+func (f *fakeCertificateStore) add(certificate string) string {
+	f.nextID++
+	id := fmt.Sprintf("container-%d", f.nextID)
+	f.containers[id] = certificate
+	return "https://kms.example.com/v1/containers/" + id
+}
+
+func TestRotateListenerCertificate(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	store := newFakeCertificateStore(t,
+		&CloudLoadBalancerListener{ID: "l1", Name: "https", Protocol: "TERMINATED_HTTPS"},
+		&CloudLoadBalancerListener{ID: "l2", Name: "https-alt", Protocol: "TERMINATED_HTTPS"},
+	)
+	oldCert, _ := testCertificate(t, "www.example.com", time.Now().AddDate(0, 0, 5))
+	oldRef := store.add(oldCert)
+	sharedRef := store.add(oldCert)
+	store.listeners["l1"].DefaultTLSContainerRef = &oldRef
+	store.listeners["l2"].SNIContainerRefs = []string{sharedRef}
+
+	certPEM, keyPEM := testCertificate(t, "www.example.com", time.Now().AddDate(0, 3, 0))
+	rotation, err := client.CloudLoadBalancer.RotateListenerCertificate(ctx, "l1", certPEM, keyPEM, "",
+		&ListenerCertificateRotateOptions{DeleteOld: true})
+	require.NoError(t, err)
+	assert.Equal(t, oldRef, rotation.OldContainerRef)
+	assert.Equal(t, "https://kms.example.com/v1/containers/container-3", rotation.NewContainerRef)
+	assert.True(t, rotation.OldDeleted)
+	assert.Equal(t, []string{"container-1"}, store.deleted)
+	assert.Equal(t, rotation.NewContainerRef, *store.listeners["l1"].DefaultTLSContainerRef)
+
+	// The container is shared with the SNI certificates of l2, so it is kept.
+	store.listeners["l1"].DefaultTLSContainerRef = &sharedRef
+	rotation, err = client.CloudLoadBalancer.RotateListenerCertificate(ctx, "l1", certPEM, keyPEM, "",
+		&ListenerCertificateRotateOptions{DeleteOld: true})
+	require.NoError(t, err)
+	assert.False(t, rotation.OldDeleted)
+	assert.Equal(t, []string{"container-1"}, store.deleted)
+
+	_, otherKey := testCertificate(t, "other.example.com", time.Now().AddDate(1, 0, 0))
+	_, err = client.CloudLoadBalancer.RotateListenerCertificate(ctx, "l1", certPEM, otherKey, "", nil)
+	assert.True(t, errors.Is(err, ErrCommon))
+	expired, expiredKey := testCertificate(t, "www.example.com", time.Now().Add(-time.Minute))
+	_, err = client.CloudLoadBalancer.RotateListenerCertificate(ctx, "l1", expired, expiredKey, "", nil)
+	assert.True(t, errors.Is(err, ErrCommon))
+}
+
+func TestRotateListenerCertificateSharedWithOtherLoadBalancer(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	store := newFakeCertificateStore(t,
+		&CloudLoadBalancerListener{ID: "l1", Name: "https", Protocol: "TERMINATED_HTTPS"},
+		&CloudLoadBalancerListener{ID: "l3", Name: "https", Protocol: "TERMINATED_HTTPS",
+			LoadBalancers: []resourceID{{ID: "lb-2"}}},
+	)
+	oldCert, _ := testCertificate(t, "www.example.com", time.Now().AddDate(0, 0, 5))
+	oldRef := store.add(oldCert)
+	store.listeners["l1"].DefaultTLSContainerRef = &oldRef
+	store.listeners["l3"].SNIContainerRefs = []string{oldRef}
+
+	certPEM, keyPEM := testCertificate(t, "www.example.com", time.Now().AddDate(0, 3, 0))
+	rotation, err := client.CloudLoadBalancer.RotateListenerCertificate(ctx, "l1", certPEM, keyPEM, "",
+		&ListenerCertificateRotateOptions{DeleteOld: true})
+	require.NoError(t, err)
+	assert.False(t, rotation.OldDeleted)
+	assert.Empty(t, store.deleted)
+}
+
+func TestRotateListenerCertificateSwitchesBack(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	store := newFakeCertificateStore(t, &CloudLoadBalancerListener{ID: "l1", Name: "https", Protocol: "TERMINATED_HTTPS"})
+	oldCert, _ := testCertificate(t, "www.example.com", time.Now().AddDate(0, 0, 5))
+	oldRef := store.add(oldCert)
+	store.listeners["l1"].DefaultTLSContainerRef = &oldRef
+	store.errorRef = "https://kms.example.com/v1/containers/container-2"
+
+	certPEM, keyPEM := testCertificate(t, "www.example.com", time.Now().AddDate(0, 3, 0))
+	_, err := client.CloudLoadBalancer.RotateListenerCertificate(ctx, "l1", certPEM, keyPEM, "",
+		&ListenerCertificateRotateOptions{DeleteOld: true})
+	assert.True(t, errors.Is(err, ErrCommon))
+	assert.Equal(t, oldRef, *store.listeners["l1"].DefaultTLSContainerRef)
+	assert.Equal(t, []string{"container-2"}, store.deleted)
+}
+
+func TestExpiringListenerCertificates(t *testing.T) {
+	setup()
+	defer teardown()
+
+	store := newFakeCertificateStore(t,
+		&CloudLoadBalancerListener{ID: "l1", Name: "https", Protocol: "TERMINATED_HTTPS"},
+		&CloudLoadBalancerListener{ID: "l2", Name: "https-alt", Protocol: "TERMINATED_HTTPS"},
+	)
+	soon, _ := testCertificate(t, "soon.example.com", time.Now().AddDate(0, 0, 10))
+	sooner, _ := testCertificate(t, "sooner.example.com", time.Now().AddDate(0, 0, 2))
+	later, _ := testCertificate(t, "later.example.com", time.Now().AddDate(0, 0, 90))
+	soonRef, soonerRef, laterRef := store.add(soon), store.add(sooner), store.add(later)
+	store.listeners["l1"].DefaultTLSContainerRef = &laterRef
+	store.listeners["l1"].SNIContainerRefs = []string{soonRef}
+	store.listeners["l2"].DefaultTLSContainerRef = &soonerRef
+
+	expiring, err := client.CloudLoadBalancer.ExpiringListenerCertificates(ctx, 30)
+	require.NoError(t, err)
+	require.Len(t, expiring, 2)
+	assert.Equal(t, "l2", expiring[0].ListenerID)
+	assert.Equal(t, "sooner.example.com", expiring[0].CommonName)
+	assert.False(t, expiring[0].SNI)
+	assert.Equal(t, "l1", expiring[1].ListenerID)
+	assert.Equal(t, soonRef, expiring[1].ContainerRef)
+	assert.True(t, expiring[1].SNI)
+}
@@ -18,6 +18,11 @@ type LoadBalancerService interface {
 	List(ctx context.Context, opts *ListOptions) ([]*LoadBalancer, error)
 	Resize(ctx context.Context, id string, newType string) error
 	Update(ctx context.Context, id string, req *LoadBalancerUpdateRequest) (*LoadBalancer, error)
+	Describe(ctx context.Context, id string) (*LoadBalancerTree, error)
+	Apply(ctx context.Context, spec *LoadBalancerSpec, opts *LoadBalancerApplyOptions) (*LoadBalancerApplyReport, error)
+	RotateListenerCertificate(ctx context.Context, listenerID string, certPEM, keyPEM, chainPEM string,
+		opts *ListenerCertificateRotateOptions) (*ListenerCertificateRotation, error)
+	ExpiringListenerCertificates(ctx context.Context, days int) ([]*ListenerCertificateExpiry, error)
 
 	Listeners() *cloudLoadBalancerListenerResource
 	Pools() *cloudLoadBalancerPoolResource
@@ -27,14 +32,14 @@ type LoadBalancerService interface {
 }
 
 type ListenerHealthMonitor struct {
-	Type           string `json:"type"`
-	URLPath        string `json:"url_path"`
-	HTTPMethod     string `json:"http_method"`
-	ExpectedCodes  string `json:"expected_codes"`
-	MaxRetries     int    `json:"max_retries"`
-	MaxRetriesDown int    `json:"max_retries_down"`
-	Delay          int    `json:"delay"`
-	Timeout        int    `json:"timeout"`
+	Type           HealthMonitorType       `json:"type"`
+	URLPath        string                  `json:"url_path"`
+	HTTPMethod     HealthMonitorHTTPMethod `json:"http_method"`
+	ExpectedCodes  string                  `json:"expected_codes"`
+	MaxRetries     int                     `json:"max_retries"`
+	MaxRetriesDown int                     `json:"max_retries_down"`
+	Delay          int                     `json:"delay"`
+	Timeout        int                     `json:"timeout"`
 }
 
 type LoadBalancerResizeRequest struct {
@@ -43,29 +48,29 @@ type LoadBalancerResizeRequest struct {
 }
 
 type ListenerPool struct {
-	LbAlgorithm                    string                `json:"lb_algorithm"`
+	LbAlgorithm                    LBAlgorithm           `json:"lb_algorithm"`
 	Name                           string                `json:"name"`
-	Protocol                       string                `json:"protocol"`
+	Protocol                       LoadBalancerProtocol  `json:"protocol"`
 	Members                        []string              `json:"members"`
 	CloudLoadBalancerHealthMonitor ListenerHealthMonitor `json:"healthmonitor"`
 }
 
 type LoadBalancerListener struct {
-	Name          string       `json:"name"`
-	Protocol      string       `json:"protocol"`
-	ProtocolPort  int          `json:"protocol_port"`
-	DefaultTLSRef string       `json:"default_tls_container_ref,omitempty"`
-	DefaultPool   ListenerPool `json:"default_pool"`
+	Name          string               `json:"name"`
+	Protocol      LoadBalancerProtocol `json:"protocol"`
+	ProtocolPort  int                  `json:"protocol_port"`
+	DefaultTLSRef string               `json:"default_tls_container_ref,omitempty"`
+	DefaultPool   ListenerPool         `json:"default_pool"`
 }
 
 // LoadBalancerCreateRequest represents create new load balancer request payload.
 type LoadBalancerCreateRequest struct {
-	Name         string                 `json:"name"`
-	Description  string                 `json:"description,omitempty"`
-	NetworkType  string                 `json:"network_type"`
-	VPCNetworkID string                 `json:"vip_network_id"`
-	Listeners    []LoadBalancerListener `json:"listeners,omitempty"`
-	Type         string                 `json:"type"`
+	Name         string                  `json:"name"`
+	Description  string                  `json:"description,omitempty"`
+	NetworkType  LoadBalancerNetworkType `json:"network_type"`
+	VPCNetworkID string                  `json:"vip_network_id"`
+	Listeners    []LoadBalancerListener  `json:"listeners,omitempty"`
+	Type         LoadBalancerType        `json:"type"`
 }
 
 // LoadBalancerUpdateRequest represents update load balancer request payload.
@@ -145,6 +150,9 @@ func (l *cloudLoadBalancerService) List(ctx context.Context, opts *ListOptions)
 
 // Create - creates a new load balancer.
 func (l *cloudLoadBalancerService) Create(ctx context.Context, lbcr *LoadBalancerCreateRequest) (*LoadBalancer, error) {
+	if err := lbcr.Validate(); err != nil {
+		return nil, err
+	}
 	var data struct {
 		LoadBalancer *LoadBalancerCreateRequest `json:"loadbalancer"`
 	}
@@ -193,6 +201,9 @@ func (l *cloudLoadBalancerService) Get(ctx context.Context, id string) (*LoadBal
 
 // Update - update the load balancer's information.
 func (l *cloudLoadBalancerService) Update(ctx context.Context, id string, lbur *LoadBalancerUpdateRequest) (*LoadBalancer, error) {
+	if err := lbur.Validate(); err != nil {
+		return nil, err
+	}
 	var data struct {
 		LoadBalancer *LoadBalancerUpdateRequest `json:"loadbalancer"`
 	}
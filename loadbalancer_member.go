@@ -18,6 +18,9 @@ type CloudLoadBalancerMemberService interface {
 	Delete(ctx context.Context, poolID, id string) error
 	Create(ctx context.Context, poolID string, req *CloudLoadBalancerMemberCreateRequest) (*CloudLoadBalancerMember, error)
 	BatchUpdate(ctx context.Context, poolID string, members *CloudLoadBalancerBatchMemberUpdateRequest) error
+	Drain(ctx context.Context, poolID, id string, opts *DrainOptions) error
+	RollingReplace(ctx context.Context, poolID string, oldMembers []string, newMembers []*CloudLoadBalancerMemberCreateRequest,
+		batchSize int, opts *DrainOptions) ([]*CloudLoadBalancerMember, error)
 }
 
 // CloudLoadBalancerMemberUpdateRequest represents update member request payload.
@@ -137,6 +140,9 @@ func (m *cloudLoadBalancerMemberResource) Get(ctx context.Context, poolID, id st
 
 // Update - Update member's information
 func (m *cloudLoadBalancerMemberResource) Update(ctx context.Context, poolID, id string, mur *CloudLoadBalancerMemberUpdateRequest) (*CloudLoadBalancerMember, error) {
+	if err := mur.Validate(); err != nil {
+		return nil, err
+	}
 	var data struct {
 		CloudLoadBalancerMember *CloudLoadBalancerMemberUpdateRequest `json:"member"`
 	}
@@ -196,6 +202,9 @@ func (m *cloudLoadBalancerMemberResource) Delete(ctx context.Context, poolID, id
 
 // Create - Create a new member
 func (m *cloudLoadBalancerMemberResource) Create(ctx context.Context, poolID string, mcr *CloudLoadBalancerMemberCreateRequest) (*CloudLoadBalancerMember, error) {
+	if err := mcr.Validate(); err != nil {
+		return nil, err
+	}
 	var data struct {
 		CloudLoadBalancerMember *CloudLoadBalancerMemberCreateRequest `json:"member"`
 	}
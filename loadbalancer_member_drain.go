@@ -0,0 +1,182 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultDrainPeriod = 30 * time.Second
+
+	memberOnlineStatus    = "ONLINE"
+	memberOfflineStatus   = "OFFLINE"
+	memberErrorStatus     = "ERROR"
+	memberNoMonitorStatus = "NO_MONITOR"
+)
+
+// DrainOptions represents options when draining members from a pool.
+type DrainOptions struct {
+	// Period is how long established connections may drain before the member is removed, 30 seconds by default.
+	// Draining ends earlier when the member goes OFFLINE or ERROR.
+	Period time.Duration
+	// Keep leaves the drained member in the pool with a zero weight instead of removing it.
+	Keep bool
+}
+
+// Drain stops new connections to a member by setting its weight to 0, waits for the drain period, then removes it.
+// A nil opts uses the defaults of DrainOptions.
+func (m *cloudLoadBalancerMemberResource) Drain(ctx context.Context, poolID, id string, opts *DrainOptions) error {
+	return m.drain(ctx, poolID, []string{id}, opts)
+}
+
+// RollingReplace replaces the old members of a pool, given by ID, with new ones batchSize at a time. Each batch of new
+// members is added and must be reported healthy by the health monitor before a batch of old members is drained,
+// so the pool never serves with less than its current capacity. Creates are not retried, a create which failed
+// after the backend made the member would add it twice. The created members are returned, even on error.
+func (m *cloudLoadBalancerMemberResource) RollingReplace(ctx context.Context, poolID string, oldMembers []string,
+	newMembers []*CloudLoadBalancerMemberCreateRequest, batchSize int, opts *DrainOptions) ([]*CloudLoadBalancerMember, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive: %w", ErrCommon)
+	}
+	created := make([]*CloudLoadBalancerMember, 0, len(newMembers))
+	for len(oldMembers) > 0 || len(newMembers) > 0 {
+		n := min(batchSize, len(newMembers))
+		batch := make([]string, 0, n)
+		for _, mcr := range newMembers[:n] {
+			member, err := m.Create(ctx, poolID, mcr)
+			if err != nil {
+				return created, err
+			}
+			created = append(created, member)
+			batch = append(batch, member.ID)
+		}
+		newMembers = newMembers[n:]
+		if err := m.waitHealthy(ctx, poolID, batch); err != nil {
+			return created, err
+		}
+
+		n = min(batchSize, len(oldMembers))
+		if err := m.drain(ctx, poolID, oldMembers[:n], opts); err != nil {
+			return created, err
+		}
+		oldMembers = oldMembers[n:]
+	}
+	return created, nil
+}
+
+// drain sets the weight of members to 0, waits for their connections to drain and removes them.
+func (m *cloudLoadBalancerMemberResource) drain(ctx context.Context, poolID string, ids []string, opts *DrainOptions) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if opts == nil {
+		opts = &DrainOptions{}
+	}
+	period := opts.Period
+	if period <= 0 {
+		period = defaultDrainPeriod
+	}
+	for _, id := range ids {
+		member, err := m.Get(ctx, poolID, id)
+		if err != nil {
+			return err
+		}
+		if err := m.client.retryTransient(ctx, func() error {
+			return m.setWeight(ctx, poolID, member, 0)
+		}); err != nil {
+			return err
+		}
+		if err := m.waitProvisioned(ctx, poolID, id); err != nil {
+			return err
+		}
+	}
+
+	drainCtx, cancel := context.WithTimeout(ctx, period)
+	defer cancel()
+	err := m.client.waitFor(drainCtx, func() (bool, error) {
+		for _, id := range ids {
+			member, err := m.Get(drainCtx, poolID, id)
+			if err != nil {
+				return false, err
+			}
+			if member.OperatingStatus != memberOfflineStatus && member.OperatingStatus != memberErrorStatus {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil && (ctx.Err() != nil || !errors.Is(err, context.DeadlineExceeded)) {
+		return err
+	}
+	if opts.Keep {
+		return nil
+	}
+
+	for _, id := range ids {
+		if err := m.client.retryTransient(ctx, func() error {
+			return m.Delete(ctx, poolID, id)
+		}); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// setWeight updates the weight of a member. Update cannot set a zero weight, which is omitted from its payload.
+func (m *cloudLoadBalancerMemberResource) setWeight(ctx context.Context, poolID string, member *CloudLoadBalancerMember, weight int) error {
+	var data struct {
+		Member struct {
+			Name   string `json:"name"`
+			Weight int    `json:"weight"`
+		} `json:"member"`
+	}
+	data.Member.Name = member.Name
+	data.Member.Weight = weight
+	req, err := m.client.NewRequest(ctx, http.MethodPut, loadBalancerServiceName, m.itemPath(poolID, member.ID), &data)
+	if err != nil {
+		return err
+	}
+	resp, err := m.client.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// waitProvisioned waits until a member is ACTIVE after a change.
+func (m *cloudLoadBalancerMemberResource) waitProvisioned(ctx context.Context, poolID, id string) error {
+	return m.client.waitFor(ctx, func() (bool, error) {
+		member, err := m.Get(ctx, poolID, id)
+		if err != nil {
+			return false, err
+		}
+		if member.ProvisoningStatus == loadBalancerErrorStatus {
+			return false, fmt.Errorf("member %s is in %s status: %w", id, member.ProvisoningStatus, ErrCommon)
+		}
+		return member.ProvisoningStatus == loadBalancerActiveStatus, nil
+	})
+}
+
+// waitHealthy waits until members are ONLINE, or NO_MONITOR when the pool has no health monitor.
+func (m *cloudLoadBalancerMemberResource) waitHealthy(ctx context.Context, poolID string, ids []string) error {
+	return m.client.waitFor(ctx, func() (bool, error) {
+		for _, id := range ids {
+			member, err := m.Get(ctx, poolID, id)
+			if err != nil {
+				return false, err
+			}
+			if member.ProvisoningStatus == loadBalancerErrorStatus {
+				return false, fmt.Errorf("member %s is in %s status: %w", id, member.ProvisoningStatus, ErrCommon)
+			}
+			if member.OperatingStatus != memberOnlineStatus && member.OperatingStatus != memberNoMonitorStatus {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
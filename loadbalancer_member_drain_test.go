@@ -0,0 +1,181 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePoolMembers serves the members of pool-1. Members are provisioned as soon as they are read, new members
+// become ONLINE on their second read and drained members go OFFLINE when offlineOnDrain is set.
+type fakePoolMembers struct {
+	mu             sync.Mutex
+	nextID         int
+	members        map[string]*CloudLoadBalancerMember
+	reads          map[string]int
+	offlineOnDrain bool
+	// createGatewayErrors makes creates fail with 502 after the member was added.
+	createGatewayErrors int
+	log                 []string
+}
+
+func newFakePoolMembers(t *testing.T, members ...*CloudLoadBalancerMember) *fakePoolMembers {
+	f := &fakePoolMembers{members: map[string]*CloudLoadBalancerMember{}, reads: map[string]int{}}
+	for _, member := range members {
+		member.ProvisoningStatus = loadBalancerActiveStatus
+		f.members[member.ID] = member
+	}
+	mux.HandleFunc(testlib.LoadBalancerURL("/pool/pool-1/member"), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		var data struct {
+			Member CloudLoadBalancerMemberCreateRequest `json:"member"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&data))
+		f.nextID++
+		member := &CloudLoadBalancerMember{ID: fmt.Sprintf("new-%d", f.nextID), Name: data.Member.Name,
+			Address: data.Member.Address, ProtocolPort: data.Member.ProtocolPort, Weight: 1,
+			ProvisoningStatus: "PENDING_CREATE", OperatingStatus: memberOfflineStatus}
+		f.members[member.ID] = member
+		f.log = append(f.log, "create "+member.Address)
+		if f.createGatewayErrors > 0 {
+			f.createGatewayErrors--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"member": member})
+	})
+	mux.HandleFunc(testlib.LoadBalancerURL("/pool/pool-1/member/"), func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		member, ok := f.members[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			f.reads[id]++
+			if member.ProvisoningStatus != loadBalancerActiveStatus {
+				member.ProvisoningStatus = loadBalancerActiveStatus
+			} else if strings.HasPrefix(id, "new-") && f.reads[id] > 1 {
+				member.OperatingStatus = memberOnlineStatus
+			}
+			_ = json.NewEncoder(w).Encode(member)
+		case http.MethodPut:
+			var data struct {
+				Member map[string]interface{} `json:"member"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&data))
+			assert.Equal(t, member.Name, data.Member["name"])
+			require.Contains(t, data.Member, "weight")
+			member.Weight = int(data.Member["weight"].(float64))
+			member.ProvisoningStatus = "PENDING_UPDATE"
+			member.OperatingStatus = "DRAINING"
+			if f.offlineOnDrain {
+				member.OperatingStatus = memberOfflineStatus
+			}
+			f.log = append(f.log, fmt.Sprintf("weight %s %d", member.Address, member.Weight))
+		case http.MethodDelete:
+			delete(f.members, id)
+			f.log = append(f.log, "delete "+member.Address)
+		}
+	})
+	return f
+}
+
+func TestLoadBalancerMemberDrain(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	backend := newFakePoolMembers(t, &CloudLoadBalancerMember{ID: "m1", Name: "web-1", Address: "10.0.0.1",
+		ProtocolPort: 80, Weight: 1, OperatingStatus: memberOnlineStatus})
+	err := client.CloudLoadBalancer.Members().Drain(ctx, "pool-1", "m1", &DrainOptions{Period: 20 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"weight 10.0.0.1 0", "delete 10.0.0.1"}, backend.log)
+	assert.Empty(t, backend.members)
+}
+
+func TestLoadBalancerMemberDrainKeep(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	backend := newFakePoolMembers(t, &CloudLoadBalancerMember{ID: "m1", Name: "web-1", Address: "10.0.0.1",
+		ProtocolPort: 80, Weight: 1, OperatingStatus: memberOnlineStatus})
+	backend.offlineOnDrain = true
+	start := time.Now()
+	err := client.CloudLoadBalancer.Members().Drain(ctx, "pool-1", "m1", &DrainOptions{Period: time.Hour, Keep: true})
+	require.NoError(t, err)
+	assert.True(t, time.Since(start) < time.Minute)
+	assert.Equal(t, []string{"weight 10.0.0.1 0"}, backend.log)
+	assert.Equal(t, 0, backend.members["m1"].Weight)
+}
+
+func TestLoadBalancerMemberRollingReplace(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	backend := newFakePoolMembers(t,
+		&CloudLoadBalancerMember{ID: "m1", Name: "web-1", Address: "10.0.0.1", ProtocolPort: 80, Weight: 1, OperatingStatus: memberOnlineStatus},
+		&CloudLoadBalancerMember{ID: "m2", Name: "web-2", Address: "10.0.0.2", ProtocolPort: 80, Weight: 1, OperatingStatus: memberOnlineStatus},
+		&CloudLoadBalancerMember{ID: "m3", Name: "web-3", Address: "10.0.0.3", ProtocolPort: 80, Weight: 1, OperatingStatus: memberOnlineStatus},
+	)
+	backend.offlineOnDrain = true
+	created, err := client.CloudLoadBalancer.Members().RollingReplace(ctx, "pool-1", []string{"m1", "m2", "m3"},
+		[]*CloudLoadBalancerMemberCreateRequest{
+			{Name: "web-4", Address: "10.0.1.1", ProtocolPort: 80},
+			{Name: "web-5", Address: "10.0.1.2", ProtocolPort: 80},
+		}, 2, &DrainOptions{Period: time.Hour})
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+	assert.Equal(t, []string{
+		"create 10.0.1.1",
+		"create 10.0.1.2",
+		"weight 10.0.0.1 0",
+		"weight 10.0.0.2 0",
+		"delete 10.0.0.1",
+		"delete 10.0.0.2",
+		"weight 10.0.0.3 0",
+		"delete 10.0.0.3",
+	}, backend.log)
+	assert.Len(t, backend.members, 2)
+	for _, member := range backend.members {
+		assert.Equal(t, memberOnlineStatus, member.OperatingStatus)
+	}
+
+	_, err = client.CloudLoadBalancer.Members().RollingReplace(ctx, "pool-1", nil, nil, 0, nil)
+	assert.Error(t, err)
+}
+
+func TestLoadBalancerMemberRollingReplaceDoesNotRetryCreates(t *testing.T) {
+	setup()
+	defer teardown()
+	client.pollInterval = time.Millisecond
+
+	backend := newFakePoolMembers(t,
+		&CloudLoadBalancerMember{ID: "m1", Name: "web-1", Address: "10.0.0.1", ProtocolPort: 80, Weight: 1, OperatingStatus: memberOnlineStatus},
+	)
+	backend.createGatewayErrors = 1
+	created, err := client.CloudLoadBalancer.Members().RollingReplace(ctx, "pool-1", []string{"m1"},
+		[]*CloudLoadBalancerMemberCreateRequest{{Name: "web-2", Address: "10.0.1.1", ProtocolPort: 80}}, 1, nil)
+	assert.True(t, errors.Is(err, ErrTransient))
+	assert.Empty(t, created)
+	assert.Equal(t, []string{"create 10.0.1.1"}, backend.log)
+	assert.Len(t, backend.members, 2)
+}
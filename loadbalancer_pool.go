@@ -22,23 +22,23 @@ type CloudLoadBalancerPoolService interface {
 // SessionPersistence object controls how LoadBalancer sends request to backend.
 // See https://support.bizflycloud.vn/api/loadbalancer/#post-loadbalancer-load_balancer_id-pools
 type SessionPersistence struct {
-	Type                   string  `json:"type"`
-	CookieName             *string `json:"cookie_name,omitempty"`
-	PersistenceTimeout     *string `json:"persistence_timeout,omitempty"`
-	PersistenceGranularity *string `json:"persistence_granularity,omitempty"`
+	Type                   SessionPersistenceType `json:"type"`
+	CookieName             *string                `json:"cookie_name,omitempty"`
+	PersistenceTimeout     *string                `json:"persistence_timeout,omitempty"`
+	PersistenceGranularity *string                `json:"persistence_granularity,omitempty"`
 }
 
 type CloudLoadBalancerPoolHealthMonitorRequest struct {
-	ID             string `json:"id,omitempty"`
-	Name           string `json:"name,omitempty"`
-	Delay          int    `json:"delay"`
-	ExpectedCodes  string `json:"expected_codes,omitempty"`
-	HttpMethod     string `json:"http_method,omitempty"`
-	MaxRetries     int    `json:"max_retries"`
-	MaxRetriesDown int    `json:"max_retries_down"`
-	Timeout        int    `json:"timeout"`
-	Type           string `json:"type"`
-	URLPath        string `json:"url_path,omitempty"`
+	ID             string                  `json:"id,omitempty"`
+	Name           string                  `json:"name,omitempty"`
+	Delay          int                     `json:"delay"`
+	ExpectedCodes  string                  `json:"expected_codes,omitempty"`
+	HttpMethod     HealthMonitorHTTPMethod `json:"http_method,omitempty"`
+	MaxRetries     int                     `json:"max_retries"`
+	MaxRetriesDown int                     `json:"max_retries_down"`
+	Timeout        int                     `json:"timeout"`
+	Type           HealthMonitorType       `json:"type"`
+	URLPath        string                  `json:"url_path,omitempty"`
 }
 
 type CloudLoadBalancerPoolMemberRequest struct {
@@ -52,10 +52,10 @@ type CloudLoadBalancerPoolMemberRequest struct {
 
 // CloudLoadBalancerPoolCreateRequest represents create new pool request payload.
 type CloudLoadBalancerPoolCreateRequest struct {
-	LBAlgorithm        string                                     `json:"lb_algorithm"`
+	LBAlgorithm        LBAlgorithm                                `json:"lb_algorithm"`
 	ListenerID         string                                     `json:"listener_id,omitempty"`
 	Name               *string                                    `json:"name,omitempty"`
-	Protocol           string                                     `json:"protocol"`
+	Protocol           LoadBalancerProtocol                       `json:"protocol"`
 	SessionPersistence *SessionPersistence                        `json:"session_persistence,omitempty"`
 	HealthMonitor      *CloudLoadBalancerPoolHealthMonitorRequest `json:"healthmonitor,omitempty"`
 	Members            []CloudLoadBalancerPoolMemberRequest       `json:"members,omitempty"`
@@ -65,7 +65,7 @@ type CloudLoadBalancerPoolCreateRequest struct {
 type CloudLoadBalancerPoolUpdateRequest struct {
 	AdminStateUp       *bool                                      `json:"admin_state_up,omitempty"`
 	Description        *string                                    `json:"description,omitempty"`
-	LBAlgorithm        *string                                    `json:"lb_algorithm,omitempty"`
+	LBAlgorithm        *LBAlgorithm                               `json:"lb_algorithm,omitempty"`
 	Name               *string                                    `json:"name,omitempty"`
 	SessionPersistence *SessionPersistence                        `json:"session_persistence"`
 	Members            []CloudLoadBalancerPoolMemberRequest       `json:"members,omitempty"`
@@ -137,6 +137,9 @@ func (p *cloudLoadBalancerPoolResource) List(ctx context.Context, lbID string, o
 
 // Create - Create a new pool
 func (p *cloudLoadBalancerPoolResource) Create(ctx context.Context, lbID string, pcr *CloudLoadBalancerPoolCreateRequest) (*CloudLoadBalancerPool, error) {
+	if err := pcr.Validate(); err != nil {
+		return nil, err
+	}
 	var data struct {
 		CloudLoadBalancerPool *CloudLoadBalancerPoolCreateRequest `json:"pool"`
 	}
@@ -185,6 +188,9 @@ func (p *cloudLoadBalancerPoolResource) Get(ctx context.Context, id string) (*Cl
 
 // Update - Update a pool's information
 func (p *cloudLoadBalancerPoolResource) Update(ctx context.Context, id string, pur *CloudLoadBalancerPoolUpdateRequest) (*CloudLoadBalancerPool, error) {
+	if err := pur.Validate(); err != nil {
+		return nil, err
+	}
 	var data struct {
 		CloudLoadBalancerPool *CloudLoadBalancerPoolUpdateRequest `json:"pool"`
 	}
@@ -117,6 +117,8 @@ func TestLoadBalancerCreate(t *testing.T) {
 	lb, err := client.CloudLoadBalancer.Create(ctx, &LoadBalancerCreateRequest{
 		Description: "Test Create LB",
 		Name:        "LB",
+		NetworkType: LoadBalancerNetworkTypeExternal,
+		Type:        LoadBalancerTypeSmall,
 	})
 	require.NoError(t, err)
 	assert.Equal(t, "e389f5eb-07b5-486b-be4d-4d4d1299f0ab", lb.ID)
@@ -350,8 +352,10 @@ func TestListenerCreate(t *testing.T) {
 	name := "CloudLoadBalancerListener"
 	desc := "Test Create CloudLoadBalancerListener"
 	listener, err := client.CloudLoadBalancer.Listeners().Create(ctx, "ae8e2072-31fb-464a-8285-bc2f2a6bab4d", &CloudLoadBalancerListenerCreateRequest{
-		Description: &desc,
-		Name:        &name,
+		Description:  &desc,
+		Name:         &name,
+		Protocol:     ProtocolHTTP,
+		ProtocolPort: 80,
 	})
 	require.NoError(t, err)
 	assert.Equal(t, "5482c4a4-f822-46d0-9af3-026f7579d653", listener.ID)
@@ -749,7 +753,7 @@ func TestPoolCreate(t *testing.T) {
 		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
 		assert.Equal(t, "CloudLoadBalancerPool", *payload.CloudLoadBalancerPool.Name)
 		assert.NotNil(t, payload.CloudLoadBalancerPool.SessionPersistence)
-		assert.Equal(t, "ROUND_ROBIN", payload.CloudLoadBalancerPool.LBAlgorithm)
+		assert.Equal(t, LBAlgorithmRoundRobin, payload.CloudLoadBalancerPool.LBAlgorithm)
 
 		resp := `
 {
@@ -813,8 +817,9 @@ func TestPoolCreate(t *testing.T) {
 	pool, err := client.CloudLoadBalancer.Pools().Create(ctx, "ae8e2072-31fb-464a-8285-bc2f2a6bab4d", &CloudLoadBalancerPoolCreateRequest{
 		LBAlgorithm: "ROUND_ROBIN",
 		Name:        &name,
+		Protocol:    ProtocolHTTP,
 		SessionPersistence: &SessionPersistence{
-			Type:                   "Test",
+			Type:                   SessionPersistenceSourceIP,
 			CookieName:             nil,
 			PersistenceTimeout:     nil,
 			PersistenceGranularity: nil,
@@ -979,7 +984,7 @@ func TestHealthMonitorCreate(t *testing.T) {
 		}
 		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
 		assert.Equal(t, "super-pool-health-monitor", payload.CloudLoadBalancerHealthMonitor.Name)
-		assert.Equal(t, "HTTP", payload.CloudLoadBalancerHealthMonitor.Type)
+		assert.Equal(t, HealthMonitorTypeHTTP, payload.CloudLoadBalancerHealthMonitor.Type)
 		assert.Equal(t, "200", payload.CloudLoadBalancerHealthMonitor.ExpectedCodes)
 
 		resp := `
@@ -1125,7 +1130,7 @@ func TestHealthMonitorUpdate(t *testing.T) {
 		_, _ = fmt.Fprint(w, resp)
 	})
 
-	method := "HED"
+	method := HealthMonitorHTTPMethodHead
 	_, err := client.CloudLoadBalancer.HealthMonitors().Update(ctx, "8ed3c5ac-6efa-420c-bedb-99ba14e58db5", &CloudLoadBalancerHealthMonitorUpdateRequest{
 		Name:       "super-pool-health-monitor-updated",
 		HTTPMethod: &method,
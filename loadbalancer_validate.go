@@ -0,0 +1,405 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LoadBalancerType is the size of a load balancer.
+type LoadBalancerType string
+
+// LoadBalancerNetworkType is where the address of a load balancer is allocated.
+type LoadBalancerNetworkType string
+
+// LBAlgorithm is how a pool spreads requests over its members.
+type LBAlgorithm string
+
+// LoadBalancerProtocol is the protocol of a listener or a pool.
+type LoadBalancerProtocol string
+
+// HealthMonitorType is how a health monitor checks members.
+type HealthMonitorType string
+
+// HealthMonitorHTTPMethod is the method of HTTP health checks.
+type HealthMonitorHTTPMethod string
+
+// SessionPersistenceType is how a pool keeps a client on the same member.
+type SessionPersistenceType string
+
+const (
+	LoadBalancerTypeSmall  LoadBalancerType = "small"
+	LoadBalancerTypeMedium LoadBalancerType = "medium"
+	LoadBalancerTypeLarge  LoadBalancerType = "large"
+
+	LoadBalancerNetworkTypeExternal LoadBalancerNetworkType = "external"
+	LoadBalancerNetworkTypeInternal LoadBalancerNetworkType = "internal"
+
+	LBAlgorithmRoundRobin       LBAlgorithm = "ROUND_ROBIN"
+	LBAlgorithmLeastConnections LBAlgorithm = "LEAST_CONNECTIONS"
+	LBAlgorithmSourceIP         LBAlgorithm = "SOURCE_IP"
+	LBAlgorithmSourceIPPort     LBAlgorithm = "SOURCE_IP_PORT"
+
+	ProtocolHTTP            LoadBalancerProtocol = "HTTP"
+	ProtocolHTTPS           LoadBalancerProtocol = "HTTPS"
+	ProtocolTerminatedHTTPS LoadBalancerProtocol = "TERMINATED_HTTPS"
+	ProtocolTCP             LoadBalancerProtocol = "TCP"
+	ProtocolUDP             LoadBalancerProtocol = "UDP"
+	ProtocolProxy           LoadBalancerProtocol = "PROXY"
+	ProtocolProxyV2         LoadBalancerProtocol = "PROXYV2"
+
+	HealthMonitorTypeHTTP       HealthMonitorType = "HTTP"
+	HealthMonitorTypeHTTPS      HealthMonitorType = "HTTPS"
+	HealthMonitorTypePing       HealthMonitorType = "PING"
+	HealthMonitorTypeTCP        HealthMonitorType = "TCP"
+	HealthMonitorTypeTLSHello   HealthMonitorType = "TLS-HELLO"
+	HealthMonitorTypeUDPConnect HealthMonitorType = "UDP-CONNECT"
+
+	HealthMonitorHTTPMethodGet     HealthMonitorHTTPMethod = "GET"
+	HealthMonitorHTTPMethodHead    HealthMonitorHTTPMethod = "HEAD"
+	HealthMonitorHTTPMethodPost    HealthMonitorHTTPMethod = "POST"
+	HealthMonitorHTTPMethodPut     HealthMonitorHTTPMethod = "PUT"
+	HealthMonitorHTTPMethodDelete  HealthMonitorHTTPMethod = "DELETE"
+	HealthMonitorHTTPMethodPatch   HealthMonitorHTTPMethod = "PATCH"
+	HealthMonitorHTTPMethodOptions HealthMonitorHTTPMethod = "OPTIONS"
+	HealthMonitorHTTPMethodTrace   HealthMonitorHTTPMethod = "TRACE"
+	HealthMonitorHTTPMethodConnect HealthMonitorHTTPMethod = "CONNECT"
+
+	SessionPersistenceSourceIP   SessionPersistenceType = "SOURCE_IP"
+	SessionPersistenceHTTPCookie SessionPersistenceType = "HTTP_COOKIE"
+	SessionPersistenceAppCookie  SessionPersistenceType = "APP_COOKIE"
+)
+
+var loadBalancerNetworkTypes = map[LoadBalancerNetworkType]bool{
+	LoadBalancerNetworkTypeExternal: true, LoadBalancerNetworkTypeInternal: true,
+}
+
+var lbAlgorithms = map[LBAlgorithm]bool{
+	LBAlgorithmRoundRobin: true, LBAlgorithmLeastConnections: true, LBAlgorithmSourceIP: true, LBAlgorithmSourceIPPort: true,
+}
+
+var loadBalancerProtocols = map[LoadBalancerProtocol]bool{
+	ProtocolHTTP: true, ProtocolHTTPS: true, ProtocolTerminatedHTTPS: true, ProtocolTCP: true, ProtocolUDP: true,
+	ProtocolProxy: true, ProtocolProxyV2: true,
+}
+
+var listenerProtocols = map[LoadBalancerProtocol]bool{
+	ProtocolHTTP: true, ProtocolHTTPS: true, ProtocolTerminatedHTTPS: true, ProtocolTCP: true, ProtocolUDP: true,
+}
+
+var healthMonitorTypes = map[HealthMonitorType]bool{
+	HealthMonitorTypeHTTP: true, HealthMonitorTypeHTTPS: true, HealthMonitorTypePing: true, HealthMonitorTypeTCP: true,
+	HealthMonitorTypeTLSHello: true, HealthMonitorTypeUDPConnect: true,
+}
+
+var healthMonitorHTTPMethods = map[HealthMonitorHTTPMethod]bool{
+	HealthMonitorHTTPMethodGet: true, HealthMonitorHTTPMethodHead: true, HealthMonitorHTTPMethodPost: true,
+	HealthMonitorHTTPMethodPut: true, HealthMonitorHTTPMethodDelete: true, HealthMonitorHTTPMethodPatch: true,
+	HealthMonitorHTTPMethodOptions: true, HealthMonitorHTTPMethodTrace: true, HealthMonitorHTTPMethodConnect: true,
+}
+
+var sessionPersistenceTypes = map[SessionPersistenceType]bool{
+	SessionPersistenceSourceIP: true, SessionPersistenceHTTPCookie: true, SessionPersistenceAppCookie: true,
+}
+
+// expectedCodesRe matches a status code, a list of codes or a range of codes, such as 200, 200,202 or 200-204.
+var expectedCodesRe = regexp.MustCompile(`^[1-5][0-9]{2}(-[1-5][0-9]{2}|(,[1-5][0-9]{2})*)$`)
+
+// healthMonitorParams are the fields shared by the health monitor payloads. Zero values are not set.
+type healthMonitorParams struct {
+	Type           HealthMonitorType
+	Delay          int
+	Timeout        int
+	MaxRetries     int
+	MaxRetriesDown int
+	HTTPMethod     HealthMonitorHTTPMethod
+	URLPath        string
+	ExpectedCodes  string
+}
+
+func (p healthMonitorParams) validate() error {
+	if p.Type != "" && !healthMonitorTypes[p.Type] {
+		return fmt.Errorf("invalid health monitor type %q: %w", p.Type, ErrCommon)
+	}
+	if p.Delay < 0 || p.Timeout < 0 {
+		return fmt.Errorf("health monitor delay and timeout must not be negative: %w", ErrCommon)
+	}
+	if p.Delay > 0 && p.Timeout > 0 && p.Timeout >= p.Delay {
+		return fmt.Errorf("health monitor timeout %d must be less than delay %d: %w", p.Timeout, p.Delay, ErrCommon)
+	}
+	if p.MaxRetries != 0 && (p.MaxRetries < 1 || p.MaxRetries > 10) {
+		return fmt.Errorf("health monitor max retries must be between 1 and 10: %w", ErrCommon)
+	}
+	if p.MaxRetriesDown != 0 && (p.MaxRetriesDown < 1 || p.MaxRetriesDown > 10) {
+		return fmt.Errorf("health monitor max retries down must be between 1 and 10: %w", ErrCommon)
+	}
+	isHTTP := p.Type == "" || p.Type == HealthMonitorTypeHTTP || p.Type == HealthMonitorTypeHTTPS
+	if !isHTTP && (p.HTTPMethod != "" || p.URLPath != "" || p.ExpectedCodes != "") {
+		return fmt.Errorf("http method, url path and expected codes only apply to HTTP health monitors: %w", ErrCommon)
+	}
+	if p.HTTPMethod != "" && !healthMonitorHTTPMethods[p.HTTPMethod] {
+		return fmt.Errorf("invalid health monitor http method %q: %w", p.HTTPMethod, ErrCommon)
+	}
+	if p.URLPath != "" && !strings.HasPrefix(p.URLPath, "/") {
+		return fmt.Errorf("health monitor url path %q must start with /: %w", p.URLPath, ErrCommon)
+	}
+	if p.ExpectedCodes != "" && !expectedCodesRe.MatchString(p.ExpectedCodes) {
+		return fmt.Errorf("invalid health monitor expected codes %q: %w", p.ExpectedCodes, ErrCommon)
+	}
+	return nil
+}
+
+// validatePoolHealthMonitor checks that a health monitor type can check the members of a pool.
+func validatePoolHealthMonitor(protocol LoadBalancerProtocol, hmType HealthMonitorType) error {
+	if (protocol == ProtocolUDP) != (hmType == HealthMonitorTypeUDPConnect) {
+		return fmt.Errorf("UDP pools need UDP-CONNECT health monitors, got %s for a %s pool: %w", hmType, protocol, ErrCommon)
+	}
+	return nil
+}
+
+func validatePort(port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port %d: %w", port, ErrCommon)
+	}
+	return nil
+}
+
+// validateListener checks the protocol, port and certificate of a listener.
+func validateListener(protocol LoadBalancerProtocol, port int, tlsContainerRef string) error {
+	if !listenerProtocols[protocol] {
+		return fmt.Errorf("invalid listener protocol %q: %w", protocol, ErrCommon)
+	}
+	if err := validatePort(port); err != nil {
+		return err
+	}
+	if (protocol == ProtocolHTTPS || protocol == ProtocolTerminatedHTTPS) && tlsContainerRef == "" {
+		return fmt.Errorf("%s listeners need a default TLS container ref: %w", protocol, ErrCommon)
+	}
+	return nil
+}
+
+// validatePool checks the algorithm and protocol of a pool. An empty algorithm is not validated.
+func validatePool(algorithm LBAlgorithm, protocol LoadBalancerProtocol) error {
+	if algorithm != "" && !lbAlgorithms[algorithm] {
+		return fmt.Errorf("invalid lb algorithm %q: %w", algorithm, ErrCommon)
+	}
+	if !loadBalancerProtocols[protocol] {
+		return fmt.Errorf("invalid pool protocol %q: %w", protocol, ErrCommon)
+	}
+	return nil
+}
+
+// Validate checks the type and cookie name of a session persistence.
+func (s *SessionPersistence) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if !sessionPersistenceTypes[s.Type] {
+		return fmt.Errorf("invalid session persistence type %q: %w", s.Type, ErrCommon)
+	}
+	if (s.Type == SessionPersistenceAppCookie) != (stringValue(s.CookieName) != "") {
+		return fmt.Errorf("a cookie name is required by, and only allowed with, APP_COOKIE persistence: %w", ErrCommon)
+	}
+	return nil
+}
+
+// Validate checks the load balancer network and type, and the listeners created with it.
+func (r *LoadBalancerCreateRequest) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("load balancer name is required: %w", ErrCommon)
+	}
+	if !loadBalancerNetworkTypes[r.NetworkType] {
+		return fmt.Errorf("invalid load balancer network type %q: %w", r.NetworkType, ErrCommon)
+	}
+	if r.NetworkType == LoadBalancerNetworkTypeInternal && r.VPCNetworkID == "" {
+		return fmt.Errorf("internal load balancers need a VPC network: %w", ErrCommon)
+	}
+	// Types are offered per region, so only their presence is checked.
+	if r.Type == "" {
+		return fmt.Errorf("load balancer type is required: %w", ErrCommon)
+	}
+	for _, listener := range r.Listeners {
+		if err := listener.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks that a name, when updated, is not empty.
+func (r *LoadBalancerUpdateRequest) Validate() error {
+	if r.Name != nil && *r.Name == "" {
+		return fmt.Errorf("load balancer name must not be empty: %w", ErrCommon)
+	}
+	return nil
+}
+
+// Validate checks a listener created with its load balancer and its default pool.
+func (l *LoadBalancerListener) Validate() error {
+	if err := validateListener(l.Protocol, l.ProtocolPort, l.DefaultTLSRef); err != nil {
+		return err
+	}
+	pool := l.DefaultPool
+	if err := validatePool(pool.LbAlgorithm, pool.Protocol); err != nil {
+		return err
+	}
+	if (l.Protocol == ProtocolUDP) != (pool.Protocol == ProtocolUDP) {
+		return fmt.Errorf("UDP listeners and pools go together, got a %s pool for a %s listener: %w", pool.Protocol, l.Protocol, ErrCommon)
+	}
+	return pool.CloudLoadBalancerHealthMonitor.validate(pool.Protocol)
+}
+
+func (h ListenerHealthMonitor) validate(protocol LoadBalancerProtocol) error {
+	if h.Type == "" {
+		return nil
+	}
+	if err := validatePoolHealthMonitor(protocol, h.Type); err != nil {
+		return err
+	}
+	return healthMonitorParams{
+		Type:           h.Type,
+		Delay:          h.Delay,
+		Timeout:        h.Timeout,
+		MaxRetries:     h.MaxRetries,
+		MaxRetriesDown: h.MaxRetriesDown,
+		HTTPMethod:     h.HTTPMethod,
+		URLPath:        h.URLPath,
+		ExpectedCodes:  h.ExpectedCodes,
+	}.validate()
+}
+
+// Validate checks the protocol, port and certificate of the listener.
+func (r *CloudLoadBalancerListenerCreateRequest) Validate() error {
+	return validateListener(r.Protocol, r.ProtocolPort, stringValue(r.DefaultTLSContainerRef))
+}
+
+// Validate checks that timeouts are not negative.
+func (r *CloudLoadBalancerListenerUpdateRequest) Validate() error {
+	for _, timeout := range []*int{r.TimeoutClientData, r.TimeoutMemberConnect, r.TimeoutMemberData, r.TimeoutTCPInspect} {
+		if timeout != nil && *timeout < 0 {
+			return fmt.Errorf("listener timeouts must not be negative: %w", ErrCommon)
+		}
+	}
+	return nil
+}
+
+// Validate checks the algorithm, protocol, session persistence and health monitor of the pool.
+func (r *CloudLoadBalancerPoolCreateRequest) Validate() error {
+	if r.LBAlgorithm == "" {
+		return fmt.Errorf("lb algorithm is required: %w", ErrCommon)
+	}
+	if err := validatePool(r.LBAlgorithm, r.Protocol); err != nil {
+		return err
+	}
+	if err := r.SessionPersistence.Validate(); err != nil {
+		return err
+	}
+	if r.HealthMonitor != nil {
+		if err := validatePoolHealthMonitor(r.Protocol, r.HealthMonitor.Type); err != nil {
+			return err
+		}
+		if err := r.HealthMonitor.validate(); err != nil {
+			return err
+		}
+	}
+	for _, member := range r.Members {
+		if err := validatePort(member.Port); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the algorithm, session persistence and health monitor of the pool.
+func (r *CloudLoadBalancerPoolUpdateRequest) Validate() error {
+	if r.LBAlgorithm != nil && !lbAlgorithms[*r.LBAlgorithm] {
+		return fmt.Errorf("invalid lb algorithm %q: %w", *r.LBAlgorithm, ErrCommon)
+	}
+	if err := r.SessionPersistence.Validate(); err != nil {
+		return err
+	}
+	if r.HealthMonitor != nil {
+		return r.HealthMonitor.validate()
+	}
+	return nil
+}
+
+func (r *CloudLoadBalancerPoolHealthMonitorRequest) validate() error {
+	if r.Type == "" {
+		return fmt.Errorf("health monitor type is required: %w", ErrCommon)
+	}
+	return healthMonitorParams{
+		Type:           r.Type,
+		Delay:          r.Delay,
+		Timeout:        r.Timeout,
+		MaxRetries:     r.MaxRetries,
+		MaxRetriesDown: r.MaxRetriesDown,
+		HTTPMethod:     r.HttpMethod,
+		URLPath:        r.URLPath,
+		ExpectedCodes:  r.ExpectedCodes,
+	}.validate()
+}
+
+// Validate checks the type, timings and HTTP options of the health monitor.
+func (r *CloudLoadBalancerHealthMonitorCreateRequest) Validate() error {
+	if r.Type == "" {
+		return fmt.Errorf("health monitor type is required: %w", ErrCommon)
+	}
+	return healthMonitorParams{
+		Type:           r.Type,
+		Delay:          r.Delay,
+		Timeout:        r.TimeOut,
+		MaxRetries:     r.MaxRetries,
+		MaxRetriesDown: r.MaxRetriesDown,
+		HTTPMethod:     r.HTTPMethod,
+		URLPath:        r.URLPath,
+		ExpectedCodes:  r.ExpectedCodes,
+	}.validate()
+}
+
+// Validate checks the updated timings and HTTP options of the health monitor.
+func (r *CloudLoadBalancerHealthMonitorUpdateRequest) Validate() error {
+	value := func(p *int) int {
+		if p == nil {
+			return 0
+		}
+		return *p
+	}
+	params := healthMonitorParams{
+		Delay:          value(r.Delay),
+		Timeout:        value(r.TimeOut),
+		MaxRetries:     value(r.MaxRetries),
+		MaxRetriesDown: value(r.MaxRetriesDown),
+		URLPath:        stringValue(r.URLPath),
+		ExpectedCodes:  stringValue(r.ExpectedCodes),
+	}
+	if r.HTTPMethod != nil {
+		params.HTTPMethod = *r.HTTPMethod
+	}
+	return params.validate()
+}
+
+// Validate checks the address, port and weight of the member.
+func (r *CloudLoadBalancerMemberCreateRequest) Validate() error {
+	if r.Address == "" {
+		return fmt.Errorf("member address is required: %w", ErrCommon)
+	}
+	if err := validatePort(r.ProtocolPort); err != nil {
+		return err
+	}
+	return validateMemberWeight(r.Weight)
+}
+
+// Validate checks the weight of the member.
+func (r *CloudLoadBalancerMemberUpdateRequest) Validate() error {
+	return validateMemberWeight(r.Weight)
+}
+
+func validateMemberWeight(weight int) error {
+	if weight < 0 || weight > 256 {
+		return fmt.Errorf("member weight must be between 0 and 256: %w", ErrCommon)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadBalancerCreateRequestValidate(t *testing.T) {
+	valid := func() *LoadBalancerCreateRequest {
+		return &LoadBalancerCreateRequest{
+			Name:        "lb",
+			NetworkType: LoadBalancerNetworkTypeExternal,
+			Type:        LoadBalancerTypeSmall,
+			Listeners: []LoadBalancerListener{{
+				Name:         "http",
+				Protocol:     ProtocolHTTP,
+				ProtocolPort: 80,
+				DefaultPool: ListenerPool{
+					LbAlgorithm: LBAlgorithmRoundRobin,
+					Protocol:    ProtocolHTTP,
+					CloudLoadBalancerHealthMonitor: ListenerHealthMonitor{
+						Type:          HealthMonitorTypeHTTP,
+						HTTPMethod:    HealthMonitorHTTPMethodGet,
+						URLPath:       "/healthz",
+						ExpectedCodes: "200-204",
+						MaxRetries:    3,
+						Delay:         10,
+						Timeout:       5,
+					},
+				},
+			}},
+		}
+	}
+	assert.NoError(t, valid().Validate())
+
+	tests := map[string]func(r *LoadBalancerCreateRequest){
+		"network type":         func(r *LoadBalancerCreateRequest) { r.NetworkType = "public" },
+		"internal without vpc": func(r *LoadBalancerCreateRequest) { r.NetworkType = LoadBalancerNetworkTypeInternal },
+		"missing type":         func(r *LoadBalancerCreateRequest) { r.Type = "" },
+		"https without cert":   func(r *LoadBalancerCreateRequest) { r.Listeners[0].Protocol = ProtocolHTTPS },
+		"port":                 func(r *LoadBalancerCreateRequest) { r.Listeners[0].ProtocolPort = 0 },
+		"algorithm":            func(r *LoadBalancerCreateRequest) { r.Listeners[0].DefaultPool.LbAlgorithm = "RANDOM" },
+		"timeout not below delay": func(r *LoadBalancerCreateRequest) {
+			r.Listeners[0].DefaultPool.CloudLoadBalancerHealthMonitor.Timeout = 10
+		},
+		"expected codes": func(r *LoadBalancerCreateRequest) {
+			r.Listeners[0].DefaultPool.CloudLoadBalancerHealthMonitor.ExpectedCodes = "2xx"
+		},
+		"udp pool with http monitor": func(r *LoadBalancerCreateRequest) {
+			r.Listeners[0].Protocol = ProtocolUDP
+			r.Listeners[0].DefaultPool.Protocol = ProtocolUDP
+		},
+		"udp listener with http pool": func(r *LoadBalancerCreateRequest) { r.Listeners[0].Protocol = ProtocolUDP },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := valid()
+			mutate(r)
+			assert.True(t, errors.Is(r.Validate(), ErrCommon))
+		})
+	}
+
+	r := valid()
+	r.Listeners[0].Protocol = ProtocolHTTPS
+	r.Listeners[0].DefaultTLSRef = "https://kms.example.com/v1/containers/1"
+	assert.NoError(t, r.Validate())
+}
+
+func TestCloudLoadBalancerPoolCreateRequestValidate(t *testing.T) {
+	cookie := "session"
+	valid := &CloudLoadBalancerPoolCreateRequest{
+		LBAlgorithm:        LBAlgorithmLeastConnections,
+		Protocol:           ProtocolUDP,
+		SessionPersistence: &SessionPersistence{Type: SessionPersistenceAppCookie, CookieName: &cookie},
+		HealthMonitor: &CloudLoadBalancerPoolHealthMonitorRequest{
+			Type: HealthMonitorTypeUDPConnect, Delay: 5, Timeout: 3, MaxRetries: 3,
+		},
+	}
+	assert.NoError(t, valid.Validate())
+
+	invalid := []*CloudLoadBalancerPoolCreateRequest{
+		{Protocol: ProtocolHTTP},
+		{LBAlgorithm: LBAlgorithmRoundRobin, Protocol: "SCTP"},
+		{LBAlgorithm: LBAlgorithmRoundRobin, Protocol: ProtocolHTTP, SessionPersistence: &SessionPersistence{Type: SessionPersistenceAppCookie}},
+		{LBAlgorithm: LBAlgorithmRoundRobin, Protocol: ProtocolHTTP, SessionPersistence: &SessionPersistence{Type: SessionPersistenceSourceIP, CookieName: &cookie}},
+		{LBAlgorithm: LBAlgorithmRoundRobin, Protocol: ProtocolUDP, HealthMonitor: &CloudLoadBalancerPoolHealthMonitorRequest{Type: HealthMonitorTypeHTTP}},
+		{LBAlgorithm: LBAlgorithmRoundRobin, Protocol: ProtocolTCP, HealthMonitor: &CloudLoadBalancerPoolHealthMonitorRequest{Type: HealthMonitorTypeUDPConnect}},
+		{LBAlgorithm: LBAlgorithmRoundRobin, Protocol: ProtocolTCP, HealthMonitor: &CloudLoadBalancerPoolHealthMonitorRequest{Type: HealthMonitorTypeTCP, URLPath: "/"}},
+	}
+	for i, r := range invalid {
+		assert.True(t, errors.Is(r.Validate(), ErrCommon), "request %d", i)
+	}
+}
+
+func TestCloudLoadBalancerHealthMonitorRequestValidate(t *testing.T) {
+	delay, timeout, retries := 5, 5, 11
+	method := HealthMonitorHTTPMethod("FETCH")
+	assert.True(t, errors.Is((&CloudLoadBalancerHealthMonitorUpdateRequest{Delay: &delay, TimeOut: &timeout}).Validate(), ErrCommon))
+	assert.True(t, errors.Is((&CloudLoadBalancerHealthMonitorUpdateRequest{MaxRetries: &retries}).Validate(), ErrCommon))
+	assert.True(t, errors.Is((&CloudLoadBalancerHealthMonitorUpdateRequest{HTTPMethod: &method}).Validate(), ErrCommon))
+	assert.True(t, errors.Is((&CloudLoadBalancerHealthMonitorCreateRequest{Type: "ICMP"}).Validate(), ErrCommon))
+	assert.NoError(t, (&CloudLoadBalancerHealthMonitorCreateRequest{Type: HealthMonitorTypePing, Delay: 5, TimeOut: 2}).Validate())
+
+	_, err := (&cloudLoadBalancerHealthMonitorResource{}).Create(ctx, "pool", &CloudLoadBalancerHealthMonitorCreateRequest{})
+	assert.True(t, errors.Is(err, ErrCommon))
+	assert.True(t, errors.Is((&CloudLoadBalancerMemberCreateRequest{Address: "10.0.0.1", ProtocolPort: 80, Weight: 300}).Validate(), ErrCommon))
+}
@@ -0,0 +1,295 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// TopologyNodeKind is the kind of resource a topology node represents.
+type TopologyNodeKind string
+
+const (
+	TopologyNodeVPC              TopologyNodeKind = "vpc"
+	TopologyNodeSubnet           TopologyNodeKind = "subnet"
+	TopologyNodeNetworkInterface TopologyNodeKind = "network_interface"
+	TopologyNodeWanIP            TopologyNodeKind = "wan_ip"
+	TopologyNodeServer           TopologyNodeKind = "server"
+	TopologyNodeLoadBalancer     TopologyNodeKind = "load_balancer"
+	TopologyNodeInternetGateway  TopologyNodeKind = "internet_gateway"
+	TopologyNodeFirewall         TopologyNodeKind = "firewall"
+)
+
+// TopologyNode is a resource in the network topology, ID is prefixed by the kind to keep it unique.
+type TopologyNode struct {
+	ID         string            `json:"id"`
+	Kind       TopologyNodeKind  `json:"kind"`
+	ResourceID string            `json:"resource_id"`
+	Name       string            `json:"name"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// TopologyEdge links two topology nodes, Relation describes how From relates to To.
+type TopologyEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Relation string `json:"relation"`
+}
+
+// Topology is an in-memory graph of the project network: VPC -> subnet -> network interface -> server,
+// with load balancers, WAN IPs, internet gateways and firewalls attached.
+type Topology struct {
+	Nodes []*TopologyNode `json:"nodes"`
+	Edges []*TopologyEdge `json:"edges"`
+
+	index map[string]*TopologyNode
+}
+
+func topologyNodeID(kind TopologyNodeKind, id string) string {
+	return string(kind) + ":" + id
+}
+
+// Node returns the node of the given kind and resource ID.
+func (t *Topology) Node(kind TopologyNodeKind, id string) (*TopologyNode, bool) {
+	if t.index == nil {
+		// The index is not serialized, rebuild it for a decoded topology.
+		t.index = make(map[string]*TopologyNode, len(t.Nodes))
+		for _, node := range t.Nodes {
+			t.index[node.ID] = node
+		}
+	}
+	node, ok := t.index[topologyNodeID(kind, id)]
+	return node, ok
+}
+
+// EdgesOf returns the edges starting from or ending at a node.
+func (t *Topology) EdgesOf(nodeID string) []*TopologyEdge {
+	var edges []*TopologyEdge
+	for _, edge := range t.Edges {
+		if edge.From == nodeID || edge.To == nodeID {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+func (t *Topology) addNode(kind TopologyNodeKind, id string, name string, attributes map[string]string) *TopologyNode {
+	if t.index == nil {
+		t.index = make(map[string]*TopologyNode)
+	}
+	nodeID := topologyNodeID(kind, id)
+	if node, ok := t.index[nodeID]; ok {
+		if name != "" {
+			node.Name = name
+		}
+		for k, v := range attributes {
+			if node.Attributes == nil {
+				node.Attributes = make(map[string]string)
+			}
+			node.Attributes[k] = v
+		}
+		return node
+	}
+	node := &TopologyNode{ID: nodeID, Kind: kind, ResourceID: id, Name: name, Attributes: attributes}
+	t.index[nodeID] = node
+	t.Nodes = append(t.Nodes, node)
+	return node
+}
+
+// addEdge links two nodes, creating placeholders for nodes which were not listed, e.g. deleted servers.
+func (t *Topology) addEdge(fromKind TopologyNodeKind, fromID string, toKind TopologyNodeKind, toID string, relation string) {
+	if fromID == "" || toID == "" {
+		return
+	}
+	from := t.addNode(fromKind, fromID, "", nil)
+	to := t.addNode(toKind, toID, "", nil)
+	for _, edge := range t.Edges {
+		if edge.From == from.ID && edge.To == to.ID && edge.Relation == relation {
+			return
+		}
+	}
+	t.Edges = append(t.Edges, &TopologyEdge{From: from.ID, To: to.ID, Relation: relation})
+}
+
+// WriteDOT writes the topology in Graphviz DOT format.
+func (t *Topology) WriteDOT(w io.Writer) error {
+	shapes := map[TopologyNodeKind]string{
+		TopologyNodeVPC:              "folder",
+		TopologyNodeSubnet:           "tab",
+		TopologyNodeNetworkInterface: "ellipse",
+		TopologyNodeWanIP:            "doublecircle",
+		TopologyNodeServer:           "box3d",
+		TopologyNodeLoadBalancer:     "hexagon",
+		TopologyNodeInternetGateway:  "house",
+		TopologyNodeFirewall:         "octagon",
+	}
+	var b strings.Builder
+	b.WriteString("digraph topology {\n\trankdir=LR;\n")
+	for _, node := range t.Nodes {
+		label := string(node.Kind) + "\\n" + node.ResourceID
+		if node.Name != "" {
+			label = string(node.Kind) + "\\n" + node.Name
+		}
+		keys := make([]string, 0, len(node.Attributes))
+		for k := range node.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			label += "\\n" + k + "=" + node.Attributes[k]
+		}
+		fmt.Fprintf(&b, "\t%s [shape=%s, label=%s];\n", dotQuote(node.ID), shapes[node.Kind], dotQuote(label))
+	}
+	for _, edge := range t.Edges {
+		fmt.Fprintf(&b, "\t%s -> %s [label=%s];\n", dotQuote(edge.From), dotQuote(edge.To), dotQuote(edge.Relation))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotQuote(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
+
+// Topology walks the VPCs, network interfaces, WAN IPs, internet gateways, firewalls, servers and load balancers
+// of the project and links them into a graph.
+func (c *Client) Topology(ctx context.Context) (*Topology, error) {
+	t := &Topology{}
+	cs := &cloudServerService{client: c}
+
+	vpcs, err := cs.VPCNetworks().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, vpc := range vpcs {
+		t.addNode(TopologyNodeVPC, vpc.ID, vpc.Name, nil)
+		for _, subnet := range vpc.Subnets {
+			t.addNode(TopologyNodeSubnet, subnet.ID, subnet.Name, map[string]string{
+				"cidr":    subnet.CIDR,
+				"gateway": subnet.GatewayIP,
+			})
+			t.addEdge(TopologyNodeVPC, vpc.ID, TopologyNodeSubnet, subnet.ID, "contains")
+		}
+	}
+
+	servers, err := cs.List(ctx, &ServerListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, server := range servers {
+		t.addNode(TopologyNodeServer, server.ID, server.Name, map[string]string{
+			"status":            server.Status,
+			"availability_zone": server.AvailabilityZone,
+		})
+	}
+
+	networkInterfaces, err := cs.NetworkInterfaces().List(ctx, &ListNetworkInterfaceOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, networkInterface := range networkInterfaces {
+		var addresses []string
+		for _, fixedIP := range networkInterface.FixedIps {
+			addresses = append(addresses, fixedIP.IPAddress)
+			t.addEdge(TopologyNodeSubnet, fixedIP.SubnetID, TopologyNodeNetworkInterface, networkInterface.ID, "contains")
+		}
+		if len(networkInterface.FixedIps) == 0 {
+			t.addEdge(TopologyNodeVPC, networkInterface.NetworkID, TopologyNodeNetworkInterface, networkInterface.ID, "contains")
+		}
+		t.addNode(TopologyNodeNetworkInterface, networkInterface.ID, networkInterface.Name, map[string]string{
+			"status":       networkInterface.Status,
+			"ip_addresses": strings.Join(addresses, ","),
+		})
+		serverID := networkInterface.AttachedServer.ID
+		if serverID == "" && strings.HasPrefix(networkInterface.DeviceOwner, "compute:") {
+			serverID = networkInterface.DeviceID
+		}
+		t.addEdge(TopologyNodeNetworkInterface, networkInterface.ID, TopologyNodeServer, serverID, "attached_to")
+	}
+
+	wanIPs, err := cs.PublicNetworkInterfaces().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, wanIP := range wanIPs {
+		t.addNode(TopologyNodeWanIP, wanIP.ID, wanIP.Name, map[string]string{
+			"ip_address": wanIP.IPAddress,
+			"status":     wanIP.Status,
+		})
+		t.addEdge(TopologyNodeWanIP, wanIP.ID, TopologyNodeServer, wanIP.AttachedServer.ID, "attached_to")
+	}
+
+	gateways, err := listAllInternetGateways(ctx, cs.InternetGateways())
+	if err != nil {
+		return nil, err
+	}
+	for _, gateway := range gateways {
+		var externalIPs []string
+		if gateway.ExTernalGatewayInfo != nil {
+			for _, fixedIP := range gateway.ExTernalGatewayInfo.ExternalFixedIPs {
+				externalIPs = append(externalIPs, fixedIP.IpAddress)
+			}
+		}
+		t.addNode(TopologyNodeInternetGateway, gateway.ID, gateway.Name, map[string]string{
+			"status":       gateway.Status,
+			"external_ips": strings.Join(externalIPs, ","),
+		})
+		for _, info := range gateway.InterfacesInfo {
+			t.addEdge(TopologyNodeInternetGateway, gateway.ID, TopologyNodeVPC, info.NetworkID, "routes")
+		}
+	}
+
+	firewalls, err := cs.Firewalls().List(ctx, &ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, firewall := range firewalls {
+		t.addNode(TopologyNodeFirewall, firewall.ID, firewall.Name, nil)
+		for _, serverID := range firewall.Servers {
+			t.addEdge(TopologyNodeFirewall, firewall.ID, TopologyNodeServer, serverID, "protects")
+		}
+	}
+
+	lbs := &cloudLoadBalancerService{client: c}
+	loadBalancers, err := lbs.List(ctx, &ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, lb := range loadBalancers {
+		t.addNode(TopologyNodeLoadBalancer, lb.ID, lb.Name, map[string]string{
+			"vip_address":  lb.VipAddress,
+			"network_type": lb.NetworkType,
+		})
+		if lb.VipSubnetID != "" {
+			t.addEdge(TopologyNodeSubnet, lb.VipSubnetID, TopologyNodeLoadBalancer, lb.ID, "contains")
+		} else {
+			t.addEdge(TopologyNodeVPC, lb.VipNetworkID, TopologyNodeLoadBalancer, lb.ID, "contains")
+		}
+	}
+	return t, nil
+}
+
+// listAllInternetGateways follows the cursor of the internet gateway list until the last page.
+func listAllInternetGateways(ctx context.Context, igws CloudServerInternetGatewayInterface) ([]*ExtendedInternetGateway, error) {
+	var gateways []*ExtendedInternetGateway
+	opts := ListInternetGatewayOpts{}
+	seen := make(map[string]bool)
+	for {
+		result, err := igws.List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		gateways = append(gateways, result.InternetGateways...)
+		next := result.Meta.NextPage
+		if next == nil || *next == "" || seen[*next] {
+			return gateways, nil
+		}
+		seen[*next] = true
+		opts.NextCursor = next
+	}
+}
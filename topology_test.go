@@ -0,0 +1,78 @@
+// This file is part of gobizfly
+
+package gobizfly
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/bizflycloud/gobizfly/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopology(t *testing.T) {
+	setup()
+	defer teardown()
+	routes := map[string]string{
+		testlib.CloudServerURL(vpcPath): `[{"id": "vpc-1", "name": "default",
+			"subnets": [{"id": "subnet-1", "name": "default-subnet", "cidr": "10.20.0.0/24", "gateway_ip": "10.20.0.1"}]}]`,
+		testlib.CloudServerURL(serverBasePath): `[{"id": "srv-1", "name": "web-1", "status": "ACTIVE", "OS-EXT-AZ:availability_zone": "HN1"}]`,
+		testlib.CloudServerURL(networkInterfacePath): `[{"id": "port-1", "name": "web-1-lan", "network_id": "vpc-1", "status": "ACTIVE",
+			"fixed_ips": [{"subnet_id": "subnet-1", "ip_address": "10.20.0.5"}], "attached_server": {"id": "srv-1", "name": "web-1"}}]`,
+		testlib.CloudServerURL(wanIpPath): `[{"id": "wan-1", "name": "web-1-wan", "ip_address": "103.1.2.3", "status": "ACTIVE",
+			"attached_server": {"id": "srv-1"}}, {"id": "wan-2", "name": "spare", "ip_address": "103.1.2.4", "status": "DOWN"}]`,
+		testlib.CloudServerURL(igwPath): `{"internet_gateways": [{"id": "igw-1", "name": "igw", "status": "ACTIVE",
+			"external_gateway_info": {"external_fixed_ips": [{"ip_address": "103.9.9.9"}]},
+			"interfaces_info": [{"network_id": "vpc-1", "subnet_id": "subnet-1"}]}], "meta": {"next_page": null}}`,
+		testlib.CloudServerURL(firewallBasePath): `[{"id": "fw-1", "name": "web", "servers": ["srv-1"]}]`,
+		testlib.LoadBalancerURL(loadBalancersPath): `{"loadbalancers": [{"id": "lb-1", "name": "web-lb", "vip_address": "10.20.0.10",
+			"vip_subnet_id": "subnet-1", "network_type": "internal"}]}`,
+	}
+	for path, body := range routes {
+		body := body
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodGet, r.Method)
+			_, _ = fmt.Fprint(w, body)
+		})
+	}
+
+	topology, err := client.Topology(ctx)
+	require.NoError(t, err)
+	assert.Len(t, topology.Nodes, 9)
+
+	server, ok := topology.Node(TopologyNodeServer, "srv-1")
+	require.True(t, ok)
+	assert.Equal(t, "web-1", server.Name)
+	relations := map[string]string{}
+	for _, edge := range topology.EdgesOf(server.ID) {
+		relations[edge.From] = edge.Relation
+	}
+	assert.Equal(t, map[string]string{
+		"network_interface:port-1": "attached_to",
+		"wan_ip:wan-1":             "attached_to",
+		"firewall:fw-1":            "protects",
+	}, relations)
+
+	subnetEdges := topology.EdgesOf("subnet:subnet-1")
+	assert.Len(t, subnetEdges, 3)
+	assert.Len(t, topology.EdgesOf("internet_gateway:igw-1"), 1)
+	assert.Empty(t, topology.EdgesOf("wan_ip:wan-2"))
+
+	data, err := json.Marshal(topology)
+	require.NoError(t, err)
+	var decoded Topology
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	lb, ok := decoded.Node(TopologyNodeLoadBalancer, "lb-1")
+	require.True(t, ok)
+	assert.Equal(t, "10.20.0.10", lb.Attributes["vip_address"])
+
+	var dot bytes.Buffer
+	require.NoError(t, topology.WriteDOT(&dot))
+	assert.Contains(t, dot.String(), "digraph topology {")
+	assert.Contains(t, dot.String(), `"network_interface:port-1" -> "server:srv-1" [label="attached_to"];`)
+	assert.Contains(t, dot.String(), `"internet_gateway:igw-1" [shape=house`)
+}